@@ -1,52 +1,119 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"agentflow/internal/commands"
+	"agentflow/internal/logging"
+)
+
+var (
+	// logger is the process logger, configured from the global flags.
+	logger = slog.Default()
+	// runCtx is cancelled on SIGINT/SIGTERM and when --timeout expires.
+	runCtx = context.Background()
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run executes the command line and returns the exit code. Everything
+// deferred here, such as flushing the log file, runs before main exits.
+func run(args []string) int {
+	global, args, err := parseGlobalFlags(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	l, closeLog, err := logging.New(global.Log, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	logger = l
+	slog.SetDefault(logger)
+	defer closeLog()
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// After the first signal, restore the default handling so a second
+		// Ctrl-C exits immediately instead of waiting for the rollback.
+		<-sigCtx.Done()
+		stop()
+	}()
+	ctx := sigCtx
+	if global.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, global.Timeout)
+		defer cancel()
+	}
+	runCtx = ctx
+
+	if len(args) < 1 {
 		usage()
-		os.Exit(1)
+		return 1
 	}
 
-	cmd := os.Args[1]
+	cmd, args := args[0], args[1:]
 	switch cmd {
 	case "help", "-h", "--help":
 		usage()
-		return
+		return 0
 	case "version", "-v", "--version":
 		fmt.Println("agentflow v0.1.0")
-		return
+		return 0
 	case "init":
-		initCmd(os.Args[2:])
+		return initCmd(args)
 	case "intake":
-		intakeCmd(os.Args[2:])
+		return intakeCmd(args)
 	case "plan":
-		planCmd(os.Args[2:])
+		return planCmd(args)
 	case "design":
-		designCmd(os.Args[2:])
+		return designCmd(args)
 	case "uml":
-		umlCmd(os.Args[2:])
+		return umlCmd(args)
 	case "qa":
-		qaCmd(os.Args[2:])
+		return qaCmd(args)
 	case "devplan":
-		devplanCmd(os.Args[2:])
+		return devplanCmd(args)
 	case "entity":
-		entityCmd(os.Args[2:])
+		return entityCmd(args)
 	case "repo":
-		repoCmd(os.Args[2:])
+		return repoCmd(args)
+	case "run":
+		return runCmd(args)
+	case "questions":
+		return questionsCmd(args)
+	case "answer":
+		return answerCmd(args)
+	case "runs":
+		return runsCmd(args)
+	case "usage":
+		return usageCmd(args)
+	case "unredact":
+		return unredactCmd(args)
+	case "tasks":
+		return tasksCmd(args)
+	case "export":
+		return exportCmd(args)
+	case "diagrams":
+		return diagramsCmd(args)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", cmd)
 		usage()
-		os.Exit(1)
+		return 1
 	}
 }
 
@@ -67,197 +134,722 @@ Commands:
   devplan     Generate task list and per-task context
   entity      Generate entities.md with data models and relationships
   repo        Generate repository.md with Golang repository interfaces
+  run         Run the intake→devplan pipeline in dependency order
+  questions   List open questions raised by agents (questions list)
+  answer      Answer a question: answer Q-12 "..."
+  runs        Inspect recorded agent runs (runs list, runs show <id>)
+  usage       Report token usage and cost across recorded runs
+  unredact    Restore redacted values in generated documents
+  tasks       Work through the dev plan (tasks list|show|done|next|graph)
+  export      Export dev plan tasks to an issue tracker (export tasks)
+  diagrams    Lint or extract the PlantUML and Mermaid blocks in generated docs (diagrams lint|extract)
   help        Show this help
   version     Show version
 
+Global flags (before or after the command, ahead of its arguments):
+  --verbose           Log at debug level, including full prompts
+  --quiet             Log warnings and errors only
+  --log-level LEVEL   debug, info, warn or error
+  --log-format FMT    text (default) or json
+  --log-file PATH     Append the log to PATH instead of stderr
+  --timeout DURATION  Cancel the command after DURATION, e.g. 30m
+
 Use "%s <command> -h" for command-specific help.
 `, prog, prog)
 }
 
-func initCmd(args []string) {
-	fs := flag.NewFlagSet("init", flag.ExitOnError)
+// globalFlags are the flags accepted by every command.
+type globalFlags struct {
+	Log     logging.Options
+	Timeout time.Duration
+}
+
+// commandGroups are the commands whose first argument names a subcommand.
+var commandGroups = map[string]bool{"questions": true, "runs": true, "tasks": true, "export": true, "diagrams": true}
+
+// parseGlobalFlags removes the global flags from args and returns what they
+// set. Both -flag and --flag forms are accepted, with the value as "=value"
+// or the next argument. Global flags may come before or after the command
+// and among its flags, but scanning stops at "--" and at the command's first
+// positional argument, so an answer or an ID that looks like a global flag
+// is left alone. An argument right after a command flag without "=" is
+// taken as that flag's value.
+func parseGlobalFlags(args []string) (globalFlags, []string, error) {
+	g := globalFlags{Log: logging.Options{Level: slog.LevelInfo}}
+	opts := &g.Log
+	var rest, command []string
+	flagValue := false // args[i] may be the value of the command flag before it
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if args[i] == "--" {
+			return g, append(rest, args[i:]...), nil
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			switch {
+			case len(command) == 0 || len(command) == 1 && commandGroups[command[0]]:
+				command = append(command, args[i])
+			case !flagValue:
+				return g, append(rest, args[i:]...), nil
+			}
+			rest = append(rest, args[i])
+			flagValue = false
+			continue
+		}
+		flagValue = false
+		needValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", fmt.Errorf("flag --%s needs a value", name)
+			}
+			i++
+			return args[i], nil
+		}
+		switch name {
+		case "verbose":
+			opts.Level = slog.LevelDebug
+		case "quiet":
+			opts.Level = slog.LevelWarn
+		case "log-level":
+			v, err := needValue()
+			if err != nil {
+				return g, nil, err
+			}
+			if opts.Level, err = logging.ParseLevel(v); err != nil {
+				return g, nil, err
+			}
+		case "log-format":
+			v, err := needValue()
+			if err != nil {
+				return g, nil, err
+			}
+			opts.Format = v
+		case "log-file":
+			v, err := needValue()
+			if err != nil {
+				return g, nil, err
+			}
+			opts.File = v
+		case "timeout":
+			v, err := needValue()
+			if err != nil {
+				return g, nil, err
+			}
+			if g.Timeout, err = time.ParseDuration(v); err != nil || g.Timeout < 0 {
+				return g, nil, fmt.Errorf("invalid --timeout %q: want a duration such as 30m", v)
+			}
+		default:
+			rest = append(rest, args[i])
+			flagValue = !hasValue
+		}
+	}
+	return g, rest, nil
+}
+
+// fail logs a failed command and returns its exit code.
+func fail(msg string, err error) int {
+	logger.Error(msg, "err", err)
+	return 1
+}
+
+// flagExit returns the exit code for a flag parse error, which the flag set
+// has already reported: 0 after -h, 2 otherwise.
+func flagExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+func initCmd(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
 	projectName := fs.String("project-name", "MyProject", "Project name to store in config")
 	model := fs.String("model", "gpt-5", "Default LLM model")
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file to create")
-	_ = fs.Parse(args)
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
 	if err := commands.Init(*configPath, *projectName, *model); err != nil {
-		log.Fatalf("init failed: %v", err)
+		return fail("init failed", err)
 	}
-	fmt.Printf("Initialized %s\n", *configPath)
+	logger.Info("initialized", "config", *configPath)
+	return 0
 }
 
-func intakeCmd(args []string) {
-	fs := flag.NewFlagSet("intake", flag.ExitOnError)
+func intakeCmd(args []string) int {
+	fs := flag.NewFlagSet("intake", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	inputsDir := fs.String("input", ".agentflow/input", "Input directory with .md files")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "po_pm", "Role to use for prompt building (po_pm)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.Intake(commands.IntakeOptions{
+	if err := commands.Intake(runCtx, commands.IntakeOptions{
 		ConfigPath: *configPath,
 		InputsDir:  *inputsDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
 	}); err != nil {
 		if errors.Is(err, commands.ErrNoInputs) {
-			fmt.Fprintln(os.Stderr, "warning: no input markdown files found; creating empty requirements.md")
+			logger.Warn("no input markdown files found; creating empty requirements.md")
 		} else {
-			log.Fatalf("intake failed: %v", err)
+			return fail("intake failed", err)
 		}
 	}
-	fmt.Printf("Wrote %s\n", filepath.Join(*outputDir, "requirements.md"))
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "requirements.md"))
+	return 0
 }
 
-func planCmd(args []string) {
-	fs := flag.NewFlagSet("plan", flag.ExitOnError)
+func planCmd(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	reqPath := fs.String("requirements", ".agentflow/output/requirements.md", "Path to requirements.md")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "sa", "Role to use for planning (sa)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.Plan(commands.PlanOptions{
+	if err := commands.Plan(runCtx, commands.PlanOptions{
 		ConfigPath:   *configPath,
 		Requirements: *reqPath,
 		OutputDir:    *outputDir,
 		Role:         *role,
 		DryRun:       *dryRun,
+		Force:        *force,
+		Logger:       logger,
 	}); err != nil {
 		if errors.Is(err, commands.ErrNoRequirements) {
-			log.Fatalf("plan failed: requirements.md not found at %s", *reqPath)
+			return fail("plan failed", fmt.Errorf("requirements.md not found at %s", *reqPath))
 		}
-		log.Fatalf("plan failed: %v", err)
+		return fail("plan failed", err)
 	}
-	fmt.Printf("Wrote %s, %s, %s\n", filepath.Join(*outputDir, "srs.md"), filepath.Join(*outputDir, "stories.md"), filepath.Join(*outputDir, "acceptance_criteria.md"))
+	logger.Info("wrote", "files", strings.Join([]string{filepath.Join(*outputDir, "srs.md"), filepath.Join(*outputDir, "stories.md"), filepath.Join(*outputDir, "acceptance_criteria.md")}, ", "))
+	return 0
 }
 
-func qaCmd(args []string) {
-	fs := flag.NewFlagSet("qa", flag.ExitOnError)
+func qaCmd(args []string) int {
+	fs := flag.NewFlagSet("qa", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	sourceDir := fs.String("source", ".agentflow/output", "Directory with prior docs (srs/stories/AC)")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "qa", "Role to use for QA (qa)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.QA(commands.QAOptions{
+	if err := commands.QA(runCtx, commands.QAOptions{
 		ConfigPath: *configPath,
 		SourceDir:  *sourceDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
 	}); err != nil {
-		log.Fatalf("qa failed: %v", err)
+		return fail("qa failed", err)
 	}
-	fmt.Printf("Wrote %s\n", filepath.Join(*outputDir, "test-plan.md"))
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "test-plan.md"))
+	return 0
 }
 
-func designCmd(args []string) {
-	fs := flag.NewFlagSet("design", flag.ExitOnError)
+func designCmd(args []string) int {
+	fs := flag.NewFlagSet("design", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	sourceDir := fs.String("source", ".agentflow/output", "Directory with prior docs (requirements/srs/stories/AC)")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "sa", "Role to use for design (sa)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.Design(commands.DesignOptions{
+	if err := commands.Design(runCtx, commands.DesignOptions{
 		ConfigPath: *configPath,
 		SourceDir:  *sourceDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
 	}); err != nil {
-		log.Fatalf("design failed: %v", err)
+		return fail("design failed", err)
 	}
-	fmt.Printf("Wrote %s\n", filepath.Join(*outputDir, "architecture.md"))
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "architecture.md"))
+	return 0
 }
 
-func umlCmd(args []string) {
-	fs := flag.NewFlagSet("uml", flag.ExitOnError)
+func umlCmd(args []string) int {
+	fs := flag.NewFlagSet("uml", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	sourceDir := fs.String("source", ".agentflow/output", "Directory with prior docs (requirements/srs/stories)")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "sa", "Role to use for uml (sa)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.Uml(commands.UmlOptions{
+	if err := commands.Uml(runCtx, commands.UmlOptions{
 		ConfigPath: *configPath,
 		SourceDir:  *sourceDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
 	}); err != nil {
-		log.Fatalf("uml failed: %v", err)
+		return fail("uml failed", err)
 	}
-	fmt.Printf("Wrote %s\n", filepath.Join(*outputDir, "uml.md"))
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "uml.md"))
+	return 0
 }
 
-func devplanCmd(args []string) {
-	fs := flag.NewFlagSet("devplan", flag.ExitOnError)
+func devplanCmd(args []string) int {
+	fs := flag.NewFlagSet("devplan", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	sourceDir := fs.String("source", ".agentflow/output", "Directory with prior generated docs (requirements/srs/stories/...)")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory for task_list.md and tasks/")
 	role := fs.String("role", "dev", "Role to use for devplanning (dev)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.DevPlan(commands.DevPlanOptions{
+	if err := commands.DevPlan(runCtx, commands.DevPlanOptions{
 		ConfigPath: *configPath,
 		SourceDir:  *sourceDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
 	}); err != nil {
-		log.Fatalf("devplan failed: %v", err)
+		return fail("devplan failed", err)
 	}
-	fmt.Printf("Wrote %s and %s/*.md\n", filepath.Join(*outputDir, "task_list.md"), filepath.Join(*outputDir, "tasks"))
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "task_list.md")+", "+filepath.Join(*outputDir, "tasks", "*.md"))
+	return 0
 }
 
-func entityCmd(args []string) {
-	fs := flag.NewFlagSet("entity", flag.ExitOnError)
+func entityCmd(args []string) int {
+	fs := flag.NewFlagSet("entity", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	sourceDir := fs.String("source", ".agentflow/output", "Directory with prior docs (requirements/srs/stories/architecture)")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "sa", "Role to use for entity design (sa)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.Entity(commands.EntityOptions{
+	if err := commands.Entity(runCtx, commands.EntityOptions{
 		ConfigPath: *configPath,
 		SourceDir:  *sourceDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
 	}); err != nil {
-		log.Fatalf("entity failed: %v", err)
+		return fail("entity failed", err)
 	}
-	fmt.Printf("Wrote %s\n", filepath.Join(*outputDir, "entities.md"))
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "entities.md"))
+	return 0
 }
 
-func repoCmd(args []string) {
-	fs := flag.NewFlagSet("repo", flag.ExitOnError)
+func repoCmd(args []string) int {
+	fs := flag.NewFlagSet("repo", flag.ContinueOnError)
 	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
 	sourceDir := fs.String("source", ".agentflow/output", "Directory with prior docs (requirements/srs/stories/architecture/entities)")
 	outputDir := fs.String("output", ".agentflow/output", "Output directory")
 	role := fs.String("role", "sa", "Role to use for repository design (sa)")
 	dryRun := fs.Bool("dry-run", false, "Do not call OpenAI, just scaffold output")
-	_ = fs.Parse(args)
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
 
-	if err := commands.Repo(commands.RepoOptions{
+	if err := commands.Repo(runCtx, commands.RepoOptions{
 		ConfigPath: *configPath,
 		SourceDir:  *sourceDir,
 		OutputDir:  *outputDir,
 		Role:       *role,
 		DryRun:     *dryRun,
+		Force:      *force,
+		Logger:     logger,
+	}); err != nil {
+		return fail("repo failed", err)
+	}
+	logger.Info("wrote", "files", filepath.Join(*outputDir, "repository.md"))
+	return 0
+}
+
+func runCmd(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	inputsDir := fs.String("input", ".agentflow/input", "Input directory with .md files")
+	outputDir := fs.String("output", ".agentflow/output", "Output directory")
+	from := fs.String("from", "", "First stage to run (inclusive)")
+	to := fs.String("to", "", "Last stage to run (inclusive)")
+	only := fs.String("only", "", "Comma-separated stages to run, e.g. plan,qa")
+	dryRun := fs.Bool("dry-run", false, "List the stages that would run and what each waits for; write nothing")
+	force := fs.Bool("force", false, "Rebuild even if inputs, prompt and config are unchanged")
+	jobs := fs.Int("jobs", 1, "Number of independent stages to run at once")
+	failFast := fs.Bool("fail-fast", false, "Cancel running stages as soon as one fails")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
+
+	var onlyStages []string
+	if strings.TrimSpace(*only) != "" {
+		onlyStages = strings.Split(*only, ",")
+	}
+	report, err := commands.Run(runCtx, commands.RunOptions{
+		ConfigPath: *configPath,
+		InputsDir:  *inputsDir,
+		OutputDir:  *outputDir,
+		From:       *from,
+		To:         *to,
+		Only:       onlyStages,
+		DryRun:     *dryRun,
+		Force:      *force,
+		Jobs:       *jobs,
+		FailFast:   *failFast,
+		Logger:     logger,
+	})
+	if report != nil {
+		fmt.Print(report)
+	}
+	if err != nil {
+		return fail("run failed", err)
+	}
+	return 0
+}
+
+func questionsCmd(args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "usage: agentflow questions list [-config path] [-stage name] [-all]")
+		return 1
+	}
+	fs := flag.NewFlagSet("questions list", flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	stage := fs.String("stage", "", "Only list questions raised by this stage")
+	all := fs.Bool("all", false, "Include answered questions")
+	if err := fs.Parse(args[1:]); err != nil {
+		return flagExit(err)
+	}
+
+	if err := commands.QuestionsList(os.Stdout, commands.QuestionsListOptions{
+		ConfigPath: *configPath,
+		Stage:      *stage,
+		All:        *all,
+	}); err != nil {
+		return fail("questions list failed", err)
+	}
+	return 0
+}
+
+func answerCmd(args []string) int {
+	fs := flag.NewFlagSet("answer", flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: agentflow answer [-config path] <ID> <answer>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return 1
+	}
+
+	id := fs.Arg(0)
+	if err := commands.Answer(commands.AnswerOptions{
+		ConfigPath: *configPath,
+		ID:         id,
+		Answer:     strings.Join(fs.Args()[1:], " "),
+	}); err != nil {
+		return fail("answer failed", err)
+	}
+	logger.Info("answered", "id", id)
+	return 0
+}
+
+func runsCmd(args []string) int {
+	const usage = "usage: agentflow runs list [-config path] [-stage name]\n       agentflow runs show [-config path] [-full] <id>"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("runs list", flag.ContinueOnError)
+		configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+		stage := fs.String("stage", "", "Only list runs of this stage")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+
+		if err := commands.RunsList(os.Stdout, commands.RunsListOptions{
+			ConfigPath: *configPath,
+			Stage:      *stage,
+		}); err != nil {
+			return fail("runs list failed", err)
+		}
+	case "show":
+		fs := flag.NewFlagSet("runs show", flag.ContinueOnError)
+		configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+		full := fs.Bool("full", false, "Print tool arguments and results untruncated")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return 1
+		}
+
+		if err := commands.RunsShow(os.Stdout, commands.RunsShowOptions{
+			ConfigPath: *configPath,
+			ID:         fs.Arg(0),
+			Full:       *full,
+		}); err != nil {
+			return fail("runs show failed", err)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	return 0
+}
+
+func tasksCmd(args []string) int {
+	const usage = `usage: agentflow tasks list [-config path] [-output dir] [-open]
+       agentflow tasks show [-config path] [-output dir] [-full] <TASK-ID>
+       agentflow tasks done [-config path] [-output dir] [-undo] <TASK-ID>
+       agentflow tasks next [-config path] [-output dir] [-full]
+       agentflow tasks graph [-config path] [-output dir] [-format mermaid|dot|text]`
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	fs := flag.NewFlagSet("tasks "+args[0], flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	outputDir := fs.String("output", "", "Output directory holding task_list.md (defaults to io.outputDir)")
+	// needID parses the flags and returns the single task ID argument. When
+	// ok is false the command stops with code.
+	needID := func() (id string, code int, ok bool) {
+		if err := fs.Parse(args[1:]); err != nil {
+			return "", flagExit(err), false
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, usage)
+			return "", 1, false
+		}
+		return fs.Arg(0), 0, true
+	}
+	switch args[0] {
+	case "list":
+		open := fs.Bool("open", false, "Only list tasks that are not done")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+		if err := commands.TasksList(os.Stdout, commands.TasksListOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			Open:       *open,
+		}); err != nil {
+			return fail("tasks list failed", err)
+		}
+	case "show":
+		full := fs.Bool("full", false, "Print the task context untruncated")
+		id, code, ok := needID()
+		if !ok {
+			return code
+		}
+		if err := commands.TasksShow(os.Stdout, commands.TasksShowOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			ID:         id,
+			Full:       *full,
+		}); err != nil {
+			return fail("tasks show failed", err)
+		}
+	case "done":
+		undo := fs.Bool("undo", false, "Mark the task as not done")
+		id, code, ok := needID()
+		if !ok {
+			return code
+		}
+		if err := commands.TasksDone(commands.TasksDoneOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			ID:         id,
+			Undo:       *undo,
+		}); err != nil {
+			return fail("tasks done failed", err)
+		}
+		status := "done"
+		if *undo {
+			status = "open"
+		}
+		logger.Info("task updated", "id", id, "status", status)
+	case "next":
+		full := fs.Bool("full", false, "Print the task context untruncated")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+		if err := commands.TasksNext(os.Stdout, commands.TasksNextOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			Full:       *full,
+		}); err != nil {
+			return fail("tasks next failed", err)
+		}
+	case "graph":
+		format := fs.String("format", "mermaid", "Output format: mermaid, dot or text")
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+		if err := commands.TasksGraph(os.Stdout, commands.TasksGraphOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			Format:     *format,
+		}); err != nil {
+			return fail("tasks graph failed", err)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	return 0
+}
+
+func exportCmd(args []string) int {
+	const usage = "usage: agentflow export tasks [-config path] [-output dir] [-format github|jira-csv|linear-json]\n       agentflow export tasks -push [-repo owner/name] [-base-url url]"
+	if len(args) == 0 || args[0] != "tasks" {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	fs := flag.NewFlagSet("export tasks", flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	outputDir := fs.String("output", "", "Output directory holding task_list.md (defaults to io.outputDir)")
+	format := fs.String("format", "github", "Export format: github, jira-csv or linear-json")
+	push := fs.Bool("push", false, "Create or update GitHub issues instead of printing them")
+	repo := fs.String("repo", "", "GitHub repository as owner/name (overrides export.github.repo)")
+	baseURL := fs.String("base-url", "", "GitHub-compatible API base URL (overrides export.github.baseURL)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return flagExit(err)
+	}
+
+	if err := commands.ExportTasks(runCtx, os.Stdout, commands.ExportTasksOptions{
+		ConfigPath: *configPath,
+		OutputDir:  *outputDir,
+		Format:     *format,
+		Push:       *push,
+		Repo:       *repo,
+		BaseURL:    *baseURL,
+		Logger:     logger,
+	}); err != nil {
+		return fail("export failed", err)
+	}
+	return 0
+}
+
+func diagramsCmd(args []string) int {
+	const usage = "usage: agentflow diagrams lint [-config path] [-output dir] [file.md ...]\n       agentflow diagrams extract [-config path] [-output dir] [file.md ...]"
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	fs := flag.NewFlagSet("diagrams "+args[0], flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	outputDir := fs.String("output", "", "Directory of generated docs (defaults to io.outputDir)")
+	switch args[0] {
+	case "lint":
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+		if err := commands.DiagramsLint(os.Stdout, commands.DiagramsLintOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			Files:      fs.Args(),
+		}); err != nil {
+			return fail("diagrams lint failed", err)
+		}
+	case "extract":
+		if err := fs.Parse(args[1:]); err != nil {
+			return flagExit(err)
+		}
+		if err := commands.DiagramsExtract(commands.DiagramsExtractOptions{
+			ConfigPath: *configPath,
+			OutputDir:  *outputDir,
+			Files:      fs.Args(),
+			Logger:     logger,
+		}); err != nil {
+			return fail("diagrams extract failed", err)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 1
+	}
+	return 0
+}
+
+func usageCmd(args []string) int {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	by := fs.String("by", "stage", "Group runs by stage, model or day")
+	since := fs.String("since", "", "Only count runs started on or after this date (YYYY-MM-DD)")
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
+
+	if err := commands.UsageReport(os.Stdout, commands.UsageReportOptions{
+		ConfigPath: *configPath,
+		By:         *by,
+		Since:      *since,
+	}); err != nil {
+		return fail("usage failed", err)
+	}
+	return 0
+}
+
+func unredactCmd(args []string) int {
+	fs := flag.NewFlagSet("unredact", flag.ContinueOnError)
+	configPath := fs.String("config", ".agentflow/config.json", "Path to config file")
+	dryRun := fs.Bool("dry-run", false, "Report what would be restored without writing")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: agentflow unredact [-config path] [-dry-run] [file or dir...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return flagExit(err)
+	}
+
+	if err := commands.Unredact(os.Stdout, commands.UnredactOptions{
+		ConfigPath: *configPath,
+		Paths:      fs.Args(),
+		DryRun:     *dryRun,
 	}); err != nil {
-		log.Fatalf("repo failed: %v", err)
+		return fail("unredact failed", err)
 	}
-	fmt.Printf("Wrote %s\n", filepath.Join(*outputDir, "repository.md"))
+	return 0
 }
@@ -1,13 +1,14 @@
 package main
 
 import (
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
-// We test only argument parsing branches that don't exit the process.
-
 func TestUsageDoesNotPanic(t *testing.T) {
 	// Just ensure the function runs; it writes to stdout
 	usage()
@@ -16,7 +17,9 @@ func TestUsageDoesNotPanic(t *testing.T) {
 func TestInitCmd_CreatesConfig(t *testing.T) {
 	dir := t.TempDir()
 	cfgPath := filepath.Join(dir, ".agentflow", "config.json")
-	initCmd([]string{"-project-name", "X", "-model", "gpt-5", "-config", cfgPath})
+	if code := initCmd([]string{"-project-name", "X", "-model", "gpt-5", "-config", cfgPath}); code != 0 {
+		t.Fatalf("init exited %d", code)
+	}
 	if _, err := os.Stat(cfgPath); err != nil {
 		t.Fatalf("config not created: %v", err)
 	}
@@ -42,3 +45,89 @@ func TestPlanCmd_AndOthers_DryRun(t *testing.T) {
 	// devplan dry-run
 	devplanCmd([]string{"-config", cfgPath, "-source", outDir, "-output", outDir, "-dry-run"})
 }
+
+func TestRunCmd_DryRun(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, ".agentflow", "config.json")
+	initCmd([]string{"-project-name", "X", "-model", "gpt-5", "-config", cfgPath})
+	outDir := filepath.Join(dir, "out")
+	if code := runCmd([]string{"-config", cfgPath, "-input", filepath.Join(dir, "input"), "-output", outDir, "-only", "design,qa", "-dry-run"}); code != 0 {
+		t.Fatalf("run --dry-run exited %d", code)
+	}
+}
+
+func TestRun_ReturnsExitCodes(t *testing.T) {
+	prev, prevDefault := logger, slog.Default()
+	defer func() { logger = prev; slog.SetDefault(prevDefault) }()
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "agentflow.log")
+	cases := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"version"}, 0},
+		{[]string{"bogus"}, 1},
+		{[]string{"--timeout", "soon", "version"}, 2},
+		{[]string{"init", "-no-such-flag"}, 2},
+		{[]string{"init", "-h"}, 0},
+		{[]string{"tasks", "show"}, 1},
+		// A failing command still returns, so its error reaches the log file.
+		{[]string{"--log-format", "json", "--log-file", logFile, "tasks", "list", "-output", filepath.Join(dir, "missing")}, 1},
+	}
+	for _, c := range cases {
+		if got := run(c.args); got != c.want {
+			t.Errorf("run(%q) = %d, want %d", c.args, got, c.want)
+		}
+	}
+	data, err := os.ReadFile(logFile)
+	if err != nil || !strings.Contains(string(data), `"msg":"tasks list failed"`) {
+		t.Errorf("log file should hold the failure: %v\n%s", err, data)
+	}
+}
+
+func TestParseGlobalFlags(t *testing.T) {
+	g, rest, err := parseGlobalFlags([]string{"--verbose", "plan", "-config", "c.json", "--log-format=json", "--log-file", "a.log", "-dry-run", "--timeout=90s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Timeout != 90*time.Second {
+		t.Errorf("timeout = %v", g.Timeout)
+	}
+	opts := g.Log
+	if opts.Level != slog.LevelDebug || opts.Format != "json" || opts.File != "a.log" {
+		t.Errorf("opts = %+v", opts)
+	}
+	want := []string{"plan", "-config", "c.json", "-dry-run"}
+	if strings.Join(rest, " ") != strings.Join(want, " ") {
+		t.Errorf("rest = %q, want %q", rest, want)
+	}
+	if g, _, _ := parseGlobalFlags([]string{"run", "--quiet"}); g.Log.Level != slog.LevelWarn {
+		t.Errorf("--quiet level = %v", g.Log.Level)
+	}
+	if _, _, err := parseGlobalFlags([]string{"--timeout", "soon"}); err == nil {
+		t.Error("want error for bad timeout")
+	}
+	if _, _, err := parseGlobalFlags([]string{"--log-level", "loud"}); err == nil {
+		t.Error("want error for bad level")
+	}
+	if _, _, err := parseGlobalFlags([]string{"--log-file"}); err == nil {
+		t.Error("want error for missing value")
+	}
+
+	// Positional arguments and whatever follows them, or "--", belong to the
+	// command, even when they look like global flags.
+	for _, args := range [][]string{
+		{"answer", "Q-1", "--log-level"},
+		{"answer", "-config", "c.json", "Q-1", "--quiet", "please"},
+		{"tasks", "show", "TASK-1", "--verbose"},
+		{"answer", "--", "--quiet"},
+	} {
+		g, rest, err := parseGlobalFlags(args)
+		if err != nil || g.Log.Level != slog.LevelInfo || strings.Join(rest, " ") != strings.Join(args, " ") {
+			t.Errorf("%q: level %v, rest %q, err %v", args, g.Log.Level, rest, err)
+		}
+	}
+	if g, rest, _ := parseGlobalFlags([]string{"tasks", "show", "--quiet", "TASK-1"}); g.Log.Level != slog.LevelWarn || strings.Join(rest, " ") != "tasks show TASK-1" {
+		t.Errorf("flag before the ID: level %v, rest %q", g.Log.Level, rest)
+	}
+}
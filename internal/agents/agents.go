@@ -1,57 +1,137 @@
 package agents
 
 import (
-	"agentflow/internal/agents/tools"
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"agentflow/internal/logging"
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
 
 	"github.com/nlpodyssey/openai-agents-go/agents"
 	"github.com/nlpodyssey/openai-agents-go/modelsettings"
 	"github.com/openai/openai-go/v2"
+	"github.com/openai/openai-go/v2/packages/param"
 )
 
+// Agent is a Runner backed by the openai-agents-go SDK.
 type Agent struct {
-	Agent *agents.Agent
+	Agent  *agents.Agent
+	Runner agents.Runner
+	Log    *slog.Logger
 }
 
 func (a *Agent) RunInputs(ctx context.Context, prompts []agents.TResponseInputItem) (string, error) {
-	// loop through prompts and print them
-	for _, prompt := range prompts {
-		fmt.Println(prompt.OfMessage.Content.OfString.String())
+	log := logging.Or(a.Log)
+	if log.Enabled(ctx, slog.LevelDebug) {
+		for _, prompt := range prompts {
+			if m := prompt.OfMessage; m != nil {
+				log.DebugContext(ctx, "prompt", "role", m.Role, "content", m.Content.OfString.String())
+			}
+		}
 	}
-	result, err := agents.RunInputs(ctx, a.Agent, prompts)
+	start := time.Now()
+	result, err := a.Runner.RunInputs(ctx, a.Agent, prompts)
+	log.DebugContext(ctx, "model run returned", "agent", a.Agent.Name, "duration", time.Since(start), "err", err)
 	if err != nil {
 		return "", err
 	}
 	return fmt.Sprint(result.FinalOutput), nil
 }
 
-var (
-	PO *Agent
-	SA *Agent
-	LD *Agent
-	LQ *Agent
-)
+// observedProvider hands out models whose responses are recorded and
+// metered as they arrive. The transcript keeps the turns of runs that fail
+// or are stopped, and the budget can stop a run between two model turns
+// even when the model calls no tool.
+type observedProvider struct {
+	agents.ModelProvider
+}
+
+func (p observedProvider) GetModel(name string) (agents.Model, error) {
+	m, err := p.ModelProvider.GetModel(name)
+	if err != nil {
+		return nil, err
+	}
+	return observedModel{m}, nil
+}
+
+type observedModel struct {
+	agents.Model
+}
 
-func init() {
-	PO = newAgent("Product Owner", "", "gpt-5")
-	SA = newAgent("Solution Architect", "", "gpt-5")
-	LD = newAgent("Lead Developer", "", "gpt-5")
-	LQ = newAgent("Lead QA", "", "gpt-5")
+// GetResponse adds each response to the run's transcript and its usage to
+// the run's meter. Once the meter has cancelled the run, the turn's result
+// is discarded and no further turn starts.
+func (m observedModel) GetResponse(ctx context.Context, params agents.ModelResponseParams) (*agents.ModelResponse, error) {
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	resp, err := m.Model.GetResponse(ctx, params)
+	if resp != nil {
+		recordResponse(transcript.FromContext(ctx), *resp)
+		if resp.Usage != nil {
+			usage.FromContext(ctx).Add(int64(resp.Usage.InputTokens), int64(resp.Usage.OutputTokens))
+		}
+	}
+	if err == nil && ctx.Err() != nil {
+		return resp, context.Cause(ctx)
+	}
+	return resp, err
 }
 
-func newAgent(role, instructions, model string) *Agent {
+// recordResponse adds a model turn to the run's transcript.
+func recordResponse(t *transcript.Transcript, r agents.ModelResponse) {
+	if t == nil {
+		return
+	}
+	rec := transcript.Response{ID: r.ResponseID}
+	var text []string
+	for _, item := range r.Output {
+		if raw := item.RawJSON(); raw != "" {
+			rec.Output = append(rec.Output, json.RawMessage(raw))
+		} else if data, err := json.Marshal(item); err == nil {
+			rec.Output = append(rec.Output, data)
+		}
+		for _, c := range item.Content {
+			if c.Text != "" {
+				text = append(text, c.Text)
+			}
+		}
+	}
+	rec.Text = strings.Join(text, "\n")
+	if u := r.Usage; u != nil {
+		rec.Usage = &transcript.Usage{
+			InputTokens:  int64(u.InputTokens),
+			OutputTokens: int64(u.OutputTokens),
+			TotalTokens:  int64(u.TotalTokens),
+		}
+	}
+	t.AddResponse(rec)
+}
+
+func newAgent(spec AgentSpec) *Agent {
+	model := spec.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	settings := modelsettings.ModelSettings{
+		Temperature: openai.Float(spec.Temperature),
+	}
+	if spec.MaxTokens > 0 {
+		settings.MaxTokens = openai.Int(int64(spec.MaxTokens))
+	}
 	return &Agent{
-		Agent: agents.New(role).
-			WithInstructions(instructions).
+		Log:    spec.Logger,
+		Runner: agents.DefaultRunner,
+		Agent: agents.New(spec.Name).
+			WithInstructions(spec.Instructions).
 			WithModel(model).
-			WithTools(
-				tools.FileCreatorTool,
-				tools.FileReaderTool,
-			).
-			WithModelSettings(modelsettings.ModelSettings{
-				Temperature: openai.Float(1.0),
-			}),
+			WithTools(spec.Tools.FunctionTools()...).
+			WithModelSettings(settings),
 	}
 }
 
@@ -65,6 +145,21 @@ func InputList(items ...any) []agents.TResponseInputItem {
 
 type TResponseInputItem = agents.TResponseInputItem
 
+// MapText returns a copy of items with fn applied to the text of every
+// plain-text message, e.g. to redact prompts before they are sent.
+func MapText(items []TResponseInputItem, fn func(string) string) []TResponseInputItem {
+	out := make([]TResponseInputItem, len(items))
+	for i, item := range items {
+		if m := item.OfMessage; m != nil && m.Content.OfString.Valid() {
+			msg := *m
+			msg.Content.OfString = param.NewOpt(fn(m.Content.OfString.Value))
+			item.OfMessage = &msg
+		}
+		out[i] = item
+	}
+	return out
+}
+
 func SystemMessage(message string) agents.TResponseInputItem {
 	return agents.SystemMessage(message)
 }
@@ -1,15 +1,219 @@
 package agents
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"agentflow/internal/agents/tools"
+	"agentflow/internal/config"
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
+
+	"github.com/nlpodyssey/openai-agents-go/agents"
+	sdkusage "github.com/nlpodyssey/openai-agents-go/usage"
 )
 
 func TestNewAgentDefaults(t *testing.T) {
-	a := newAgent("Role", "", "gpt-5")
+	a := newAgent(AgentSpec{Name: "Role", Model: "gpt-5", Temperature: 1})
 	if a == nil || a.Agent == nil {
 		t.Fatal("expected agent constructed")
 	}
 	// Ensure the agent prints something meaningful via fmt.Sprint
 	_ = fmt.Sprint(a.Agent)
 }
+
+func TestNewAgentModelSettings(t *testing.T) {
+	a := newAgent(AgentSpec{Name: "Role", Model: "gpt-4o-mini", Temperature: 0.2, MaxTokens: 4000})
+	ms := a.Agent.ModelSettings
+	if got := ms.Temperature.Value; got != 0.2 {
+		t.Fatalf("temperature got %v want 0.2", got)
+	}
+	if got := ms.MaxTokens.Value; got != 4000 {
+		t.Fatalf("maxTokens got %v want 4000", got)
+	}
+
+	a = newAgent(AgentSpec{Name: "Role", Temperature: 0.2})
+	if a.Agent.ModelSettings.MaxTokens.Valid() {
+		t.Fatal("expected maxTokens unset when spec leaves it zero")
+	}
+}
+
+func TestNewProviderSelection(t *testing.T) {
+	p, err := NewProvider(config.LLMConfig{})
+	if err != nil {
+		t.Fatalf("default provider: %v", err)
+	}
+	if p.Name() != "openai" {
+		t.Fatalf("expected openai provider, got %s", p.Name())
+	}
+
+	p, err = NewProvider(config.LLMConfig{
+		Provider: config.ProviderOpenAICompatible,
+		BaseURL:  "http://localhost:11434/v1",
+		Headers:  map[string]string{"X-Team": "agentflow"},
+	})
+	if err != nil {
+		t.Fatalf("compatible provider: %v", err)
+	}
+	if p.Name() != "openai-compatible" {
+		t.Fatalf("expected openai-compatible provider, got %s", p.Name())
+	}
+	r, err := p.NewRunner(AgentSpec{Name: SolutionArchitect})
+	if err != nil || r == nil {
+		t.Fatalf("expected runner, got %v", err)
+	}
+
+	if _, err := NewProvider(config.LLMConfig{Provider: config.ProviderOpenAICompatible}); err == nil {
+		t.Fatal("expected error for missing base URL")
+	}
+	if _, err := NewProvider(config.LLMConfig{Provider: "nope"}); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
+
+func TestRoleRegistryResolve(t *testing.T) {
+	reg := NewRoleRegistry(map[string]string{
+		"sa":                "Custom SA prompt",
+		"security_reviewer": "You review designs for security issues.",
+	})
+
+	sa, err := reg.Resolve("sa")
+	if err != nil {
+		t.Fatalf("resolve sa: %v", err)
+	}
+	if sa.Name != SolutionArchitect || sa.Instructions != "Custom SA prompt" {
+		t.Fatalf("unexpected sa role: %+v", sa)
+	}
+
+	qa, err := reg.Resolve("qa")
+	if err != nil {
+		t.Fatalf("resolve built-in qa: %v", err)
+	}
+	if qa.Name != LeadQA || qa.Instructions == "" {
+		t.Fatalf("unexpected qa role: %+v", qa)
+	}
+
+	sec, err := reg.Resolve("security_reviewer")
+	if err != nil {
+		t.Fatalf("resolve custom role: %v", err)
+	}
+	if sec.Name != "security_reviewer" {
+		t.Fatalf("custom role should use its key as name, got %q", sec.Name)
+	}
+
+	if _, err := reg.Resolve("pirate"); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("expected ErrUnknownRole, got %v", err)
+	}
+}
+
+func TestMockProviderReplaysFixture(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.md")
+	out := filepath.Join(dir, "out", "srs.md")
+	if err := os.WriteFile(src, []byte("# Requirements"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := MockScript{
+		Calls: []MockCall{
+			{Tool: "file_reader", Args: json.RawMessage(fmt.Sprintf(`{"Path": %q}`, src))},
+			{Tool: "file_creator", Args: json.RawMessage(fmt.Sprintf(`{"Path": %q, "Content": "# SRS"}`, out))},
+			{Tool: "file_reader", Args: json.RawMessage(`{"Path": "/nonexistent/file.md"}`)},
+		},
+		Output: "done",
+	}
+	data, _ := json.Marshal(script)
+	if err := os.WriteFile(filepath.Join(dir, "plan.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewProvider(config.LLMConfig{Provider: config.ProviderMock, Fixtures: dir})
+	if err != nil {
+		t.Fatalf("mock provider: %v", err)
+	}
+	ws := &tools.Workspace{ReadRoots: []string{dir}, WriteRoots: []string{dir}}
+	r, err := p.NewRunner(AgentSpec{Stage: "plan", Name: SolutionArchitect, Tools: ws.Tools()})
+	if err != nil {
+		t.Fatalf("new runner: %v", err)
+	}
+	got, err := r.RunInputs(context.Background(), InputList(SystemMessage("prompt")))
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if got != "done" {
+		t.Fatalf("output got %q want %q", got, "done")
+	}
+	if b, err := os.ReadFile(out); err != nil || string(b) != "# SRS" {
+		t.Fatalf("expected scripted file written, got %q (%v)", b, err)
+	}
+	calls := p.(*MockProvider).Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 recorded calls, got %d", len(calls))
+	}
+	if calls[0].Result != "# Requirements" || calls[2].Error == "" {
+		t.Fatalf("unexpected call records: %+v", calls)
+	}
+
+	r, _ = p.NewRunner(AgentSpec{Stage: "qa"})
+	if _, err := r.RunInputs(context.Background(), nil); err == nil {
+		t.Fatal("expected missing fixture error")
+	}
+}
+
+func TestMockProviderScriptedError(t *testing.T) {
+	p := NewMockProvider(MockProviderOptions{Scripts: map[string]MockScript{
+		"uml": {Error: "rate limited"},
+	}})
+	r, _ := p.NewRunner(AgentSpec{Stage: "uml"})
+	if _, err := r.RunInputs(context.Background(), nil); err == nil || err.Error() != "rate limited" {
+		t.Fatalf("expected scripted error, got %v", err)
+	}
+}
+
+// textModel answers every turn with text only, like a model that never
+// calls a tool.
+type textModel struct {
+	agents.Model
+	turns int
+}
+
+func (m *textModel) GetResponse(context.Context, agents.ModelResponseParams) (*agents.ModelResponse, error) {
+	m.turns++
+	return &agents.ModelResponse{Usage: &sdkusage.Usage{InputTokens: 1000, OutputTokens: 100}}, nil
+}
+
+type textModelProvider struct{ model *textModel }
+
+func (p textModelProvider) GetModel(string) (agents.Model, error) { return p.model, nil }
+
+func TestObservedModelStopsRunWithoutToolCalls(t *testing.T) {
+	cfg := config.DefaultConfig("Demo", "gpt-5")
+	cfg.Budget.MaxTokensPerRun = 2500
+	ctx, cancel := context.WithCancelCause(context.Background())
+	ctx = usage.NewContext(ctx, usage.NewMeter(cfg, "gpt-5", 0, cancel))
+	run := transcript.New("qa", "openai", "gpt-5", nil)
+	ctx = transcript.NewContext(ctx, run)
+	fake := &textModel{}
+	model, err := observedProvider{textModelProvider{fake}}.GetModel("gpt-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drive the model the way the SDK's run loop does, up to its turn limit.
+	var runErr error
+	for turn := 0; turn < 10 && runErr == nil; turn++ {
+		_, runErr = model.GetResponse(ctx, agents.ModelResponseParams{})
+	}
+	if !errors.Is(runErr, usage.ErrBudgetExceeded) || fake.turns != 3 {
+		t.Fatalf("run stopped after %d turns with %v, want 3 turns and ErrBudgetExceeded", fake.turns, runErr)
+	}
+	// The stopped run's turns are in its transcript, the one that crossed
+	// the budget included.
+	if len(run.Responses) != 3 || run.Responses[2].Usage == nil || run.Responses[2].Usage.InputTokens != 1000 {
+		t.Fatalf("transcript responses: %+v", run.Responses)
+	}
+}
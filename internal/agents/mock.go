@@ -0,0 +1,164 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"agentflow/internal/agents/tools"
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
+)
+
+// DefaultFixturesDir is where the mock provider looks for scripts when
+// llm.fixtures is not set.
+const DefaultFixturesDir = ".agentflow/fixtures"
+
+// MockScript is a scripted agent run, stored as <fixtures>/<stage>.json:
+//
+//	{
+//	  "calls": [
+//	    {"tool": "file_reader", "args": {"Path": ".agentflow/output/requirements.md"}},
+//	    {"tool": "file_creator", "args": {"Path": ".agentflow/output/srs.md", "Content": "# SRS"}}
+//	  ],
+//	  "output": "done"
+//	}
+//
+// Calls are replayed in order against the tools in the agent spec, so the
+// same workspace policy applies as for a live model. A non-empty Error makes
+// the run fail after the calls have been replayed.
+type MockScript struct {
+	Calls  []MockCall `json:"calls"`
+	Output string     `json:"output"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// MockCall is a single scripted tool invocation. InputTokens and
+// OutputTokens are the usage of the model turn that issued the call; they
+// are metered like a live model's.
+type MockCall struct {
+	Tool         string          `json:"tool"`
+	Args         json.RawMessage `json:"args"`
+	InputTokens  int64           `json:"inputTokens,omitempty"`
+	OutputTokens int64           `json:"outputTokens,omitempty"`
+}
+
+// MockToolCall records a replayed tool call and its outcome.
+type MockToolCall struct {
+	Stage  string
+	Tool   string
+	Args   string
+	Result string
+	Error  string
+}
+
+// MockProviderOptions configures the mock provider. Scripts, keyed by stage,
+// take precedence over fixture files in Fixtures.
+type MockProviderOptions struct {
+	Fixtures string
+	Scripts  map[string]MockScript
+}
+
+// MockProvider is a deterministic, offline Provider that replays scripted
+// runs. It is meant for tests and dry runs that need to exercise commands
+// past prompt rendering.
+type MockProvider struct {
+	fixtures string
+	scripts  map[string]MockScript
+
+	mu    sync.Mutex
+	calls []MockToolCall
+}
+
+func NewMockProvider(opts MockProviderOptions) *MockProvider {
+	dir := strings.TrimSpace(opts.Fixtures)
+	if dir == "" {
+		dir = DefaultFixturesDir
+	}
+	return &MockProvider{fixtures: dir, scripts: opts.Scripts}
+}
+
+func (p *MockProvider) Name() string { return "mock" }
+
+func (p *MockProvider) NewRunner(spec AgentSpec) (Runner, error) {
+	if strings.TrimSpace(spec.Stage) == "" {
+		return nil, errors.New("mock provider: agent spec has no stage")
+	}
+	return &mockRunner{provider: p, stage: spec.Stage, tools: spec.Tools}, nil
+}
+
+// Calls returns every tool call replayed so far, across all runners.
+func (p *MockProvider) Calls() []MockToolCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MockToolCall(nil), p.calls...)
+}
+
+func (p *MockProvider) script(stage string) (MockScript, error) {
+	if s, ok := p.scripts[stage]; ok {
+		return s, nil
+	}
+	path := filepath.Join(p.fixtures, stage+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return MockScript{}, fmt.Errorf("mock provider: no fixture for stage %q at %s", stage, path)
+		}
+		return MockScript{}, err
+	}
+	var s MockScript
+	if err := json.Unmarshal(data, &s); err != nil {
+		return MockScript{}, fmt.Errorf("mock provider: parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (p *MockProvider) record(c MockToolCall) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, c)
+}
+
+type mockRunner struct {
+	provider *MockProvider
+	stage    string
+	tools    tools.Set
+}
+
+func (r *mockRunner) RunInputs(ctx context.Context, _ []TResponseInputItem) (string, error) {
+	script, err := r.provider.script(r.stage)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range script.Calls {
+		if c.InputTokens > 0 || c.OutputTokens > 0 {
+			transcript.FromContext(ctx).AddResponse(transcript.Response{Usage: &transcript.Usage{
+				InputTokens:  c.InputTokens,
+				OutputTokens: c.OutputTokens,
+				TotalTokens:  c.InputTokens + c.OutputTokens,
+			}})
+			usage.FromContext(ctx).Add(c.InputTokens, c.OutputTokens)
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		// Like the SDK, tool errors are reported back to the "model" and do
+		// not abort the run.
+		result, err := r.tools.Call(ctx, c.Tool, c.Args)
+		rec := MockToolCall{Stage: r.stage, Tool: c.Tool, Args: string(c.Args), Result: result}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		r.provider.record(rec)
+	}
+	if script.Error != "" {
+		return "", errors.New(script.Error)
+	}
+	transcript.FromContext(ctx).AddResponse(transcript.Response{Text: script.Output})
+	return script.Output, nil
+}
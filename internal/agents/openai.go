@@ -0,0 +1,105 @@
+package agents
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"agentflow/internal/retry"
+
+	"github.com/nlpodyssey/openai-agents-go/agents"
+	"github.com/openai/openai-go/v2/option"
+	"github.com/openai/openai-go/v2/packages/param"
+)
+
+// RetryOptions configures the retrying transport every HTTP-backed provider
+// sends its requests through.
+type RetryOptions struct {
+	Policy  retry.Policy
+	Limiter *retry.Limiter // nil means no rate limit
+}
+
+// clientOptions replaces the API client's built-in retries with the
+// transport configured by r.
+func (r RetryOptions) clientOptions() []option.RequestOption {
+	transport := &retry.Transport{Policy: r.Policy, Limiter: r.Limiter}
+	return []option.RequestOption{
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(0),
+	}
+}
+
+// OpenAIProvider runs agents against the OpenAI API using the SDK defaults
+// (OPENAI_API_KEY, Responses API).
+type OpenAIProvider struct {
+	runner agents.Runner
+}
+
+func NewOpenAIProvider(r RetryOptions) *OpenAIProvider {
+	client := agents.NewOpenaiClient(param.Opt[string]{}, param.Opt[string]{}, r.clientOptions()...)
+	provider := agents.NewOpenAIProvider(agents.OpenAIProviderParams{
+		OpenaiClient: &client,
+		UseResponses: param.NewOpt(true),
+	})
+	return &OpenAIProvider{
+		runner: agents.Runner{Config: agents.RunConfig{ModelProvider: observedProvider{provider}}},
+	}
+}
+
+func (p *OpenAIProvider) Name() string { return "openai" }
+
+func (p *OpenAIProvider) NewRunner(spec AgentSpec) (Runner, error) {
+	a := newAgent(spec)
+	a.Runner = p.runner
+	return a, nil
+}
+
+// HTTPProviderOptions configures an OpenAI-compatible HTTP backend.
+type HTTPProviderOptions struct {
+	BaseURL string
+	// APIKeyEnv names the environment variable holding the API key. Local
+	// gateways often need none, in which case a placeholder is sent.
+	APIKeyEnv string
+	Headers   map[string]string
+	Retry     RetryOptions
+}
+
+// HTTPProvider runs agents against any server exposing the OpenAI Chat
+// Completions API, such as vLLM or Ollama.
+type HTTPProvider struct {
+	runner agents.Runner
+}
+
+func NewHTTPProvider(opts HTTPProviderOptions) (*HTTPProvider, error) {
+	baseURL := strings.TrimSpace(opts.BaseURL)
+	if baseURL == "" {
+		return nil, errors.New("openai-compatible provider requires a base URL")
+	}
+	apiKey := "unused"
+	if k := strings.TrimSpace(opts.APIKeyEnv); k != "" {
+		if v := os.Getenv(k); v != "" {
+			apiKey = v
+		}
+	}
+	reqOpts := opts.Retry.clientOptions()
+	for k, v := range opts.Headers {
+		reqOpts = append(reqOpts, option.WithHeader(k, v))
+	}
+	client := agents.NewOpenaiClient(param.NewOpt(baseURL), param.NewOpt(apiKey), reqOpts...)
+	provider := agents.NewOpenAIProvider(agents.OpenAIProviderParams{
+		OpenaiClient: &client,
+		UseResponses: param.NewOpt(false),
+	})
+	return &HTTPProvider{
+		runner: agents.Runner{Config: agents.RunConfig{ModelProvider: observedProvider{provider}}},
+	}, nil
+}
+
+func (p *HTTPProvider) Name() string { return "openai-compatible" }
+
+func (p *HTTPProvider) NewRunner(spec AgentSpec) (Runner, error) {
+	a := newAgent(spec)
+	a.Runner = p.runner
+	return a, nil
+}
@@ -0,0 +1,83 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"agentflow/internal/agents/tools"
+	"agentflow/internal/config"
+	"agentflow/internal/retry"
+)
+
+// Display names of the built-in personas.
+const (
+	ProductOwner      = "Product Owner"
+	SolutionArchitect = "Solution Architect"
+	LeadDeveloper     = "Lead Developer"
+	LeadQA            = "Lead QA"
+)
+
+// DefaultModel is used when an AgentSpec does not name a model.
+const DefaultModel = "gpt-5"
+
+// Runner executes an agent over a list of input items and returns the final
+// output of the run.
+type Runner interface {
+	RunInputs(ctx context.Context, prompts []TResponseInputItem) (string, error)
+}
+
+// AgentSpec describes the agent a command wants to run. Stage names the
+// command the agent runs for and Tools are the only tools it may call. A
+// zero MaxTokens leaves the limit to the backend; a nil Logger uses
+// slog.Default().
+type AgentSpec struct {
+	Stage        string
+	Name         string
+	Instructions string
+	Model        string
+	Temperature  float64
+	MaxTokens    int
+	Tools        tools.Set
+	Logger       *slog.Logger
+}
+
+// Provider builds Runners backed by a particular LLM backend. Commands take
+// a Provider by injection so tests and alternative backends can replace the
+// default OpenAI one.
+type Provider interface {
+	Name() string
+	NewRunner(spec AgentSpec) (Runner, error)
+}
+
+// NewProvider returns the Provider selected by llm.provider.
+// HTTP providers retry failed requests per llm.retry and share the
+// process-wide limiter configured by llm.rateLimit.
+func NewProvider(llm config.LLMConfig) (Provider, error) {
+	r := RetryOptions{
+		Policy: retry.Policy{
+			MaxAttempts:    llm.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(llm.Retry.InitialBackoff),
+			MaxBackoff:     time.Duration(llm.Retry.MaxBackoff),
+			MaxRetryAfter:  time.Duration(llm.Retry.MaxRetryAfter),
+		},
+		Limiter: retry.Shared(llm.RateLimit.RequestsPerMinute, llm.RateLimit.Burst),
+	}
+	switch strings.TrimSpace(llm.Provider) {
+	case "", config.ProviderOpenAI:
+		return NewOpenAIProvider(r), nil
+	case config.ProviderOpenAICompatible:
+		return NewHTTPProvider(HTTPProviderOptions{
+			BaseURL:   llm.BaseURL,
+			APIKeyEnv: llm.APIKeyEnv,
+			Headers:   llm.Headers,
+			Retry:     r,
+		})
+	case config.ProviderMock:
+		return NewMockProvider(MockProviderOptions{Fixtures: llm.Fixtures}), nil
+	default:
+		return nil, fmt.Errorf("unsupported llm provider: %s", llm.Provider)
+	}
+}
@@ -0,0 +1,73 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"agentflow/internal/config"
+)
+
+// ErrUnknownRole is returned when a --role value is neither built in nor
+// defined in the roles section of config.json.
+var ErrUnknownRole = errors.New("unknown role")
+
+// Role is a resolved persona: the agent name shown to the model and the
+// instructions it runs with.
+type Role struct {
+	Key          string
+	Name         string
+	Instructions string
+}
+
+// personaNames maps built-in role keys to agent display names. Custom roles
+// use their key as the name.
+var personaNames = map[string]string{
+	"po_pm": ProductOwner,
+	"sa":    SolutionArchitect,
+	"qa":    LeadQA,
+	"dev":   LeadDeveloper,
+}
+
+// RoleRegistry resolves role keys to personas. Roles from config take
+// precedence over the built-in defaults.
+type RoleRegistry struct {
+	roles map[string]string
+}
+
+func NewRoleRegistry(roles map[string]string) *RoleRegistry {
+	merged := config.DefaultRoles()
+	for k, v := range roles {
+		k = strings.TrimSpace(k)
+		if k == "" || strings.TrimSpace(v) == "" {
+			continue
+		}
+		merged[k] = v
+	}
+	return &RoleRegistry{roles: merged}
+}
+
+// Resolve returns the persona registered under key.
+func (r *RoleRegistry) Resolve(key string) (Role, error) {
+	key = strings.TrimSpace(key)
+	instructions, ok := r.roles[key]
+	if !ok {
+		return Role{}, fmt.Errorf("%w %q (known: %s)", ErrUnknownRole, key, strings.Join(r.Keys(), ", "))
+	}
+	name := personaNames[key]
+	if name == "" {
+		name = key
+	}
+	return Role{Key: key, Name: name, Instructions: instructions}, nil
+}
+
+// Keys returns the registered role keys in sorted order.
+func (r *RoleRegistry) Keys() []string {
+	keys := make([]string, 0, len(r.roles))
+	for k := range r.roles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"agentflow/internal/config"
+	"agentflow/internal/questions"
+)
+
+// ErrAwaitingAnswers is returned when a stage asked questions through
+// ask_human that nobody has answered yet.
+var ErrAwaitingAnswers = errors.New("waiting for answers to agent questions")
+
+// AskHumanArgs defines the input for the ask_human tool.
+type AskHumanArgs struct {
+	Question string
+	Context  string
+}
+
+const assumeInstead = "No human is available to answer. Make a reasonable assumption, state it explicitly under an \"Assumptions\" heading in the document you are writing, and continue."
+
+// Asker implements the ask_human tool for one stage run. Mode is one of the
+// config.AskHuman* values. Questions and answers are kept in the tracker at
+// Path (questions.json), so a question asked again on a later run is
+// answered from there.
+//
+// In interactive mode the question is written to Out and the answer read
+// from In; a nil In means no terminal is attached and the agent is told to
+// assume instead. In file mode the question is added to the tracker and
+// counted in Pending so the command can pause the stage.
+type Asker struct {
+	Mode  string
+	Stage string
+	Path  string
+	In    io.Reader
+	Out   io.Writer
+
+	mu      sync.Mutex
+	pending int
+}
+
+// terminal is held while a question is on the terminal, so stages running
+// in parallel ask one at a time. It is released once the answer has been
+// read, even if the question was cancelled before then.
+var terminal = make(chan struct{}, 1)
+
+// Tools returns the ask_human tool.
+func (a *Asker) Tools() Set {
+	return Set{
+		newTool("ask_human", "Ask the product team a clarifying question when the documents leave something important undecided. Returns the answer, or instructions to proceed with a stated assumption.", a.Ask),
+	}
+}
+
+// Pending returns the number of questions written to the questions file
+// during this run.
+func (a *Asker) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.pending
+}
+
+// Ask handles one ask_human call.
+func (a *Asker) Ask(ctx context.Context, args AskHumanArgs) (string, error) {
+	text := strings.TrimSpace(args.Question)
+	if text == "" {
+		return "", errors.New("ask_human: question is required")
+	}
+	detail := strings.TrimSpace(args.Context)
+	store, err := questions.Load(a.Path)
+	if err != nil {
+		return "", err
+	}
+	if q := store.Find(a.Stage, text); q != nil && q.Answered() {
+		return answerFromHuman(q.Answer), nil
+	}
+	q := questions.Question{Stage: a.Stage, Origin: questions.OriginAskHuman, Text: text, Context: detail}
+
+	switch strings.TrimSpace(a.Mode) {
+	case config.AskHumanOff:
+		return assumeInstead, nil
+	case config.AskHumanFile:
+		var id string
+		err := questions.Update(a.Path, func(s *questions.Store) error {
+			stored, _ := s.Add(q)
+			id = stored.ID
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		a.mu.Lock()
+		a.pending++
+		a.mu.Unlock()
+		return fmt.Sprintf("The question was recorded as %s for the product team and this stage will pause until it is answered. %s", id, assumeInstead), nil
+	default:
+		answer, err := a.prompt(ctx, text, detail)
+		if ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+		if err != nil || answer == "" {
+			return assumeInstead, nil
+		}
+		err = questions.Update(a.Path, func(s *questions.Store) error {
+			stored, _ := s.Add(q)
+			return s.Answer(stored.ID, answer)
+		})
+		if err != nil {
+			return "", err
+		}
+		return answerFromHuman(answer), nil
+	}
+}
+
+// prompt asks question on the terminal and reads a single-line answer. It
+// gives up when ctx is cancelled, so Ctrl-C is not stuck behind a question.
+// Questions from other stages wait their turn.
+func (a *Asker) prompt(ctx context.Context, question, detail string) (string, error) {
+	if a.In == nil || a.Out == nil {
+		return "", errors.New("no terminal")
+	}
+	select {
+	case terminal <- struct{}{}:
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	}
+	fmt.Fprintf(a.Out, "\n? [%s] %s\n", a.Stage, question)
+	if detail != "" {
+		fmt.Fprintf(a.Out, "  (%s)\n", detail)
+	}
+	fmt.Fprint(a.Out, "> ")
+
+	// A read blocked on the terminal cannot be interrupted. The reader
+	// owns the terminal until its line arrives and then exits, so a
+	// cancelled question keeps no goroutine beyond that line.
+	type result struct {
+		line string
+		err  error
+	}
+	answer := make(chan result, 1)
+	go func() {
+		defer func() { <-terminal }()
+		line, err := readLine(a.In)
+		answer <- result{line, err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", context.Cause(ctx)
+	case r := <-answer:
+		if r.line == "" && r.err != nil {
+			return "", r.err
+		}
+		return strings.TrimSpace(r.line), nil
+	}
+}
+
+// readLine reads one line from r a byte at a time, so that nothing past it
+// is consumed and the next question, from any stage, reads on from there.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+func answerFromHuman(answer string) string {
+	return "Answer from the product team: " + strings.TrimSpace(answer)
+}
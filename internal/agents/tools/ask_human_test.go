@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agentflow/internal/config"
+	"agentflow/internal/questions"
+)
+
+func TestAskerModes(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "questions.json")
+	args := AskHumanArgs{Question: "What is the launch date?"}
+
+	off := &Asker{Mode: config.AskHumanOff, Stage: "plan", Path: path}
+	if got, err := off.Ask(ctx, args); err != nil || !strings.Contains(got, "Assumptions") {
+		t.Fatalf("off mode: %q, %v", got, err)
+	}
+
+	file := &Asker{Mode: config.AskHumanFile, Stage: "plan", Path: path}
+	for i := 0; i < 2; i++ {
+		if _, err := file.Ask(ctx, args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store, err := questions.Load(path)
+	if err != nil || len(store.Questions) != 1 || !store.Questions[0].Blocking() || file.Pending() != 2 {
+		t.Fatalf("file mode should record the question once, got %+v, %v (pending %d)", store, err, file.Pending())
+	}
+
+	var out bytes.Buffer
+	tty := &Asker{Mode: config.AskHumanInteractive, Stage: "plan", Path: path, In: strings.NewReader("Q3 2026\n"), Out: &out}
+	got, err := tty.Ask(ctx, args)
+	if err != nil || !strings.Contains(got, "Q3 2026") || !strings.Contains(out.String(), args.Question) {
+		t.Fatalf("interactive mode: %q, %v (prompt %q)", got, err, out.String())
+	}
+
+	// The stored answer is reused without asking again, whatever the mode.
+	got, err = file.Ask(ctx, args)
+	if err != nil || !strings.Contains(got, "Q3 2026") || file.Pending() != 2 {
+		t.Fatalf("answered question: %q, %v (pending %d)", got, err, file.Pending())
+	}
+
+	noTTY := &Asker{Mode: config.AskHumanInteractive, Stage: "design", Path: path}
+	if got, err := noTTY.Ask(ctx, AskHumanArgs{Question: "Cloud provider?"}); err != nil || !strings.Contains(got, "assumption") {
+		t.Fatalf("interactive without terminal: %q, %v", got, err)
+	}
+}
+
+func TestAskerPromptCancelled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "questions.json")
+	in, w := io.Pipe() // never written: the answer does not come
+	defer w.Close()
+	a := &Asker{Mode: config.AskHumanInteractive, Stage: "plan", Path: path, In: in, Out: io.Discard}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := a.Ask(ctx, AskHumanArgs{Question: "Launch date?"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The cancelled question's reader ends with its input and frees the
+	// terminal; stages sharing an input then read one line each.
+	w.Close()
+	shared := strings.NewReader("Q3 2026\nAWS\n")
+	for _, q := range []struct{ stage, question, want string }{{"plan", "Launch date?", "Q3 2026"}, {"design", "Cloud provider?", "AWS"}} {
+		a := &Asker{Mode: config.AskHumanInteractive, Stage: q.stage, Path: path, In: shared, Out: io.Discard}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		got, err := a.Ask(ctx, AskHumanArgs{Question: q.question})
+		cancel()
+		if err != nil || !strings.HasSuffix(got, q.want) {
+			t.Fatalf("%s: %q, %v", q.stage, got, err)
+		}
+	}
+}
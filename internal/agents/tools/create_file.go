@@ -2,37 +2,72 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-
-	"github.com/nlpodyssey/openai-agents-go/agents"
-)
-
-// FileCreatorTool is a tool that creates a file with specified content.
-var FileCreatorTool = agents.NewFunctionTool(
-	"file_creator",
-	"Create a file with specified content.",
-	CreateFile,
 )
 
-// CreateFileInput defines the input for the file creation tool.
+// CreateFileArgs defines the input for the file creation tool.
 type CreateFileArgs struct {
 	Path    string
 	Content string
 }
 
-// CreateFile creates a file at the given path with the provided content.
-func CreateFile(_ context.Context, args CreateFileArgs) (string, error) {
-	dir := filepath.Dir(args.Path)
-	if dir != "." && dir != "" {
-		if err := os.MkdirAll(dir, 0o755); err != nil {
-			return "", err
+// CreateFile creates a file at the given path with the provided content, as
+// long as the path is inside the workspace's writable roots and allow-list.
+// Refused calls are recorded; see Refused. The content is written to a
+// temporary file and renamed into place, so a cancelled call never leaves a
+// half-written file behind.
+func (w *Workspace) CreateFile(ctx context.Context, args CreateFileArgs) (string, error) {
+	if int64(len(args.Content)) > w.maxBytes() {
+		return "", w.refuse(&PolicyError{Op: "write", Path: args.Path, Reason: fmt.Sprintf("content exceeds %d bytes", w.maxBytes())})
+	}
+	path, err := w.CheckWrite(args.Path)
+	if err != nil {
+		var pe *PolicyError
+		if errors.As(err, &pe) {
+			return "", w.refuse(pe)
 		}
+		return "", err
 	}
-
-	if err := os.WriteFile(args.Path, []byte(args.Content), 0o644); err != nil {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.journal(path); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := writeAtomic(ctx, path, []byte(args.Content)); err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("created file: %s", args.Path), nil
 }
+
+// writeAtomic writes data to path through a temporary file in the same
+// directory. The file is only renamed into place if ctx is still live.
+func writeAtomic(ctx context.Context, path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0o644)
+	}
+	if err == nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -2,16 +2,8 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"os"
-
-	"github.com/nlpodyssey/openai-agents-go/agents"
-)
-
-// FileReaderTool is a tool that reads the contents of a file at a given path.
-var FileReaderTool = agents.NewFunctionTool(
-	"file_reader",
-	"Read the contents of a file at the specified path.",
-	ReadFile,
 )
 
 // ReadFileArgs defines the input for the file reader tool.
@@ -19,11 +11,29 @@ type ReadFileArgs struct {
 	Path string
 }
 
-// ReadFile reads a file at the given path and returns its contents as a string.
-func ReadFile(_ context.Context, args ReadFileArgs) (string, error) {
-	data, err := os.ReadFile(args.Path)
+// ReadFile reads a file at the given path and returns its contents as a
+// string, as long as the path is inside the workspace's readable roots.
+func (w *Workspace) ReadFile(ctx context.Context, args ReadFileArgs) (string, error) {
+	path, err := w.CheckRead(args.Path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
 	if err != nil {
 		return "", err
 	}
+	if info.Size() > w.maxBytes() {
+		return "", &PolicyError{Op: "read", Path: args.Path, Reason: fmt.Sprintf("file exceeds %d bytes", w.maxBytes())}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if ctx.Err() != nil {
+		return "", context.Cause(ctx)
+	}
+	if w.Redact != nil {
+		return w.Redact(string(data)), nil
+	}
 	return string(data), nil
 }
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"agentflow/internal/transcript"
+
+	"github.com/nlpodyssey/openai-agents-go/agents"
+)
+
+// Tool is a function the agent may call. The SDK form is handed to real
+// models; Invoke lets providers that do not go through the SDK, such as the
+// mock provider, call the same code with JSON-encoded arguments.
+type Tool struct {
+	Name     string
+	Function agents.FunctionTool
+	invoke   func(ctx context.Context, args json.RawMessage) (string, error)
+}
+
+func newTool[T any](name, description string, fn func(context.Context, T) (string, error)) Tool {
+	fn = recorded(name, fn)
+	return Tool{
+		Name:     name,
+		Function: agents.NewFunctionTool(name, description, fn),
+		invoke: func(ctx context.Context, raw json.RawMessage) (string, error) {
+			var args T
+			if len(raw) > 0 {
+				if err := json.Unmarshal(raw, &args); err != nil {
+					return "", fmt.Errorf("%s: decode args: %w", name, err)
+				}
+			}
+			return fn(ctx, args)
+		},
+	}
+}
+
+// recorded wraps fn so that every call is added to the run's transcript,
+// if the context carries one. Once the run's budget is spent the context is
+// cancelled and the tool refuses to run.
+func recorded[T any](name string, fn func(context.Context, T) (string, error)) func(context.Context, T) (string, error) {
+	return func(ctx context.Context, args T) (string, error) {
+		start := time.Now()
+		var (
+			result string
+			err    error
+		)
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		} else {
+			result, err = fn(ctx, args)
+		}
+		if t := transcript.FromContext(ctx); t != nil {
+			raw, _ := json.Marshal(args)
+			t.AddToolCall(name, raw, result, err, start)
+		}
+		return result, err
+	}
+}
+
+// Invoke calls the tool with JSON-encoded arguments.
+func (t Tool) Invoke(ctx context.Context, args json.RawMessage) (string, error) {
+	return t.invoke(ctx, args)
+}
+
+// Set is the collection of tools available to a single agent run.
+type Set []Tool
+
+// FunctionTools returns the SDK form of every tool in the set.
+func (s Set) FunctionTools() []agents.Tool {
+	out := make([]agents.Tool, len(s))
+	for i, t := range s {
+		out[i] = t.Function
+	}
+	return out
+}
+
+// Call invokes the named tool, the same way the model would through the SDK.
+func (s Set) Call(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	for _, t := range s {
+		if t.Name == name {
+			return t.Invoke(ctx, args)
+		}
+	}
+	return "", fmt.Errorf("unknown tool: %s", name)
+}
@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestWorkspace(t *testing.T) (*Workspace, string, string) {
+	t.Helper()
+	root := t.TempDir()
+	in := filepath.Join(root, "input")
+	out := filepath.Join(root, "output")
+	for _, d := range []string{in, out} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg := filepath.Join(root, "config.json")
+	if err := os.WriteFile(cfg, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return &Workspace{
+		ReadRoots:  []string{in, out},
+		WriteRoots: []string{out},
+		Protected:  []string{cfg},
+	}, root, out
+}
+
+func TestWorkspaceAllowsOutputWrites(t *testing.T) {
+	ws, _, out := newTestWorkspace(t)
+	path := filepath.Join(out, "nested", "srs.md")
+	if _, err := ws.CreateFile(context.Background(), CreateFileArgs{Path: path, Content: "# SRS"}); err != nil {
+		t.Fatalf("CreateFile: %v", err)
+	}
+	got, err := ws.ReadFile(context.Background(), ReadFileArgs{Path: path})
+	if err != nil || got != "# SRS" {
+		t.Fatalf("ReadFile got %q, %v", got, err)
+	}
+}
+
+func TestWorkspaceRejectsEscapes(t *testing.T) {
+	ws, root, out := newTestWorkspace(t)
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(out, "link")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("s3cr3t"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	writes := []string{
+		"/etc/agentflow-test",
+		filepath.Join(out, "..", "..", "escape.md"),
+		filepath.Join(root, "config.json"),
+		filepath.Join(root, "input", "notes.md"),
+		filepath.Join(out, "link", "planted.md"),
+	}
+	for _, p := range writes {
+		_, err := ws.CreateFile(context.Background(), CreateFileArgs{Path: p, Content: "x"})
+		if !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("write %s: expected ErrOutsideWorkspace, got %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(outside, "planted.md")); err == nil {
+		t.Error("write through symlink escaped the workspace")
+	}
+
+	reads := []string{secret, filepath.Join(out, "link", "secret.txt"), filepath.Join(root, "config.json")}
+	for _, p := range reads {
+		if _, err := ws.ReadFile(context.Background(), ReadFileArgs{Path: p}); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("read %s: expected ErrOutsideWorkspace, got %v", p, err)
+		}
+	}
+}
+
+func TestWorkspaceSizeLimits(t *testing.T) {
+	ws, _, out := newTestWorkspace(t)
+	ws.MaxFileBytes = 8
+	_, err := ws.CreateFile(context.Background(), CreateFileArgs{Path: filepath.Join(out, "big.md"), Content: strings.Repeat("x", 9)})
+	if !errors.Is(err, ErrOutsideWorkspace) {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+	big := filepath.Join(out, "big.md")
+	if err := os.WriteFile(big, []byte(strings.Repeat("x", 9)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.ReadFile(context.Background(), ReadFileArgs{Path: big}); err == nil {
+		t.Fatal("expected read size limit error")
+	}
+}
+
+func TestSetCall(t *testing.T) {
+	ws, _, out := newTestWorkspace(t)
+	set := ws.Tools()
+	args, _ := json.Marshal(CreateFileArgs{Path: filepath.Join(out, "a.md"), Content: "a"})
+	if _, err := set.Call(context.Background(), "file_creator", args); err != nil {
+		t.Fatalf("Call file_creator: %v", err)
+	}
+	if _, err := set.Call(context.Background(), "shell", nil); err == nil {
+		t.Fatal("expected unknown tool error")
+	}
+	if got := len(set.FunctionTools()); got != 2 {
+		t.Fatalf("expected 2 SDK tools, got %d", got)
+	}
+}
+
+func TestWorkspaceAllowList(t *testing.T) {
+	ws, _, out := newTestWorkspace(t)
+	ws.Allow = []string{filepath.Join(out, "srs.md"), filepath.Join(out, "tasks"), filepath.Join(out, "notes", "*.md")}
+	ok := []string{"srs.md", "tasks/TASK-001.md", "notes/a.md"}
+	for _, p := range ok {
+		if _, err := ws.CreateFile(context.Background(), CreateFileArgs{Path: filepath.Join(out, p), Content: "x"}); err != nil {
+			t.Errorf("write %s: %v", p, err)
+		}
+	}
+	denied := []string{"requirements.md", "notes/a.txt", "tasks.md"}
+	for _, p := range denied {
+		if _, err := ws.CreateFile(context.Background(), CreateFileArgs{Path: filepath.Join(out, p), Content: "x"}); !errors.Is(err, ErrOutsideWorkspace) {
+			t.Errorf("write %s: expected refusal, got %v", p, err)
+		}
+	}
+	refused := ws.Refused()
+	if len(refused) != len(denied) {
+		t.Fatalf("expected %d refused writes, got %+v", len(denied), refused)
+	}
+	if refused[0].Path != filepath.Join(out, "requirements.md") {
+		t.Errorf("unexpected first refusal: %+v", refused[0])
+	}
+}
+
+func TestWorkspaceRollback(t *testing.T) {
+	ws, _, out := newTestWorkspace(t)
+	existing := filepath.Join(out, "srs.md")
+	if err := os.WriteFile(existing, []byte("# old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	created := filepath.Join(out, "tasks", "TASK-001.md")
+	for _, p := range []string{existing, existing, created} {
+		if _, err := ws.CreateFile(context.Background(), CreateFileArgs{Path: p, Content: "new"}); err != nil {
+			t.Fatalf("CreateFile %s: %v", p, err)
+		}
+	}
+	restored, err := ws.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if len(restored) != 2 {
+		t.Fatalf("restored = %v, want 2 paths", restored)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "# old" {
+		t.Errorf("srs.md = %q, want the original content", data)
+	}
+	if _, err := os.Stat(filepath.Join(out, "tasks")); !os.IsNotExist(err) {
+		t.Errorf("tasks/ should be removed, stat err = %v", err)
+	}
+	if restored, _ := ws.Rollback(); len(restored) != 0 {
+		t.Errorf("second rollback restored %v", restored)
+	}
+}
+
+func TestToolsRespectCancellation(t *testing.T) {
+	ws, _, out := newTestWorkspace(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	path := filepath.Join(out, "srs.md")
+	if _, err := ws.CreateFile(ctx, CreateFileArgs{Path: path, Content: "x"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CreateFile err = %v, want context.Canceled", err)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 0 {
+		t.Fatalf("cancelled write left files behind: %v", entries)
+	}
+	if _, err := ws.Tools().Call(ctx, "file_reader", json.RawMessage(`{"Path":"x"}`)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("file_reader err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultMaxFileBytes bounds how much a single file_reader or file_creator
+// call may transfer when the workspace does not set a limit.
+const DefaultMaxFileBytes = 2 << 20
+
+// ErrOutsideWorkspace is wrapped by every PolicyError so callers can detect
+// refused tool calls with errors.Is.
+var ErrOutsideWorkspace = errors.New("outside workspace")
+
+// PolicyError is returned to the model when a tool call violates the
+// workspace policy.
+type PolicyError struct {
+	Op     string
+	Path   string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s refused for %s: %s", e.Op, e.Path, e.Reason)
+}
+
+func (e *PolicyError) Unwrap() error { return ErrOutsideWorkspace }
+
+// Workspace confines the file tools to the project directories. Reads are
+// allowed under ReadRoots, writes under WriteRoots; Protected files can never
+// be written. Roots may name directories or individual files.
+//
+// Allow, when set, narrows writes further to the outputs a command is
+// expected to produce. Entries are files, directories (everything below is
+// allowed) or filepath.Match globs such as "out/tasks/TASK-*.md". Give each
+// command run its own Workspace so Refused reports only that run's writes.
+//
+// Redact, when set, is applied to file contents before file_reader returns
+// them to the model.
+//
+// The workspace remembers what each written file held before the run, so
+// Rollback can undo the run's writes if it is interrupted.
+type Workspace struct {
+	ReadRoots    []string
+	WriteRoots   []string
+	Protected    []string
+	Allow        []string
+	MaxFileBytes int64
+	Redact       func(string) string
+
+	mu      sync.Mutex
+	refused []PolicyError
+	before  map[string][]byte // resolved path → prior content; nil if new
+	dirs    []string          // directories created by writes, outermost first
+}
+
+// Tools returns the sandboxed file_creator and file_reader tools.
+func (w *Workspace) Tools() Set {
+	return Set{
+		newTool("file_creator", "Create a file with specified content.", w.CreateFile),
+		newTool("file_reader", "Read the contents of a file at the specified path.", w.ReadFile),
+	}
+}
+
+func (w *Workspace) maxBytes() int64 {
+	if w.MaxFileBytes > 0 {
+		return w.MaxFileBytes
+	}
+	return DefaultMaxFileBytes
+}
+
+// resolve returns the absolute, symlink-free form of path. Components that
+// do not exist yet are appended to the resolved form of their deepest
+// existing ancestor, so a symlinked parent directory cannot be used to
+// escape a root.
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	existing, rest := abs, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(resolved, rest), nil
+}
+
+func within(path string, roots []string) bool {
+	for _, root := range roots {
+		if strings.TrimSpace(root) == "" {
+			continue
+		}
+		r, err := resolve(root)
+		if err != nil {
+			continue
+		}
+		if path == r || strings.HasPrefix(path, r+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRead returns the resolved path if reading it is allowed.
+func (w *Workspace) CheckRead(path string) (string, error) {
+	resolved, err := resolve(path)
+	if err != nil {
+		return "", err
+	}
+	if !within(resolved, w.ReadRoots) {
+		return "", &PolicyError{Op: "read", Path: path, Reason: "path is outside the readable input/output directories"}
+	}
+	return resolved, nil
+}
+
+func allowed(path string, patterns []string) bool {
+	for _, pat := range patterns {
+		if strings.TrimSpace(pat) == "" {
+			continue
+		}
+		p, err := resolve(pat)
+		if err != nil {
+			continue
+		}
+		if path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+			return true
+		}
+		if ok, _ := filepath.Match(p, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Refused returns the writes this workspace has turned down, in the order
+// they were attempted.
+func (w *Workspace) Refused() []PolicyError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]PolicyError(nil), w.refused...)
+}
+
+// refuse records a refused write and returns it as an error for the model.
+func (w *Workspace) refuse(e *PolicyError) error {
+	w.mu.Lock()
+	w.refused = append(w.refused, *e)
+	w.mu.Unlock()
+	return e
+}
+
+// CheckWrite returns the resolved path if writing it is allowed.
+func (w *Workspace) CheckWrite(path string) (string, error) {
+	resolved, err := resolve(path)
+	if err != nil {
+		return "", err
+	}
+	for _, p := range w.Protected {
+		if rp, err := resolve(p); err == nil && rp == resolved {
+			return "", &PolicyError{Op: "write", Path: path, Reason: "file is protected"}
+		}
+	}
+	if !within(resolved, w.WriteRoots) {
+		return "", &PolicyError{Op: "write", Path: path, Reason: "path is outside the writable output directory"}
+	}
+	if len(w.Allow) > 0 && !allowed(resolved, w.Allow) {
+		return "", &PolicyError{Op: "write", Path: path, Reason: "path is not one of this command's outputs"}
+	}
+	return resolved, nil
+}
+
+// journal records the state of path before its first write in this run,
+// along with the directories the write is about to create. Callers must
+// hold w.mu.
+func (w *Workspace) journal(path string) error {
+	if _, seen := w.before[path]; seen {
+		return nil
+	}
+	if w.before == nil {
+		w.before = map[string][]byte{}
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if data == nil {
+			data = []byte{}
+		}
+	case errors.Is(err, os.ErrNotExist):
+		data = nil
+	default:
+		return err
+	}
+	var created []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		created = append([]string{dir}, created...)
+	}
+	w.before[path] = data
+	w.dirs = append(w.dirs, created...)
+	return nil
+}
+
+// Rollback restores every file written through the workspace to what it
+// held before the run: rewritten files get their old content back and new
+// files are removed, along with any directories the writes created. It
+// returns the restored paths.
+func (w *Workspace) Rollback() ([]string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var (
+		restored []string
+		errs     []error
+	)
+	for path, data := range w.before {
+		var err error
+		if data == nil {
+			err = os.Remove(path)
+			if errors.Is(err, os.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(path, data, 0o644)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		restored = append(restored, path)
+	}
+	for i := len(w.dirs) - 1; i >= 0; i-- {
+		os.Remove(w.dirs[i]) // fails, harmlessly, if something else lives there
+	}
+	w.before, w.dirs = nil, nil
+	sort.Strings(restored)
+	return restored, errors.Join(errs...)
+}
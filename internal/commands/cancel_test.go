@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/agents/tools"
+	"agentflow/internal/config"
+	"agentflow/internal/transcript"
+)
+
+// funcProvider runs every stage with run, handing it the stage's tools.
+type funcProvider struct {
+	run func(ctx context.Context, set tools.Set) (string, error)
+}
+
+func (p funcProvider) Name() string { return "func" }
+
+func (p funcProvider) NewRunner(spec agents.AgentSpec) (agents.Runner, error) {
+	return funcRunner{run: p.run, tools: spec.Tools}, nil
+}
+
+type funcRunner struct {
+	run   func(ctx context.Context, set tools.Set) (string, error)
+	tools tools.Set
+}
+
+func (r funcRunner) RunInputs(ctx context.Context, _ []agents.TResponseInputItem) (string, error) {
+	return r.run(ctx, r.tools)
+}
+
+func TestPlan_CancelRollsBackWrites(t *testing.T) {
+	dir := t.TempDir()
+	configPath := createTestConfig(t, dir)
+	srs := filepath.Join(dir, "srs.md")
+	if err := os.WriteFile(srs, []byte("# old srs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "requirements.md"), []byte("# req"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider := funcProvider{run: func(ctx context.Context, set tools.Set) (string, error) {
+		for _, name := range []string{"srs.md", "stories.md"} {
+			args, _ := json.Marshal(tools.CreateFileArgs{Path: filepath.Join(dir, name), Content: "partial"})
+			if _, err := set.Call(ctx, "file_creator", args); err != nil {
+				return "", err
+			}
+		}
+		cancel() // as if Ctrl-C arrived mid-run
+		return "", ctx.Err()
+	}}
+
+	err := Plan(ctx, PlanOptions{ConfigPath: configPath, OutputDir: dir, Provider: provider})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if data, _ := os.ReadFile(srs); string(data) != "# old srs" {
+		t.Errorf("srs.md = %q, want it restored", data)
+	}
+	for _, name := range []string{"stories.md", "acceptance_criteria.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist after rollback (stat err %v)", name, err)
+		}
+	}
+	runs, err := transcript.List(runsPath(configPath))
+	if err != nil || len(runs) != 1 || runs[0].Status != transcript.StatusFailed {
+		t.Fatalf("want one failed transcript, got %v, %v", runs, err)
+	}
+}
+
+func TestPlan_StageTimeout(t *testing.T) {
+	dir := t.TempDir()
+	configPath := createTestConfig(t, dir)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Stages = map[string]config.StageConfig{"plan": {Timeout: config.Duration(20 * time.Millisecond)}}
+	if err := config.Save(configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "requirements.md"), []byte("# req"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := funcProvider{run: func(ctx context.Context, _ tools.Set) (string, error) {
+		<-ctx.Done() // a model call that never returns on its own
+		return "", ctx.Err()
+	}}
+	err = Plan(context.Background(), PlanOptions{ConfigPath: configPath, OutputDir: dir, Provider: provider})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 20ms") {
+		t.Fatalf("err = %v, want a stage timeout", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "srs.md")); !os.IsNotExist(err) {
+		t.Errorf("a timed-out run should not leave scaffolds (stat err %v)", err)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -20,12 +20,15 @@ type DesignOptions struct {
 	OutputDir  string // where to write architecture.md and uml.md
 	Role       string
 	DryRun     bool
+	Force      bool            // rebuild even if the manifest says the outputs are current
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
 }
 
 //go:embed design_prompt.md
 var designPromptTemplate string
 
-func Design(opts DesignOptions) error {
+func Design(ctx context.Context, opts DesignOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -40,6 +43,11 @@ func Design(opts DesignOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("design")
+	role, err := resolveRole(cfg, opts.Role, "sa")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -54,16 +62,29 @@ func Design(opts DesignOptions) error {
 		return writeDesignScaffold(cfg.IO.OutputDir)
 	}
 
-	_, err = agents.SA.RunInputs(context.Background(), systemMessages)
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "design", cfg, filepath.Join(opts.SourceDir, "requirements.md"), opts.SourceDir)
 	if err != nil {
-		fmt.Printf("\n\n> Note: OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
-		// Write scaffold as fallback
-		if scaffoldErr := writeDesignScaffold(cfg.IO.OutputDir); scaffoldErr != nil {
-			return fmt.Errorf("API call failed and scaffold write failed: %v (original: %v)", scaffoldErr, err)
-		}
+		return err
 	}
+	systemMessages = run.withAnswers(systemMessages)
 
-	return err
+	b, err := newBuild(opts.ConfigPath, "design", cfg, role, systemMessages, joinAll(opts.SourceDir, stageSpecs["design"].Inputs))
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, systemMessages)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	return b.record()
 }
 
 func buildDesignSystemMessage(sourceDir, outputDir string) ([]agents.TResponseInputItem, error) {
@@ -122,43 +143,28 @@ func splitDesignContent(s string) (string, string) {
 	return "", strings.TrimSpace(uml)
 }
 
+// ensureArchitecture and ensureUML patch in Mermaid, the only diagram
+// language their prompts allow.
 func ensureArchitecture(s string) string {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		// Provide fallback content when empty
 		return `# Architecture
 
-## Project Structure
+## Project File Structure
 
-This section describes the overall project structure and organization.
+This section describes the directories and files of the project.
 
 ## Components
 
 Key components and their relationships.
 
-## PlantUML Diagrams
+## Infrastructure Diagram
 
-` + "```plantuml\n@startuml\n!theme plain\ntitle System Architecture\n@enduml\n```"
+` + "```mermaid\nflowchart LR\n  User --> App[Application]\n  App --> DB[Database]\n```"
 	}
 
-	lower := strings.ToLower(s)
-	var additions []string
-
-	// Check if Project Structure section exists
-	if !strings.Contains(lower, "project structure") {
-		additions = append(additions, "\n## Project Structure\n\nThis section describes the overall project structure and organization.")
-	}
-
-	// Check if PlantUML section exists
-	if !strings.Contains(lower, "plantuml") {
-		additions = append(additions, "\n## PlantUML Diagrams\n\n```plantuml\n@startuml\n!theme plain\ntitle System Architecture\n@enduml\n```")
-	}
-
-	if len(additions) > 0 {
-		return s + strings.Join(additions, "")
-	}
-
-	return s
+	return appendMissingSections(s, architectureSections)
 }
 
 func ensureUML(s string) string {
@@ -169,52 +175,30 @@ func ensureUML(s string) string {
 
 ## Sequence: User Interactions
 
-` + "```plantuml\n@startuml\nactor User\nUser -> System: Request\nSystem -> User: Response\n@enduml\n```" + `
+` + "```mermaid\nsequenceDiagram\n  actor User\n  User->>System: Request\n  System-->>User: Response\n```" + `
 
 ## Class: Domain Models
 
-` + "```plantuml\n@startuml\nclass Entity {\n  +id: string\n  +method()\n}\n@enduml\n```" + `
+` + "```mermaid\nclassDiagram\n  class Entity {\n    +String id\n  }\n```" + `
 
 ## Activity: Process Flow
 
-` + "```plantuml\n@startuml\nstart\n:Process Request;\n:Generate Response;\nstop\n@enduml\n```"
-	}
-
-	lower := strings.ToLower(s)
-	var additions []string
-
-	// Check for required diagram types
-	if !strings.Contains(lower, "sequence") {
-		additions = append(additions, "\n## Sequence: User Interactions\n\n```plantuml\n@startuml\nactor User\nUser -> System: Request\nSystem -> User: Response\n@enduml\n```")
+` + "```mermaid\nflowchart TD\n  A[Receive request] --> B[Process request]\n  B --> C[Send response]\n```"
 	}
 
-	if !strings.Contains(lower, "class") {
-		additions = append(additions, "\n## Class: Domain Models\n\n```plantuml\n@startuml\nclass Entity {\n  +id: string\n  +method()\n}\n@enduml\n```")
-	}
-
-	if !strings.Contains(lower, "activity") {
-		additions = append(additions, "\n## Activity: Process Flow\n\n```plantuml\n@startuml\nstart\n:Process Request;\n:Generate Response;\nstop\n@enduml\n```")
-	}
-
-	if len(additions) > 0 {
-		return s + strings.Join(additions, "")
-	}
-
-	return s
+	return appendMissingSections(s, umlSections)
 }
 func writeDesignScaffold(outputDir string) error {
 	// Write architecture.md with scaffold content
 	archContent := ensureArchitecture("")
-	archPath := filepath.Join(outputDir, "architecture.md")
-	if err := os.WriteFile(archPath, []byte(archContent), 0644); err != nil {
-		return fmt.Errorf("write architecture.md: %w", err)
+	if err := writeScaffold(filepath.Join(outputDir, "architecture.md"), archContent); err != nil {
+		return err
 	}
 
 	// Write uml.md with scaffold content
 	umlContent := ensureUML("")
-	umlPath := filepath.Join(outputDir, "uml.md")
-	if err := os.WriteFile(umlPath, []byte(umlContent), 0644); err != nil {
-		return fmt.Errorf("write uml.md: %w", err)
+	if err := writeScaffold(filepath.Join(outputDir, "uml.md"), umlContent); err != nil {
+		return err
 	}
 
 	return nil
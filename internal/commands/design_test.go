@@ -1,8 +1,14 @@
 package commands
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/diagram"
 )
 
 func TestSplitDesignContent_Variants(t *testing.T) {
@@ -15,7 +21,7 @@ func TestSplitDesignContent_Variants(t *testing.T) {
 
 func TestEnsureArchitecture_AddsMissingSections(t *testing.T) {
 	out := ensureArchitecture("Overview only")
-	if out == "" || !containsLower(out, "project structure") || !containsLower(out, "plantuml") {
+	if out == "" || !containsLower(out, "project file structure") || !containsLower(out, "```mermaid") {
 		t.Fatalf("ensureArchitecture did not add required sections: %s", out)
 	}
 }
@@ -27,6 +33,99 @@ func TestEnsureUML_AddsMissingDiagrams(t *testing.T) {
 	}
 }
 
+func TestEnsurers_PatchLintCleanDiagrams(t *testing.T) {
+	for out, ensure := range ensurers {
+		for _, doc := range []string{"", "# Draft"} {
+			if problems := diagram.Lint(out, ensure(doc)); len(problems) > 0 {
+				t.Errorf("%s patched from %q: %v", out, doc, problems)
+			}
+		}
+	}
+	if out := ensureArchitecture("Overview"); containsLower(out, "plantuml") {
+		t.Errorf("architecture.md patched with PlantUML:\n%s", out)
+	}
+	withMermaid := "# Architecture\n\n```mermaid\nflowchart LR\n  A --> B\n```\n"
+	if out := ensureArchitecture(withMermaid); strings.Count(out, "```mermaid") != 1 {
+		t.Errorf("diagram fallback added to a document that has one:\n%s", out)
+	}
+}
+
 func containsLower(s, sub string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
 }
+
+func TestDesign_RefusesWritesOutsideOutputs(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	reqPath := filepath.Join(tempDir, "requirements.md")
+	if err := os.WriteFile(reqPath, []byte("# Requirements"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"design": {Calls: []agents.MockCall{
+			createFileCall(reqPath, "clobbered"),
+			createFileCall(filepath.Join(tempDir, "architecture.md"), validDoc("architecture.md")),
+		}},
+	}})
+
+	if err := Design(context.Background(), DesignOptions{ConfigPath: configPath, SourceDir: tempDir, OutputDir: tempDir, Provider: provider}); err != nil {
+		t.Fatalf("Design: %v", err)
+	}
+	if data, _ := os.ReadFile(reqPath); string(data) != "# Requirements" {
+		t.Fatalf("requirements.md was overwritten: %q", data)
+	}
+	calls := provider.Calls()
+	if len(calls) != 2 || !strings.Contains(calls[0].Error, "not one of this command's outputs") || calls[1].Error != "" {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+}
+
+// promptArchitecture follows design_prompt.md: Mermaid diagrams only and a
+// "Project file structure" section.
+const promptArchitecture = `# Architecture
+
+## Assumptions
+- AWS in one region.
+
+## Infrastructure overview and rationale
+
+` + "```mermaid" + `
+flowchart LR
+  User --> CDN --> ALB --> App[App service]
+  App --> DB[Postgres]
+` + "```" + `
+
+## CI CD overview
+Build, test and deploy on merge.
+
+## Project file structure
+
+` + "```text" + `
+backend/
+frontend/
+infra/
+` + "```" + `
+
+## Security and observability checklist
+- Least-privilege IAM.
+`
+
+func TestDesign_AcceptsPromptCompliantArchitecture(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	archPath := filepath.Join(tempDir, "architecture.md")
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"design": {Calls: []agents.MockCall{createFileCall(archPath, promptArchitecture)}},
+	}})
+
+	if err := Design(context.Background(), DesignOptions{ConfigPath: configPath, SourceDir: tempDir, OutputDir: tempDir, Provider: provider}); err != nil {
+		t.Fatalf("Design: %v", err)
+	}
+	data, err := os.ReadFile(archPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), promptArchitecture) || containsLower(string(data), "plantuml") {
+		t.Fatalf("architecture.md was patched:\n%s", data)
+	}
+}
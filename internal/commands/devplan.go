@@ -5,6 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -12,6 +13,8 @@ import (
 
 	"agentflow/internal/agents"
 	"agentflow/internal/config"
+	"agentflow/internal/taskgraph"
+	"agentflow/internal/tasks"
 )
 
 type DevPlanOptions struct {
@@ -22,12 +25,15 @@ type DevPlanOptions struct {
 	OutputDir string
 	Role      string // usually "dev"
 	DryRun    bool
+	Force     bool            // rebuild even if the manifest says the outputs are current
+	Provider  agents.Provider // nil selects the provider configured in llm.provider
+	Logger    *slog.Logger    // nil uses slog.Default()
 }
 
 //go:embed devplan_prompt.md
 var devPlanPromptTemplate string
 
-func DevPlan(opts DevPlanOptions) error {
+func DevPlan(ctx context.Context, opts DevPlanOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -42,6 +48,11 @@ func DevPlan(opts DevPlanOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("devplan")
+	role, err := resolveRole(cfg, opts.Role, "dev")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -55,11 +66,42 @@ func DevPlan(opts DevPlanOptions) error {
 		return nil
 	}
 
-	_, err = agents.SA.RunInputs(context.Background(), prompts)
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "devplan", cfg, filepath.Join(opts.SourceDir, "requirements.md"), opts.SourceDir)
+	if err != nil {
+		return err
+	}
+	prompts = run.withAnswers(prompts)
+
+	b, err := newBuild(opts.ConfigPath, "devplan", cfg, role, prompts, joinAll(opts.SourceDir, stageSpecs["devplan"].Inputs))
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, prompts)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	warnTaskGraph(run.log, cfg.IO.OutputDir)
+	return b.record()
+}
+
+// warnTaskGraph logs the dependency problems of the plan just written, so
+// they surface before anyone runs `agentflow tasks graph`.
+func warnTaskGraph(log *slog.Logger, outputDir string) {
+	plan, err := tasks.Load(outputDir)
 	if err != nil {
-		fmt.Printf("\n\n> Note: OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
+		return
+	}
+	for _, p := range taskgraph.FromPlan(plan).Problems() {
+		log.Warn("task dependency problem", "task", p.ID, "problem", p.Message)
 	}
-	return err
 }
 
 func buildDevPlanSystemMessage(sourceDir string, cfg *config.Config) ([]agents.TResponseInputItem, error) {
@@ -109,3 +151,10 @@ func buildDevPlanSystemMessage(sourceDir string, cfg *config.Config) ([]agents.T
 		agents.SystemMessage(buf.String()),
 	), nil
 }
+
+func ensureTaskList(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "# Task List\n\n- [ ] TASK-001 — Project Scaffold / Bootstrap"
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+)
+
+func TestDevPlan_MockProvider(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"devplan": {
+			Calls: []agents.MockCall{
+				createFileCall(filepath.Join(tempDir, "task_list.md"), "- [ ] TASK-001 — Project Scaffold / Bootstrap"),
+				createFileCall(filepath.Join(tempDir, "tasks", "TASK-001.md"), "<task>\nScaffold\n</task>"),
+			},
+			Output: "done",
+		},
+	}})
+
+	err := DevPlan(context.Background(), DevPlanOptions{
+		ConfigPath: configPath,
+		SourceDir:  tempDir,
+		OutputDir:  tempDir,
+		Provider:   provider,
+	})
+	if err != nil {
+		t.Fatalf("devplan with mock provider failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "tasks", "TASK-001.md"))
+	if err != nil || !strings.Contains(string(data), "<task>") {
+		t.Fatalf("expected task file written, got %q (%v)", data, err)
+	}
+	if got := len(provider.Calls()); got != 2 {
+		t.Fatalf("expected 2 tool calls, got %d", got)
+	}
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/config"
+	"agentflow/internal/diagram"
+	"agentflow/internal/logging"
+	"agentflow/internal/transcript"
+)
+
+// ErrDiagramLint is returned by DiagramsLint when a diagram has problems.
+var ErrDiagramLint = errors.New("diagram lint failed")
+
+// lintDiagrams checks the diagrams in the stage's documents.
+func (r *stageRun) lintDiagrams() []diagram.Problem {
+	var problems []diagram.Problem
+	for _, out := range stageSpecs[r.stage].Outputs {
+		found, err := diagram.LintFile(filepath.Join(r.cfg.IO.OutputDir, out))
+		if err != nil {
+			continue // not written; verifyOutputs reports it
+		}
+		problems = append(problems, found...)
+	}
+	return problems
+}
+
+// repairDiagrams lints the diagrams the agent just wrote and, if any are
+// broken, runs the agent once more with the problems so it can fix them.
+// ctx is the context of the run being repaired, so the pass shares its
+// budget, deadline and transcript. Problems left after that pass are
+// logged by finish, not fatal: the documents are otherwise complete. Only a
+// repair that was stopped fails, so that finish rolls it back or reports
+// the budget. out is the output of the run so far.
+func (r *stageRun) repairDiagrams(ctx context.Context, runner agents.Runner, prompts []agents.TResponseInputItem, out string) (string, error) {
+	problems := r.lintDiagrams()
+	if len(problems) == 0 {
+		return out, nil
+	}
+	r.log.Warn("diagram lint failed; asking the agent to repair", "problems", len(problems))
+	msg := diagram.FormatRepair(problems)
+	if r.redactor.Enabled() {
+		msg = r.redactor.Redact(msg)
+	}
+	transcript.FromContext(ctx).AddPrompt(transcript.Message{Role: "user", Content: msg})
+	repaired, err := runner.RunInputs(ctx, append(slices.Clone(prompts), agents.UserMessage(msg)))
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return out, err
+	case err != nil:
+		r.log.Warn("diagram repair failed", "err", err)
+	default:
+		out = repaired
+	}
+	return out, nil
+}
+
+type DiagramsLintOptions struct {
+	ConfigPath string
+	OutputDir  string   // defaults to io.outputDir
+	Files      []string // documents to check; defaults to every .md in OutputDir
+}
+
+// DiagramsLint checks the PlantUML and Mermaid blocks in the generated
+// documents and prints each problem. It returns ErrDiagramLint if there
+// were any.
+func DiagramsLint(w io.Writer, opts DiagramsLintOptions) error {
+	files := opts.Files
+	if len(files) == 0 {
+		dir, err := diagramsDir(opts.ConfigPath, opts.OutputDir)
+		if err != nil {
+			return err
+		}
+		if files, err = inputMarkdownFiles(dir); err != nil {
+			return err
+		}
+	}
+	var problems []diagram.Problem
+	blocks := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		blocks += len(diagram.Extract(string(data)))
+		problems = append(problems, diagram.Lint(f, string(data))...)
+	}
+	for _, p := range problems {
+		fmt.Fprintln(w, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %d problem(s) in %d diagram(s)", ErrDiagramLint, len(problems), blocks)
+	}
+	fmt.Fprintf(w, "%d diagram(s) in %d document(s): no problems.\n", blocks, len(files))
+	return nil
+}
+
+type DiagramsExtractOptions struct {
+	ConfigPath string
+	OutputDir  string       // defaults to io.outputDir; diagrams go to its diagrams/ subdirectory
+	Files      []string     // documents to extract from; defaults to every .md in OutputDir
+	Logger     *slog.Logger // nil uses slog.Default()
+}
+
+// DiagramsExtract writes every PlantUML and Mermaid block of the generated
+// documents to its own .puml or .mmd file under <output>/diagrams, with an
+// index.json mapping each file back to its document and section.
+func DiagramsExtract(opts DiagramsExtractOptions) error {
+	dir, err := diagramsDir(opts.ConfigPath, opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	files := opts.Files
+	if len(files) == 0 {
+		if files, err = inputMarkdownFiles(dir); err != nil {
+			return err
+		}
+	}
+	outDir := filepath.Join(dir, "diagrams")
+	idx, err := diagram.ExtractFiles(files, outDir)
+	if err != nil {
+		return fmt.Errorf("extract diagrams: %w", err)
+	}
+	log := logging.Or(opts.Logger)
+	log.Info("extracted diagrams", "count", len(idx.Diagrams), "documents", len(files), "dir", outDir)
+	for _, e := range idx.Diagrams {
+		if len(e.Problems) > 0 {
+			log.Warn("extracted diagram fails lint", "file", e.File, "problems", strings.Join(e.Problems, "; "))
+		}
+	}
+	return nil
+}
+
+// diagramsDir returns outputDir, or the configured output directory when
+// it is empty.
+func diagramsDir(configPath, outputDir string) (string, error) {
+	if strings.TrimSpace(outputDir) != "" {
+		return outputDir, nil
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return "", fmt.Errorf("load config: %w", err)
+	}
+	cfg.ApplyEnv()
+	return cfg.IO.OutputDir, nil
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/agents/tools"
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
+)
+
+// inputsProvider is funcProvider with access to the run's input messages.
+type inputsProvider struct {
+	run func(ctx context.Context, set tools.Set, inputs []agents.TResponseInputItem) (string, error)
+}
+
+func (p inputsProvider) Name() string { return "inputs" }
+
+func (p inputsProvider) NewRunner(spec agents.AgentSpec) (agents.Runner, error) {
+	return inputsRunner{p.run, spec.Tools}, nil
+}
+
+type inputsRunner struct {
+	run   func(ctx context.Context, set tools.Set, inputs []agents.TResponseInputItem) (string, error)
+	tools tools.Set
+}
+
+func (r inputsRunner) RunInputs(ctx context.Context, inputs []agents.TResponseInputItem) (string, error) {
+	return r.run(ctx, r.tools, inputs)
+}
+
+func TestUml_RepairsBrokenDiagrams(t *testing.T) {
+	dir := t.TempDir()
+	configPath := createTestConfig(t, dir)
+	umlPath := filepath.Join(dir, "uml.md")
+	broken := validDoc("uml.md") + "\n```mermaid\nflowchart LR\n  A[Web] --> B(API)\n```\n"
+	fixed := validDoc("uml.md") + "\n```mermaid\nflowchart LR\n  A[Web] --> B[API]\n```\n"
+
+	var repairPrompt string
+	runs := 0
+	provider := inputsProvider{run: func(ctx context.Context, set tools.Set, inputs []agents.TResponseInputItem) (string, error) {
+		runs++
+		usage.FromContext(ctx).Add(100, 10)
+		content := broken
+		if runs > 1 {
+			last := inputs[len(inputs)-1]
+			repairPrompt = last.OfMessage.Content.OfString.String()
+			content = fixed
+		}
+		args, _ := json.Marshal(tools.CreateFileArgs{Path: umlPath, Content: content})
+		_, err := set.Call(ctx, "file_creator", args)
+		return "written", err
+	}}
+
+	if err := Uml(context.Background(), UmlOptions{ConfigPath: configPath, OutputDir: dir, Provider: provider}); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Fatalf("agent ran %d times, want one run and one repair pass", runs)
+	}
+	if !strings.Contains(repairPrompt, umlPath+":") || !strings.Contains(repairPrompt, "parentheses are not allowed") {
+		t.Fatalf("repair prompt:\n%s", repairPrompt)
+	}
+	if data, _ := os.ReadFile(umlPath); !strings.Contains(string(data), "B[API]") {
+		t.Fatalf("uml.md was not repaired:\n%s", data)
+	}
+	// The repair pass is part of the stage's run: one transcript holds both
+	// passes and its usage counts against the same budget.
+	list, err := transcript.List(runsPath(configPath))
+	if err != nil || len(list) != 1 {
+		t.Fatalf("want one transcript for both passes, got %d (%v)", len(list), err)
+	}
+	run := list[0]
+	if got := run.Prompt[len(run.Prompt)-1]; got.Role != "user" || got.Content != repairPrompt {
+		t.Errorf("transcript does not record the repair prompt: %+v", got)
+	}
+	if run.Usage == nil || run.Usage.InputTokens != 200 || run.Usage.OutputTokens != 20 {
+		t.Errorf("transcript usage = %+v, want both passes", run.Usage)
+	}
+
+	// Only one repair pass is made; what it leaves broken is reported by
+	// diagrams lint.
+	runs = 0
+	provider.run = func(ctx context.Context, set tools.Set, _ []agents.TResponseInputItem) (string, error) {
+		runs++
+		args, _ := json.Marshal(tools.CreateFileArgs{Path: umlPath, Content: broken})
+		_, err := set.Call(ctx, "file_creator", args)
+		return "written", err
+	}
+	if err := Uml(context.Background(), UmlOptions{ConfigPath: configPath, OutputDir: dir, Provider: provider, Force: true}); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Fatalf("agent ran %d times, want 2", runs)
+	}
+	var buf bytes.Buffer
+	err = DiagramsLint(&buf, DiagramsLintOptions{ConfigPath: configPath})
+	if !errors.Is(err, ErrDiagramLint) || !strings.Contains(buf.String(), "uml.md:") {
+		t.Fatalf("diagrams lint: %v\n%s", err, buf.String())
+	}
+	buf.Reset()
+	if err := DiagramsLint(&buf, DiagramsLintOptions{Files: []string{filepath.Join(dir, "config.yaml")}}); err != nil || !strings.Contains(buf.String(), "no problems") {
+		t.Fatalf("diagrams lint on a file without diagrams: %v\n%s", err, buf.String())
+	}
+}
+
+func TestDiagramsExtract(t *testing.T) {
+	dir := t.TempDir()
+	configPath := createTestConfig(t, dir)
+	doc := "# Architecture\n\n## Deployment\n\n```mermaid\nflowchart LR\n  LB --> App\n```\n"
+	if err := os.WriteFile(filepath.Join(dir, "architecture.md"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := DiagramsExtract(DiagramsExtractOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "diagrams", "architecture-1-deployment.mmd"))
+	if err != nil || string(data) != "flowchart LR\n  LB --> App\n" {
+		t.Fatalf("extracted file: %q, %v", data, err)
+	}
+	index, _ := os.ReadFile(filepath.Join(dir, "diagrams", "index.json"))
+	for _, want := range []string{`"source": "architecture.md"`, `"section": "Deployment"`, `"lang": "mermaid"`} {
+		if !strings.Contains(string(index), want) {
+			t.Errorf("index.json missing %s:\n%s", want, index)
+		}
+	}
+}
+
+func TestDiagramsLint_ListsDocumentsLikeStageInputs(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := DiagramsLint(&buf, DiagramsLintOptions{OutputDir: filepath.Join(dir, "missing")}); err != nil {
+		t.Fatalf("missing output directory should be empty input: %v", err)
+	}
+	doc := "# UML\n\n```mermaid\nflowchart LR\n  A[Web] --> B(API)\n```\n"
+	if err := os.WriteFile(filepath.Join(dir, "UML.MD"), []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := DiagramsLint(&buf, DiagramsLintOptions{OutputDir: dir}); !errors.Is(err, ErrDiagramLint) || !strings.Contains(buf.String(), "UML.MD:") {
+		t.Fatalf("upper-case .MD document not linted: %v\n%s", err, buf.String())
+	}
+}
+
+func TestUml_LintsPatchedDocuments(t *testing.T) {
+	dir := t.TempDir()
+	configPath := createTestConfig(t, dir)
+	umlPath := filepath.Join(dir, "uml.md")
+	partial := "# UML\n\n## Activity\n\n```mermaid\nflowchart LR\n  A[Web] --> B(API)\n```\n"
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"uml": {Calls: []agents.MockCall{createFileCall(umlPath, partial)}, Error: "model unavailable"},
+	}})
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	err := Uml(context.Background(), UmlOptions{ConfigPath: configPath, OutputDir: dir, Provider: provider, Logger: logger})
+	if err == nil || !strings.Contains(err.Error(), "model unavailable") {
+		t.Fatalf("expected agent error, got %v", err)
+	}
+	data, _ := os.ReadFile(umlPath)
+	if !strings.Contains(string(data), "```mermaid\nsequenceDiagram") || strings.Contains(string(data), "@startuml") {
+		t.Fatalf("uml.md not patched with Mermaid sections:\n%s", data)
+	}
+	if got := strings.Count(logs.String(), "diagram still broken"); got != 1 || !strings.Contains(logs.String(), "parentheses") {
+		t.Fatalf("want the agent's broken diagram, and only it, reported after patching:\n%s", logs.String())
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -20,12 +20,15 @@ type EntityOptions struct {
 	OutputDir  string // where to write entities.md
 	Role       string
 	DryRun     bool
+	Force      bool            // rebuild even if the manifest says the outputs are current
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
 }
 
 //go:embed entity_prompt.md
 var entityPromptTemplate string
 
-func Entity(opts EntityOptions) error {
+func Entity(ctx context.Context, opts EntityOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -40,6 +43,11 @@ func Entity(opts EntityOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("entity")
+	role, err := resolveRole(cfg, opts.Role, "sa")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -54,16 +62,29 @@ func Entity(opts EntityOptions) error {
 		return writeEntityScaffold(cfg.IO.OutputDir)
 	}
 
-	_, err = agents.SA.RunInputs(context.Background(), systemMessages)
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "entity", cfg, filepath.Join(opts.SourceDir, "requirements.md"), opts.SourceDir)
 	if err != nil {
-		fmt.Printf("\n\n> Note: OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
-		// Write scaffold as fallback
-		if scaffoldErr := writeEntityScaffold(cfg.IO.OutputDir); scaffoldErr != nil {
-			return fmt.Errorf("API call failed and scaffold write failed: %v (original: %v)", scaffoldErr, err)
-		}
+		return err
 	}
+	systemMessages = run.withAnswers(systemMessages)
 
-	return err
+	b, err := newBuild(opts.ConfigPath, "entity", cfg, role, systemMessages, joinAll(opts.SourceDir, stageSpecs["entity"].Inputs))
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, systemMessages)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	return b.record()
 }
 
 func buildEntitySystemMessage(sourceDir, outputDir string) ([]agents.TResponseInputItem, error) {
@@ -176,42 +197,14 @@ e1 ||--o{ e2
 `
 	}
 
-	lower := strings.ToLower(s)
-	var additions []string
-
-	// Check if Domain Entities section exists
-	if !strings.Contains(lower, "domain entities") {
-		additions = append(additions, "\n## Domain Entities\n\nThis section describes the core domain entities and their relationships.")
-	}
-
-	// Check if Data Models section exists
-	if !strings.Contains(lower, "data models") {
-		additions = append(additions, "\n## Data Models\n\nDetailed schemas and data structures.")
-	}
-
-	// Check if Relationships section exists
-	if !strings.Contains(lower, "relationships") {
-		additions = append(additions, "\n## Relationships\n\nEntity relationships and dependencies.")
-	}
-
-	// Check if Database Design section exists
-	if !strings.Contains(lower, "database design") {
-		additions = append(additions, "\n## Database Design\n\nDatabase-specific design considerations.")
-	}
-
-	if len(additions) > 0 {
-		return s + strings.Join(additions, "")
-	}
-
-	return s
+	return appendMissingSections(s, entitySections)
 }
 
 func writeEntityScaffold(outputDir string) error {
 	// Write entities.md with scaffold content
 	entityContent := ensureEntities("")
-	entityPath := filepath.Join(outputDir, "entities.md")
-	if err := os.WriteFile(entityPath, []byte(entityContent), 0644); err != nil {
-		return fmt.Errorf("write entities.md: %w", err)
+	if err := writeScaffold(filepath.Join(outputDir, "entities.md"), entityContent); err != nil {
+		return err
 	}
 
 	return nil
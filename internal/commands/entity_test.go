@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+)
+
+func TestEntity_MockProviderFailureWritesScaffold(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"entity": {Error: "model unavailable"},
+	}})
+
+	err := Entity(context.Background(), EntityOptions{
+		ConfigPath: configPath,
+		SourceDir:  tempDir,
+		OutputDir:  tempDir,
+		Provider:   provider,
+	})
+	if err == nil {
+		t.Fatal("expected scripted failure to surface")
+	}
+	data, readErr := os.ReadFile(filepath.Join(tempDir, "entities.md"))
+	if readErr != nil || !strings.Contains(string(data), "Domain Entities") {
+		t.Fatalf("expected scaffold entities.md, got %q (%v)", data, readErr)
+	}
+}
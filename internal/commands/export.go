@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+
+	"agentflow/internal/config"
+	"agentflow/internal/export"
+	"agentflow/internal/logging"
+	"agentflow/internal/tasks"
+)
+
+type ExportTasksOptions struct {
+	ConfigPath string
+	OutputDir  string // defaults to io.outputDir
+	Format     string // export.FormatGitHub (default), FormatJiraCSV or FormatLinearJSON
+	// Push sends the issues to the GitHub-compatible API configured in
+	// export.github instead of printing them. Repo and BaseURL override the
+	// config when set.
+	Push    bool
+	Repo    string
+	BaseURL string
+	Logger  *slog.Logger // nil uses slog.Default()
+}
+
+// ExportTasks writes the development plan as tracker issues to w, or with
+// Push creates and updates them through the GitHub API and prints what was
+// done with each task.
+func ExportTasks(ctx context.Context, w io.Writer, opts ExportTasksOptions) error {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	cfg.ApplyEnv()
+	outputDir := strings.TrimSpace(opts.OutputDir)
+	if outputDir == "" {
+		outputDir = cfg.IO.OutputDir
+	}
+	plan, err := tasks.Load(outputDir)
+	if err != nil {
+		return fmt.Errorf("load tasks: %w", err)
+	}
+	log := logging.Or(opts.Logger)
+	for _, p := range planProblems(plan) {
+		log.Warn("task plan problem", "task", p.ID, "problem", p.Message, "path", p.Path)
+	}
+
+	gh := cfg.Export.GitHub
+	labels := gh.Labels
+	if len(labels) == 0 {
+		labels = []string{"agentflow"}
+	}
+	issues := export.FromPlan(plan, labels)
+
+	format := opts.Format
+	if strings.TrimSpace(format) == "" {
+		format = export.FormatGitHub
+	}
+	if !opts.Push {
+		return export.Write(w, format, issues)
+	}
+	if format != export.FormatGitHub {
+		return fmt.Errorf("--push only supports the %s format", export.FormatGitHub)
+	}
+	client := &export.GitHub{BaseURL: gh.BaseURL, Repo: gh.Repo, Token: gh.GitHubToken(), Log: log}
+	if v := strings.TrimSpace(opts.Repo); v != "" {
+		client.Repo = v
+	}
+	if v := strings.TrimSpace(opts.BaseURL); v != "" {
+		client.BaseURL = v
+	}
+	if client.Token == "" {
+		log.Warn("no GitHub token set; pushing unauthenticated")
+	}
+	results, err := client.Push(ctx, issues)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tISSUE\tACTION\tURL")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t#%d\t%s\t%s\n", r.TaskID, r.Number, r.Action, r.URL)
+	}
+	if ferr := tw.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
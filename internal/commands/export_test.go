@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestExportTasks(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	writeTaskPlan(t, tempDir)
+
+	var buf bytes.Buffer
+	if err := ExportTasks(context.Background(), &buf, ExportTasksOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"title": "TASK-002: Add bookings"`, `"state": "closed"`, `- Depends on TASK-001`, `"agentflow"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("github export missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := ExportTasks(context.Background(), &buf, ExportTasksOptions{ConfigPath: configPath, Format: "jira-csv"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "Issue Id,Summary,Issue Type,Status,Description,Labels,Blocked By\n") {
+		t.Fatalf("jira csv:\n%s", buf.String())
+	}
+
+	err := ExportTasks(context.Background(), &buf, ExportTasksOptions{ConfigPath: configPath, Format: "jira-csv", Push: true})
+	if err == nil || !strings.Contains(err.Error(), "only supports") {
+		t.Fatalf("expected push to reject jira-csv, got %v", err)
+	}
+}
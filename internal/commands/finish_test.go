@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+)
+
+func TestPlan_PatchesAndStampsOutputs(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "requirements.md"), []byte("# req"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"plan": {Calls: []agents.MockCall{
+			createFileCall(filepath.Join(tempDir, "srs.md"), "# SRS\n## Use Cases\n## Interfaces"),
+			createFileCall(filepath.Join(tempDir, "stories.md"), "## EPIC-1"),
+			createFileCall(filepath.Join(tempDir, "acceptance_criteria.md"), "## STORY-1.1"),
+		}},
+	}})
+	opts := PlanOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider, Force: true}
+
+	for i := 0; i < 2; i++ {
+		if err := Plan(context.Background(), opts); err != nil {
+			t.Fatalf("plan run %d: %v", i, err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(tempDir, "stories.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "<!-- Run Metadata"); got != 1 {
+		t.Fatalf("expected exactly one metadata header, got %d:\n%s", got, data)
+	}
+	if !strings.Contains(string(data), "Model: gpt-4") {
+		t.Errorf("header should record the model:\n%s", data)
+	}
+}
+
+func TestQA_FailureWritesScaffold(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"qa": {Error: "model unavailable"},
+	}})
+
+	err := QA(context.Background(), QAOptions{ConfigPath: configPath, SourceDir: tempDir, OutputDir: tempDir, Provider: provider})
+	if err == nil || !strings.Contains(err.Error(), "model unavailable") {
+		t.Fatalf("expected agent error, got %v", err)
+	}
+	data, readErr := os.ReadFile(filepath.Join(tempDir, "test-plan.md"))
+	if readErr != nil {
+		t.Fatalf("expected scaffold test-plan.md: %v", readErr)
+	}
+	if missing := missingSections(string(data), testPlanSections); len(missing) > 0 {
+		t.Fatalf("scaffold lacks sections %v", missing)
+	}
+}
+
+func TestFinishOutputs_LeavesEmptyResultsForVerification(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"uml": {Calls: []agents.MockCall{createFileCall(filepath.Join(tempDir, "uml.md"), "  ")}},
+	}})
+
+	err := Uml(context.Background(), UmlOptions{ConfigPath: configPath, SourceDir: tempDir, OutputDir: tempDir, Provider: provider})
+	if err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Fatalf("expected empty uml.md to be reported, got %v", err)
+	}
+}
+
+func TestPlan_IncompleteSRSFailsVerification(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "requirements.md"), []byte("# req"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srs := "# SRS\n\nThe agent's own overview."
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"plan": {Calls: []agents.MockCall{
+			createFileCall(filepath.Join(tempDir, "srs.md"), srs),
+			createFileCall(filepath.Join(tempDir, "stories.md"), "## EPIC-1"),
+			createFileCall(filepath.Join(tempDir, "acceptance_criteria.md"), "## STORY-1.1"),
+		}},
+	}})
+
+	err := Plan(context.Background(), PlanOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider})
+	var outErr *OutputError
+	if !errors.As(err, &outErr) || len(outErr.Problems) != 1 || outErr.Problems[0].Problem != "missing sections" {
+		t.Fatalf("expected srs.md to fail with missing sections, got %v", err)
+	}
+	data, readErr := os.ReadFile(filepath.Join(tempDir, "srs.md"))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if !strings.Contains(string(data), "The agent's own overview.") {
+		t.Errorf("agent output was replaced:\n%s", data)
+	}
+	if strings.Contains(string(data), "## Interfaces") {
+		t.Errorf("successful run should not be patched with fallback sections:\n%s", data)
+	}
+}
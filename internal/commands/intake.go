@@ -5,13 +5,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	_ "embed"
 
 	"agentflow/internal/agents"
 	"agentflow/internal/config"
+	"agentflow/internal/timeline"
 )
 
 type IntakeOptions struct {
@@ -20,6 +23,9 @@ type IntakeOptions struct {
 	OutputDir  string
 	Role       string
 	DryRun     bool
+	Force      bool            // rebuild even if the manifest says the outputs are current
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
 }
 
 var ErrNoInputs = errors.New("no input files found")
@@ -27,7 +33,7 @@ var ErrNoInputs = errors.New("no input files found")
 //go:embed intake_prompt.md
 var intakePromptTemplate string
 
-func Intake(opts IntakeOptions) error {
+func Intake(ctx context.Context, opts IntakeOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -43,34 +49,78 @@ func Intake(opts IntakeOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("intake")
+	role, err := resolveRole(cfg, opts.Role, "po_pm")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
 
-	systemMessages, err := buildIntakeSystemMessage(cfg.IO.InputDir, cfg.IO.OutputDir)
+	tl, err := timeline.Load(cfg.IO.InputDir)
+	if err != nil {
+		return fmt.Errorf("load input timeline: %w", err)
+	}
+	systemMessages, err := buildIntakeSystemMessage(cfg.IO.InputDir, cfg.IO.OutputDir, tl)
 	if err != nil {
 		return err
 	}
 
 	if opts.DryRun {
 		return nil
-	} else {
-		_, err := agents.PO.RunInputs(context.Background(), systemMessages)
-		if err != nil {
-			fmt.Printf("\n\n> Note: OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
-		}
 	}
 
-	return nil
+	// timeline.json is derived from the inputs alone, so it is refreshed on
+	// every run, including ones the manifest later skips.
+	if err := tl.WriteJSON(timelinePath(cfg.IO.OutputDir)); err != nil {
+		return fmt.Errorf("write timeline: %w", err)
+	}
+
+	inputs, err := inputMarkdownFiles(cfg.IO.InputDir)
+	if err != nil {
+		return err
+	}
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "intake", cfg, cfg.IO.InputDir)
+	if err != nil {
+		return err
+	}
+	systemMessages = run.withAnswers(systemMessages)
+
+	b, err := newBuild(opts.ConfigPath, "intake", cfg, role, systemMessages, inputs)
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, systemMessages)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	return b.record()
+}
+
+// timelinePath is where intake writes the machine-readable input timeline,
+// next to requirements.md.
+func timelinePath(outputDir string) string {
+	return filepath.Join(outputDir, "timeline.json")
 }
 
-func buildIntakeSystemMessage(inputDir, outputDir string) ([]agents.TResponseInputItem, error) {
+func buildIntakeSystemMessage(inputDir, outputDir string, tl *timeline.Timeline) ([]agents.TResponseInputItem, error) {
 	data := struct {
 		InputPath        string
 		RequirementsPath string
+		Timeline         string
 	}{
 		InputPath:        filepath.Clean(inputDir),
 		RequirementsPath: filepath.Join(outputDir, "requirements.md"),
+		Timeline:         strings.TrimSpace(tl.Narrative()),
 	}
 	tmpl, err := template.New("intake").Parse(intakePromptTemplate)
 	if err != nil {
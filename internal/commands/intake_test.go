@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/timeline"
+)
+
+func TestIntake_WritesTimeline(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	inputDir := filepath.Join(tempDir, "input")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"2025-03-01.md": "- Database: PostgreSQL\n",
+		"2025-01-15.md": "- Database: MySQL\n- Support SSO\n",
+		"2025-02-10.md": "- Do not support SSO\n",
+	} {
+		if err := os.WriteFile(filepath.Join(inputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"intake": {Calls: []agents.MockCall{createFileCall(filepath.Join(tempDir, "requirements.md"), "# Requirements\n")}},
+	}})
+	if err := Intake(context.Background(), IntakeOptions{ConfigPath: configPath, InputsDir: inputDir, OutputDir: tempDir, Provider: provider}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "timeline.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tl timeline.Timeline
+	if err := json.Unmarshal(data, &tl); err != nil {
+		t.Fatalf("timeline.json is not valid JSON: %v", err)
+	}
+	if len(tl.Entries) != 3 || tl.Entries[0].Date != "2025-01-15" || len(tl.Changes) != 2 {
+		t.Fatalf("unexpected timeline: %s", data)
+	}
+
+	inputs, err := buildIntakeSystemMessage(inputDir, tempDir, &tl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	systemMsg := inputs[0].OfMessage.Content.OfString.String()
+	if !strings.Contains(systemMsg, "2025-01-15: "+filepath.Join(inputDir, "2025-01-15.md")) || !strings.Contains(systemMsg, `"Database: MySQL" → "Database: PostgreSQL"`) {
+		t.Fatalf("prompt is missing the timeline:\n%s", systemMsg)
+	}
+}
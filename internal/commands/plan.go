@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
@@ -23,6 +24,9 @@ type PlanOptions struct {
 	OutputDir    string
 	Role         string
 	DryRun       bool
+	Force        bool            // rebuild even if the manifest says the outputs are current
+	Provider     agents.Provider // nil selects the provider configured in llm.provider
+	Logger       *slog.Logger    // nil uses slog.Default()
 }
 
 var ErrNoRequirements = errors.New("requirements.md not found")
@@ -30,7 +34,7 @@ var ErrNoRequirements = errors.New("requirements.md not found")
 //go:embed plan_prompt.md
 var planPromptTemplate string
 
-func Plan(opts PlanOptions) error {
+func Plan(ctx context.Context, opts PlanOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -45,6 +49,11 @@ func Plan(opts PlanOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("plan")
+	role, err := resolveRole(cfg, opts.Role, "sa")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -64,12 +73,29 @@ func Plan(opts PlanOptions) error {
 		return nil
 	}
 
-	_, err = agents.SA.RunInputs(context.Background(), prompts)
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "plan", cfg, opts.Requirements, opts.Requirements)
 	if err != nil {
-		fmt.Printf("OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
+		return err
 	}
+	prompts = run.withAnswers(prompts)
 
-	return err
+	b, err := newBuild(opts.ConfigPath, "plan", cfg, role, prompts, []string{opts.Requirements})
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, prompts)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	return b.record()
 }
 
 func buildPlanSystemMessage(requirementsPath, outputDir string) ([]agents.TResponseInputItem, error) {
@@ -124,6 +150,20 @@ func writeFileWithHeader(cfg *config.Config, sourcePath, outPath, body string) e
 	return os.WriteFile(outPath, []byte(content), 0o644)
 }
 
+// stripRunMetadata removes a run-metadata block left by an earlier
+// writeFileWithHeader so re-stamping a document does not stack headers.
+func stripRunMetadata(s string) string {
+	start := strings.Index(s, "<!-- Run Metadata")
+	if start == -1 {
+		return s
+	}
+	end := strings.Index(s[start:], "-->")
+	if end == -1 {
+		return s
+	}
+	return strings.TrimSpace(s[:start] + s[start+end+len("-->"):])
+}
+
 func splitPlanContent(s string) (string, string, string) {
 	// naive splitting by markers; if missing, treat whole as SRS
 	low := strings.ToLower(s)
@@ -170,18 +210,7 @@ func ensureSRS(s string) string {
 	if strings.TrimSpace(s) == "" {
 		return "## 1. บทนำ\n- ...\n\n## 3. Use Cases\n- UC-01 ...\n\n## Interfaces\n- ...\n\n## Constraints\n- ..."
 	}
-	low := strings.ToLower(s)
-	need := []string{"use cases", "interfaces", "constraints"}
-	missing := 0
-	for _, n := range need {
-		if !strings.Contains(low, n) {
-			missing++
-		}
-	}
-	if missing >= 2 { // likely not SRS shaped
-		return "## 1. บทนำ\n- ...\n\n## 3. Use Cases\n- UC-01 ...\n\n## Interfaces\n- ...\n\n## Constraints\n- ..."
-	}
-	return s
+	return appendMissingSections(s, srsSections)
 }
 
 func ensureStories(s string) string {
@@ -1,13 +1,18 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"agentflow/internal/agents"
+	"agentflow/internal/agents/tools"
 	"agentflow/internal/config"
+	"agentflow/internal/questions"
 )
 
 func TestPlan_NoRequirements(t *testing.T) {
@@ -37,7 +42,7 @@ func TestPlan_NoRequirements(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err := Plan(opts)
+	err := Plan(context.Background(), opts)
 	if !errors.Is(err, ErrNoRequirements) {
 		t.Errorf("expected ErrNoRequirements, got %v", err)
 	}
@@ -78,12 +83,103 @@ func TestPlan_DryRun(t *testing.T) {
 		DryRun:       true,
 	}
 
-	err := Plan(opts)
+	err := Plan(context.Background(), opts)
 	if err != nil {
 		t.Errorf("dry run should not fail, got %v", err)
 	}
 }
 
+func TestPlan_MockProvider(t *testing.T) {
+	tempDir := t.TempDir()
+	fixtures := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "requirements.md"), []byte("# req"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeFixture(t, fixtures, "plan", agents.MockScript{
+		Calls: []agents.MockCall{
+			createFileCall(filepath.Join(tempDir, "srs.md"), "## Use Cases\n## Interfaces\n## Constraints"),
+			createFileCall(filepath.Join(tempDir, "stories.md"), "## EPIC-1"),
+			createFileCall(filepath.Join(tempDir, "acceptance_criteria.md"), "## STORY-1.1"),
+		},
+		Output: "done",
+	})
+	t.Setenv("AGENTFLOW_PROVIDER", "mock")
+	t.Setenv("AGENTFLOW_FIXTURES", fixtures)
+
+	if err := Plan(context.Background(), PlanOptions{ConfigPath: configPath, OutputDir: tempDir}); err != nil {
+		t.Fatalf("plan with mock provider failed: %v", err)
+	}
+	for _, name := range []string{"srs.md", "stories.md", "acceptance_criteria.md"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+			t.Errorf("expected %s written: %v", name, err)
+		}
+	}
+}
+
+func TestPlan_SkipsWhenUpToDate(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	reqFile := filepath.Join(tempDir, "requirements.md")
+	if err := os.WriteFile(reqFile, []byte("# req v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"plan": {Calls: []agents.MockCall{
+			createFileCall(filepath.Join(tempDir, "srs.md"), validDoc("srs.md")),
+			createFileCall(filepath.Join(tempDir, "stories.md"), "stories"),
+			createFileCall(filepath.Join(tempDir, "acceptance_criteria.md"), "ac"),
+		}},
+	}})
+	opts := PlanOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider}
+
+	for i := 0; i < 2; i++ {
+		if err := Plan(context.Background(), opts); err != nil {
+			t.Fatalf("plan run %d: %v", i, err)
+		}
+	}
+	if got := len(provider.Calls()); got != 3 {
+		t.Fatalf("second run should be skipped, got %d tool calls", got)
+	}
+
+	opts.Force = true
+	if err := Plan(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(provider.Calls()); got != 6 {
+		t.Fatalf("--force should rebuild, got %d tool calls", got)
+	}
+
+	opts.Force = false
+	if err := os.WriteFile(reqFile, []byte("# req v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Plan(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(provider.Calls()); got != 9 {
+		t.Fatalf("changed requirements should rebuild, got %d tool calls", got)
+	}
+}
+
+func TestPlan_UnknownRole(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "requirements.md"), []byte("# req"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Plan(context.Background(), PlanOptions{
+		ConfigPath: configPath,
+		OutputDir:  tempDir,
+		Role:       "pirate",
+		DryRun:     true,
+	})
+	if !errors.Is(err, agents.ErrUnknownRole) {
+		t.Errorf("expected ErrUnknownRole, got %v", err)
+	}
+}
+
 func TestPlan_DefaultRequirementsPath(t *testing.T) {
 	tempDir := t.TempDir()
 	reqFile := filepath.Join(tempDir, "requirements.md")
@@ -119,7 +215,7 @@ func TestPlan_DefaultRequirementsPath(t *testing.T) {
 		DryRun:       true,
 	}
 
-	err := Plan(opts)
+	err := Plan(context.Background(), opts)
 	if err != nil {
 		t.Errorf("should use default requirements path, got %v", err)
 	}
@@ -249,9 +345,11 @@ func TestEnsureSRS_MissingSections(t *testing.T) {
 	content := "Just an overview without proper sections"
 	result := ensureSRS(content)
 
-	// Should return template because it's missing most required sections
-	if !strings.Contains(result, "Use Cases") {
-		t.Error("ensureSRS should add template when sections are missing")
+	if !strings.HasPrefix(result, content) {
+		t.Errorf("ensureSRS must keep what the agent wrote, got %q", result)
+	}
+	if missing := missingSections(result, srsSections); len(missing) > 0 {
+		t.Errorf("ensureSRS should append missing sections %v", missing)
 	}
 }
 
@@ -383,3 +481,46 @@ func TestWriteFileWithHeader_AgentflowTitle(t *testing.T) {
 		t.Error("output should contain metadata even with AgentFlow title")
 	}
 }
+
+func TestPlan_PausesForQuestionsInFileMode(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "requirements.md"), []byte("# req"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AGENTFLOW_ASK_HUMAN", config.AskHumanFile)
+	ask, _ := json.Marshal(tools.AskHumanArgs{Question: "Which regions are in scope?"})
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"plan": {Calls: []agents.MockCall{
+			{Tool: "ask_human", Args: ask},
+			createFileCall(filepath.Join(tempDir, "srs.md"), validDoc("srs.md")),
+			createFileCall(filepath.Join(tempDir, "stories.md"), "## EPIC-1"),
+			createFileCall(filepath.Join(tempDir, "acceptance_criteria.md"), "## STORY-1.1"),
+		}},
+	}})
+	opts := PlanOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider}
+
+	if err := Plan(context.Background(), opts); !errors.Is(err, tools.ErrAwaitingAnswers) {
+		t.Fatalf("expected stage to pause, got %v", err)
+	}
+	if err := Plan(context.Background(), opts); !errors.Is(err, tools.ErrAwaitingAnswers) {
+		t.Fatalf("expected stage to stay paused, got %v", err)
+	}
+	if got := len(provider.Calls()); got != 4 {
+		t.Fatalf("paused stage should not run the agent again, got %d calls", got)
+	}
+
+	store, err := questions.Load(questionsPath(configPath))
+	if err != nil || len(store.Questions) != 1 {
+		t.Fatalf("expected one recorded question, got %+v (%v)", store, err)
+	}
+	if err := Answer(AnswerOptions{ConfigPath: configPath, ID: store.Questions[0].ID, Answer: "Thailand only"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Plan(context.Background(), opts); err != nil {
+		t.Fatalf("answered stage should run: %v", err)
+	}
+	if res := provider.Calls()[4].Result; !strings.Contains(res, "Thailand only") {
+		t.Fatalf("expected the stored answer to be returned, got %q", res)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -22,10 +23,13 @@ type QAOptions struct {
 	OutputDir  string // where to write test-plan.md
 	Role       string
 	DryRun     bool
+	Force      bool            // rebuild even if the manifest says the outputs are current
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
 }
 
 // QA generates a test-plan.md using SRS/Stories/Acceptance Criteria as context.
-func QA(opts QAOptions) error {
+func QA(ctx context.Context, opts QAOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -37,6 +41,11 @@ func QA(opts QAOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("qa")
+	role, err := resolveRole(cfg, opts.Role, "qa")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -53,14 +62,31 @@ func QA(opts QAOptions) error {
 
 	if opts.DryRun {
 		return nil
-	} else {
-		_, err := agents.LQ.RunInputs(context.Background(), prompts)
-		if err != nil {
-			fmt.Printf("OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
-		}
 	}
 
-	return nil
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "qa", cfg, filepath.Join(sourceDir, "requirements.md"), sourceDir)
+	if err != nil {
+		return err
+	}
+	prompts = run.withAnswers(prompts)
+
+	b, err := newBuild(opts.ConfigPath, "qa", cfg, role, prompts, joinAll(sourceDir, stageSpecs["qa"].Inputs))
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, prompts)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	return b.record()
 }
 
 func buildQASystemMessage(sourceDir, outputDir string) ([]agents.TResponseInputItem, error) {
@@ -91,3 +117,11 @@ func buildQASystemMessage(sourceDir, outputDir string) ([]agents.TResponseInputI
 		agents.SystemMessage(buf.String()),
 	), nil
 }
+
+func ensureTestPlan(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		s = "# AgentFlow — Test Plan"
+	}
+	return appendMissingSections(s, testPlanSections)
+}
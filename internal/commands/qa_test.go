@@ -1,10 +1,14 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"agentflow/internal/agents"
 )
 
 // Test helper to create a minimal valid config file
@@ -29,13 +33,31 @@ func createTestConfig(t *testing.T, tempDir string) string {
 	return configPath
 }
 
+// writeFixture stores a mock provider script for stage under dir.
+func writeFixture(t *testing.T, dir, stage string, script agents.MockScript) {
+	t.Helper()
+	data, err := json.Marshal(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, stage+".json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createFileCall builds a scripted file_creator call.
+func createFileCall(path, content string) agents.MockCall {
+	args, _ := json.Marshal(map[string]string{"Path": path, "Content": content})
+	return agents.MockCall{Tool: "file_creator", Args: args}
+}
+
 func TestQA_ConfigLoadError(t *testing.T) {
 	opts := QAOptions{
 		ConfigPath: "/nonexistent/config.yaml",
 		DryRun:     true,
 	}
 
-	err := QA(opts)
+	err := QA(context.Background(), opts)
 	if err == nil {
 		t.Error("expected error when config file doesn't exist")
 	}
@@ -55,7 +77,7 @@ func TestQA_DryRun(t *testing.T) {
 		DryRun:     true,
 	}
 
-	err := QA(opts)
+	err := QA(context.Background(), opts)
 	if err != nil {
 		t.Errorf("dry run should not fail, got %v", err)
 	}
@@ -72,7 +94,7 @@ func TestQA_DefaultSourceDir_DryRun(t *testing.T) {
 		DryRun:     true,
 	}
 
-	err := QA(opts)
+	err := QA(context.Background(), opts)
 	if err != nil {
 		t.Errorf("should use default source dir, got %v", err)
 	}
@@ -94,7 +116,7 @@ func TestQA_OutputDirOverride_DryRun(t *testing.T) {
 		DryRun:     true,
 	}
 
-	err := QA(opts)
+	err := QA(context.Background(), opts)
 	if err != nil {
 		t.Errorf("should override output dir, got %v", err)
 	}
@@ -111,7 +133,7 @@ func TestQA_SourceDirTrimming_DryRun(t *testing.T) {
 		DryRun:     true,
 	}
 
-	err := QA(opts)
+	err := QA(context.Background(), opts)
 	if err != nil {
 		t.Errorf("should trim source dir whitespace, got %v", err)
 	}
@@ -283,7 +305,7 @@ func TestQA_TableDriven_DryRun(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			opts := tt.setupFunc(t)
 
-			err := QA(opts)
+			err := QA(context.Background(), opts)
 
 			if tt.expectError && err == nil {
 				t.Errorf("expected error but got none")
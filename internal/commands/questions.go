@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"agentflow/internal/questions"
+)
+
+type QuestionsListOptions struct {
+	ConfigPath string
+	Stage      string // only questions raised by this stage
+	All        bool   // include answered questions
+}
+
+// QuestionsList prints the tracked questions, open ones only unless All is
+// set.
+func QuestionsList(w io.Writer, opts QuestionsListOptions) error {
+	store, err := questions.Load(questionsPath(opts.ConfigPath))
+	if err != nil {
+		return fmt.Errorf("load questions: %w", err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTAGE\tSTATUS\tKIND\tTEXT")
+	n := 0
+	for _, q := range store.Questions {
+		if opts.Stage != "" && q.Stage != opts.Stage {
+			continue
+		}
+		if !opts.All && q.Answered() {
+			continue
+		}
+		text := q.Text
+		if q.Answered() {
+			text += " → " + q.Answer
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", q.ID, q.Stage, q.Status, q.Kind, strings.ReplaceAll(text, "\n", " "))
+		n++
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "No questions.")
+	}
+	return nil
+}
+
+type AnswerOptions struct {
+	ConfigPath string
+	ID         string // e.g. "Q-12"
+	Answer     string
+}
+
+// Answer records an answer. The next run of the stage that raised the
+// question receives it in its prompt.
+func Answer(opts AnswerOptions) error {
+	return questions.Update(questionsPath(opts.ConfigPath), func(s *questions.Store) error {
+		return s.Answer(opts.ID, opts.Answer)
+	})
+}
@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+)
+
+func TestIntake_TracksQuestionsAndReRunsOnAnswer(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	inputDir := filepath.Join(tempDir, "input")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "notes.md"), []byte("# notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	requirements := "# Requirements\n\n- **Questions to Human (Stakeholders)**\n  - Who owns consent data?\n"
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"intake": {Calls: []agents.MockCall{createFileCall(filepath.Join(tempDir, "requirements.md"), requirements)}},
+	}})
+	opts := IntakeOptions{ConfigPath: configPath, InputsDir: inputDir, OutputDir: tempDir, Provider: provider}
+
+	if err := Intake(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := QuestionsList(&out, QuestionsListOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Q-1") || !strings.Contains(out.String(), "Who owns consent data?") {
+		t.Fatalf("question not tracked:\n%s", out.String())
+	}
+
+	// Re-running without new answers is a no-op and does not duplicate
+	// the question.
+	if err := Intake(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(provider.Calls()); got != 1 {
+		t.Fatalf("expected the second run to be skipped, got %d calls", got)
+	}
+
+	if err := Answer(AnswerOptions{ConfigPath: configPath, ID: "Q-1", Answer: "The data platform team"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Intake(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(provider.Calls()); got != 2 {
+		t.Fatalf("an answer should trigger a rebuild, got %d calls", got)
+	}
+	out.Reset()
+	if err := QuestionsList(&out, QuestionsListOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No questions.") {
+		t.Fatalf("answered question should not be listed as open:\n%s", out.String())
+	}
+}
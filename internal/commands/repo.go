@@ -5,7 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -20,12 +20,15 @@ type RepoOptions struct {
 	OutputDir  string // where to write repository.md
 	Role       string
 	DryRun     bool
+	Force      bool            // rebuild even if the manifest says the outputs are current
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
 }
 
 //go:embed repo_prompt.md
 var repoPromptTemplate string
 
-func Repo(opts RepoOptions) error {
+func Repo(ctx context.Context, opts RepoOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -40,6 +43,11 @@ func Repo(opts RepoOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("repo")
+	role, err := resolveRole(cfg, opts.Role, "sa")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -54,16 +62,29 @@ func Repo(opts RepoOptions) error {
 		return writeRepoScaffold(cfg.IO.OutputDir)
 	}
 
-	_, err = agents.SA.RunInputs(context.Background(), systemMessages)
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "repo", cfg, filepath.Join(opts.SourceDir, "requirements.md"), opts.SourceDir)
 	if err != nil {
-		fmt.Printf("\n\n> Note: OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
-		// Write scaffold as fallback
-		if scaffoldErr := writeRepoScaffold(cfg.IO.OutputDir); scaffoldErr != nil {
-			return fmt.Errorf("API call failed and scaffold write failed: %v (original: %v)", scaffoldErr, err)
-		}
+		return err
+	}
+	systemMessages = run.withAnswers(systemMessages)
+
+	b, err := newBuild(opts.ConfigPath, "repo", cfg, role, systemMessages, joinAll(opts.SourceDir, stageSpecs["repo"].Inputs))
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
 	}
 
-	return err
+	runner, err := newRunner(opts.Provider, cfg, role, run)
+	if err != nil {
+		return err
+	}
+	err = run.execute(ctx, runner, systemMessages)
+	if err := run.finish(err); err != nil {
+		return err
+	}
+	return b.record()
 }
 
 func buildRepoSystemMessage(sourceDir, outputDir string) ([]agents.TResponseInputItem, error) {
@@ -354,42 +375,14 @@ type SchemaManager interface {
 This repository design provides a solid foundation for data access layer implementation in Go applications.`
 	}
 
-	lower := strings.ToLower(s)
-	var additions []string
-
-	// Check if Repository Pattern Overview section exists
-	if !strings.Contains(lower, "repository pattern overview") {
-		additions = append(additions, "\n## Repository Pattern Overview\n\nThe Repository pattern encapsulates data access logic.")
-	}
-
-	// Check if Repository Interfaces section exists
-	if !strings.Contains(lower, "repository interfaces") {
-		additions = append(additions, "\n## Repository Interfaces\n\nInterface definitions for data access operations.")
-	}
-
-	// Check if Implementation Guidelines section exists
-	if !strings.Contains(lower, "implementation guidelines") {
-		additions = append(additions, "\n## Implementation Guidelines\n\nBest practices for repository implementation.")
-	}
-
-	// Check if Testing Strategies section exists
-	if !strings.Contains(lower, "testing strategies") {
-		additions = append(additions, "\n## Testing Strategies\n\nApproaches for testing repository implementations.")
-	}
-
-	if len(additions) > 0 {
-		return s + strings.Join(additions, "")
-	}
-
-	return s
+	return appendMissingSections(s, repositorySections)
 }
 
 func writeRepoScaffold(outputDir string) error {
 	// Write repository.md with scaffold content
 	repoContent := ensureRepository("")
-	repoPath := filepath.Join(outputDir, "repository.md")
-	if err := os.WriteFile(repoPath, []byte(repoContent), 0644); err != nil {
-		return fmt.Errorf("write repository.md: %w", err)
+	if err := writeScaffold(filepath.Join(outputDir, "repository.md"), repoContent); err != nil {
+		return err
 	}
 
 	return nil
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/config"
+	"agentflow/internal/logging"
+	"agentflow/internal/pipeline"
+)
+
+// RunOptions configures `agentflow run`. From/To/Only select a slice of the
+// pipeline; see pipeline.Selection.
+type RunOptions struct {
+	ConfigPath string
+	InputsDir  string
+	OutputDir  string
+	From       string
+	To         string
+	Only       []string
+	DryRun     bool
+	Force      bool            // rebuild stages even when they are up to date
+	Jobs       int             // stages run at once; below 1 means one at a time
+	FailFast   bool            // cancel running stages when one fails
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
+}
+
+// Stages returns the AgentFlow pipeline. Inputs and outputs come from
+// stageSpecs and determine the order stages run in. opts.DryRun is not
+// passed on: a dry run of the pipeline runs no stage at all. Each stage also logs to
+// its own file under .agentflow/logs, so the log of one stage can be read
+// apart from those running alongside it.
+func Stages(opts RunOptions) []pipeline.Stage {
+	stage := func(name string, run func(ctx context.Context, log *slog.Logger) error) pipeline.Stage {
+		spec := stageSpecs[name]
+		return pipeline.Stage{Name: name, Inputs: spec.Inputs, Outputs: spec.Outputs, Run: func(ctx context.Context) error {
+			log, closeLog := stageLogger(opts.Logger, opts.ConfigPath, name)
+			defer closeLog()
+			return run(ctx, log)
+		}}
+	}
+	return []pipeline.Stage{
+		stage("intake", func(ctx context.Context, log *slog.Logger) error {
+			return Intake(ctx, IntakeOptions{ConfigPath: opts.ConfigPath, InputsDir: opts.InputsDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("plan", func(ctx context.Context, log *slog.Logger) error {
+			return Plan(ctx, PlanOptions{ConfigPath: opts.ConfigPath, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("design", func(ctx context.Context, log *slog.Logger) error {
+			return Design(ctx, DesignOptions{ConfigPath: opts.ConfigPath, SourceDir: opts.OutputDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("uml", func(ctx context.Context, log *slog.Logger) error {
+			return Uml(ctx, UmlOptions{ConfigPath: opts.ConfigPath, SourceDir: opts.OutputDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("qa", func(ctx context.Context, log *slog.Logger) error {
+			return QA(ctx, QAOptions{ConfigPath: opts.ConfigPath, SourceDir: opts.OutputDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("entity", func(ctx context.Context, log *slog.Logger) error {
+			return Entity(ctx, EntityOptions{ConfigPath: opts.ConfigPath, SourceDir: opts.OutputDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("repo", func(ctx context.Context, log *slog.Logger) error {
+			return Repo(ctx, RepoOptions{ConfigPath: opts.ConfigPath, SourceDir: opts.OutputDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+		stage("devplan", func(ctx context.Context, log *slog.Logger) error {
+			return DevPlan(ctx, DevPlanOptions{ConfigPath: opts.ConfigPath, SourceDir: opts.OutputDir, OutputDir: opts.OutputDir, Force: opts.Force, Provider: opts.Provider, Logger: log})
+		}),
+	}
+}
+
+// Run executes the selected pipeline stages in dependency order, up to
+// opts.Jobs at a time, and starts no new stage once one fails. The report
+// is returned even when a stage fails. A dry run only reports the stages
+// that would run and what each waits for; nothing is written.
+func Run(ctx context.Context, opts RunOptions) (*pipeline.Report, error) {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	cfg.ApplyEnv()
+	if strings.TrimSpace(opts.OutputDir) == "" {
+		opts.OutputDir = cfg.IO.OutputDir
+	}
+	if opts.Provider == nil && !opts.DryRun {
+		// Share one provider across stages so they see the same backend.
+		if opts.Provider, err = agents.NewProvider(cfg.LLM); err != nil {
+			return nil, err
+		}
+	}
+
+	g, err := pipeline.New(Stages(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	names, err := g.Select(pipeline.Selection{From: opts.From, To: opts.To, Only: opts.Only})
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return g.Plan(names), nil
+	}
+	return g.Execute(ctx, names, pipeline.ExecuteOptions{
+		Dir:         opts.OutputDir,
+		CheckInputs: true,
+		Jobs:        opts.Jobs,
+		FailFast:    opts.FailFast,
+		Log:         opts.Logger,
+	})
+}
+
+// stageLogger returns a logger that writes to log and, truncated at the
+// start of every run, to .agentflow/logs/<stage>.log at the same level. If
+// the file cannot be opened, log alone is used.
+func stageLogger(log *slog.Logger, configPath, stage string) (*slog.Logger, func()) {
+	log = logging.Or(log)
+	path := filepath.Join(logsPath(configPath), stage+".log")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Warn("could not create stage log", "stage", stage, "err", err)
+		return log, func() {}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		log.Warn("could not create stage log", "stage", stage, "err", err)
+		return log, func() {}
+	}
+	file := slog.NewTextHandler(f, &slog.HandlerOptions{Level: logging.Level(log)})
+	return slog.New(logging.Tee(log.Handler(), file)), func() { f.Close() }
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/pipeline"
+)
+
+// validDoc returns a document for out that passes verifyOutputs.
+func validDoc(out string) string {
+	doc := "# " + out + "\n"
+	for _, sec := range artifactChecks[out].Sections {
+		doc += sec.Fallback + "\n"
+	}
+	return doc
+}
+
+// pipelineScripts returns mock scripts that make every stage write its
+// declared outputs under dir.
+func pipelineScripts(dir string) map[string]agents.MockScript {
+	scripts := map[string]agents.MockScript{}
+	for _, s := range Stages(RunOptions{}) {
+		var calls []agents.MockCall
+		for _, out := range s.Outputs {
+			if out == "tasks" {
+				out = filepath.Join("tasks", "TASK-001.md")
+			}
+			calls = append(calls, createFileCall(filepath.Join(dir, out), validDoc(out)))
+		}
+		scripts[s.Name] = agents.MockScript{Calls: calls, Output: "done"}
+	}
+	return scripts
+}
+
+func TestRun_FullPipelineWithMock(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: pipelineScripts(tempDir)})
+
+	report, err := Run(context.Background(), RunOptions{ConfigPath: configPath, InputsDir: tempDir, OutputDir: tempDir, Provider: provider})
+	if err != nil {
+		t.Fatalf("run failed: %v\n%s", err, report)
+	}
+	if len(report.Results) != 8 {
+		t.Fatalf("expected 8 stages, got %d", len(report.Results))
+	}
+	if last := report.Results[len(report.Results)-1]; last.Stage != "devplan" || last.Status != pipeline.StatusOK {
+		t.Fatalf("expected devplan to run last, got %+v", last)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "tasks", "TASK-001.md")); err != nil {
+		t.Fatalf("expected devplan output: %v", err)
+	}
+}
+
+func TestRun_DryRunWritesNothing(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	arch := filepath.Join(tempDir, "architecture.md")
+	if err := os.WriteFile(arch, []byte("# Real architecture\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), RunOptions{ConfigPath: configPath, InputsDir: tempDir, OutputDir: tempDir, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if data, _ := os.ReadFile(arch); string(data) != "# Real architecture\n" {
+		t.Fatalf("dry run changed architecture.md:\n%s", data)
+	}
+	for _, out := range []string{"uml.md", "entities.md", "repository.md", "srs.md"} {
+		if _, err := os.Stat(filepath.Join(tempDir, out)); err == nil {
+			t.Errorf("dry run wrote %s", out)
+		}
+	}
+	if len(report.Results) != 8 || report.Results[0].Status != pipeline.StatusPlanned {
+		t.Fatalf("expected 8 planned stages, got\n%s", report)
+	}
+	if last := report.Results[7]; last.Stage != "devplan" || !strings.Contains(strings.Join(last.After, ","), "design") {
+		t.Errorf("plan should show what devplan waits for:\n%s", report)
+	}
+}
+
+func TestDesign_DryRunKeepsExistingDocuments(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	arch := filepath.Join(tempDir, "architecture.md")
+	if err := os.WriteFile(arch, []byte("# Real architecture\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Design(context.Background(), DesignOptions{ConfigPath: configPath, SourceDir: tempDir, OutputDir: tempDir, DryRun: true}); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if data, _ := os.ReadFile(arch); string(data) != "# Real architecture\n" {
+		t.Fatalf("dry run overwrote architecture.md:\n%s", data)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "uml.md")); err != nil {
+		t.Fatalf("dry run should scaffold the missing uml.md: %v", err)
+	}
+}
+
+func TestRun_StopsWhenOutputMissing(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "requirements.md"), []byte("# req"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scripts := pipelineScripts(tempDir)
+	// entity "succeeds" without writing entities.md; verification catches
+	// it before repo starts.
+	scripts["entity"] = agents.MockScript{Output: "done"}
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: scripts})
+
+	report, err := Run(context.Background(), RunOptions{ConfigPath: configPath, OutputDir: tempDir, From: "plan", To: "repo", Provider: provider})
+	if !errors.Is(err, ErrInvalidOutputs) {
+		t.Fatalf("expected ErrInvalidOutputs, got %v", err)
+	}
+	failed := report.Failed()
+	if failed == nil || failed.Stage != "entity" {
+		t.Fatalf("expected entity to fail, got %+v\n%s", failed, report)
+	}
+	if last := report.Results[len(report.Results)-1]; last.Stage != "repo" || last.Status != pipeline.StatusPending {
+		t.Fatalf("repo should not run, got %+v", last)
+	}
+}
+
+func TestRun_ParallelJobsWithStageLogs(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: pipelineScripts(tempDir)})
+
+	report, err := Run(context.Background(), RunOptions{ConfigPath: configPath, InputsDir: tempDir, OutputDir: tempDir, Jobs: 4, Provider: provider})
+	if err != nil {
+		t.Fatalf("run failed: %v\n%s", err, report)
+	}
+	for _, r := range report.Results {
+		if r.Status != pipeline.StatusOK {
+			t.Fatalf("stage %s: %s", r.Stage, r.Status)
+		}
+		data, err := os.ReadFile(filepath.Join(logsPath(configPath), r.Stage+".log"))
+		if err != nil {
+			t.Fatalf("stage log: %v", err)
+		}
+		if !strings.Contains(string(data), "stage="+r.Stage) || strings.Contains(string(data), "stage=intake") != (r.Stage == "intake") {
+			t.Errorf("%s.log should hold only that stage's lines:\n%s", r.Stage, data)
+		}
+	}
+}
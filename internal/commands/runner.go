@@ -0,0 +1,357 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/agents/tools"
+	"agentflow/internal/config"
+	"agentflow/internal/logging"
+	"agentflow/internal/questions"
+	"agentflow/internal/redact"
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
+)
+
+// stdout receives what stage commands print for the user rather than log:
+// ask_human questions and the usage summary of each agent run.
+var stdout io.Writer = os.Stdout
+
+// resolveRole looks up the --role value (or fallback when it is empty) in the
+// role registry built from cfg.Roles.
+func resolveRole(cfg *config.Config, name, fallback string) (agents.Role, error) {
+	if strings.TrimSpace(name) == "" {
+		name = fallback
+	}
+	return agents.NewRoleRegistry(cfg.Roles).Resolve(name)
+}
+
+// newWorkspace confines the agent's file tools to the configured input and
+// output directories. Writes are further limited to the outputs stageSpecs
+// declares for stage. extraReads are further readable paths, such as a
+// --source directory outside the output directory. The config file and the
+// build manifest are never writable.
+func newWorkspace(configPath, stage string, cfg *config.Config, extraReads ...string) *tools.Workspace {
+	return &tools.Workspace{
+		ReadRoots:    append([]string{cfg.IO.InputDir, cfg.IO.OutputDir}, extraReads...),
+		WriteRoots:   []string{cfg.IO.OutputDir},
+		Protected:    []string{configPath, manifestPath(configPath), questionsPath(configPath), questions.MarkdownPath(questionsPath(configPath)), redactionsPath(configPath)},
+		Allow:        joinAll(cfg.IO.OutputDir, stageSpecs[stage].Outputs),
+		MaxFileBytes: cfg.Security.MaxFileBytes,
+	}
+}
+
+// reportRefused logs the writes the agent attempted but the workspace
+// turned down, so unexpected behaviour does not go unnoticed.
+func reportRefused(log *slog.Logger, ws *tools.Workspace) {
+	for _, r := range ws.Refused() {
+		log.Warn("agent write refused", "path", r.Path, "reason", r.Reason)
+	}
+}
+
+// newRunner returns a Runner for the stage of run and the given role, using
+// the model settings in cfg.LLM and the run's tools and logger. The
+// injected provider is used when set; otherwise one is built from cfg.LLM.
+func newRunner(p agents.Provider, cfg *config.Config, role agents.Role, run *stageRun) (agents.Runner, error) {
+	if p == nil {
+		var err error
+		p, err = agents.NewProvider(cfg.LLM)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p.NewRunner(agents.AgentSpec{
+		Stage:        run.stage,
+		Name:         role.Name,
+		Instructions: role.Instructions,
+		Model:        cfg.LLM.Model,
+		Temperature:  cfg.LLM.Temperature,
+		MaxTokens:    cfg.LLM.MaxTokens,
+		Tools:        run.tools(),
+		Logger:       run.log,
+	})
+}
+
+// questionsPath returns the location of the questions tracker, next to the
+// config file.
+func questionsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "questions.json")
+}
+
+// runsPath returns the directory holding the per-run transcripts, next to
+// the config file.
+func runsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "runs")
+}
+
+// logsPath returns the directory holding the per-stage logs written by
+// `agentflow run`, next to the config file.
+func logsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "logs")
+}
+
+// redactionsPath returns the location of the redaction vault, which maps
+// placeholders back to the values they replace.
+func redactionsPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "redactions.json")
+}
+
+// newRedactor returns the redactor configured by cfg.Redact.
+func newRedactor(configPath string, cfg *config.Config) (*redact.Redactor, error) {
+	var values []string
+	for _, k := range cfg.SecretEnvKeys() {
+		if v := os.Getenv(k); v != "" {
+			values = append(values, v)
+		}
+	}
+	return redact.Open(redactionsPath(configPath), redact.Options{
+		Secrets:   cfg.Redact.Secrets,
+		PII:       cfg.Redact.PII,
+		EnvValues: values,
+	})
+}
+
+// stageRun holds the tool instances of a single command run: the sandboxed
+// file tools and ask_human. Each run gets its own so that refused writes and
+// pending questions are attributed to the right stage.
+type stageRun struct {
+	stage    string
+	cfg      *config.Config
+	source   string // recorded as SourceRequirements in output headers
+	ws       *tools.Workspace
+	ask      *tools.Asker
+	answered []questions.Question
+	runsDir  string
+	redactor *redact.Redactor
+	log      *slog.Logger
+	// interrupted is set when the run was cancelled or timed out, in which
+	// case finish rolls back the agent's writes instead of scaffolding.
+	interrupted bool
+}
+
+// newStageRun prepares the tools for stage. In file mode a stage whose
+// earlier ask_human questions are still unanswered stays paused. A nil
+// logger uses slog.Default().
+func newStageRun(log *slog.Logger, configPath, stage string, cfg *config.Config, source string, extraReads ...string) (*stageRun, error) {
+	path := questionsPath(configPath)
+	store, err := questions.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("load questions: %w", err)
+	}
+	answered, open := store.ForStage(stage)
+	if blocking := countBlocking(open); blocking > 0 && cfg.AskHuman.Mode == config.AskHumanFile {
+		return nil, fmt.Errorf("%s: %w: %d open in %s", stage, tools.ErrAwaitingAnswers, blocking, questions.MarkdownPath(path))
+	}
+	ask := &tools.Asker{Mode: cfg.AskHuman.Mode, Stage: stage, Path: path, Out: stdout}
+	if isTerminal(os.Stdin) {
+		ask.In = os.Stdin
+	}
+	redactor, err := newRedactor(configPath, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("load redactions: %w", err)
+	}
+	ws := newWorkspace(configPath, stage, cfg, extraReads...)
+	if redactor.Enabled() {
+		ws.Redact = redactor.Redact
+	}
+	return &stageRun{
+		stage:    stage,
+		cfg:      cfg,
+		source:   source,
+		ws:       ws,
+		ask:      ask,
+		answered: answered,
+		runsDir:  runsPath(configPath),
+		redactor: redactor,
+		log:      logging.Or(log).With("stage", stage),
+	}, nil
+}
+
+func (r *stageRun) tools() tools.Set {
+	return append(r.ws.Tools(), r.ask.Tools()...)
+}
+
+// execute runs the agent over the redacted prompts under the configured
+// budget and stage timeout, prints its token usage and stores the
+// transcript of the run under .agentflow/runs, whether or not the run
+// succeeds. For stages with diagrams, the repair pass runs inside the same
+// budget, deadline and transcript. A run that exceeds its budget is stopped
+// and fails with usage.ErrBudgetExceeded; one that is cancelled or times
+// out fails with the context's error.
+func (r *stageRun) execute(ctx context.Context, runner agents.Runner, prompts []agents.TResponseInputItem) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%s: %w", r.stage, context.Cause(ctx))
+	}
+	if r.redactor.Enabled() {
+		prompts = agents.MapText(prompts, r.redactor.Redact)
+		defer func() {
+			if err := r.redactor.Save(); err != nil {
+				r.log.Warn("could not save redactions", "err", err)
+			}
+		}()
+	}
+	spent := 0.0
+	if r.cfg.Budget.MaxCostPerProject > 0 {
+		past, err := transcript.List(r.runsDir)
+		if err != nil {
+			return fmt.Errorf("load past runs: %w", err)
+		}
+		spent = usage.Spent(past)
+		if err := usage.CheckProject(r.cfg, spent); err != nil {
+			return fmt.Errorf("%s: %w", r.stage, err)
+		}
+	}
+
+	if timeout := r.cfg.StageTimeout(r.stage); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s: %w", timeout, context.DeadlineExceeded))
+		defer cancel()
+	}
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	meter := usage.NewMeter(r.cfg, r.cfg.LLM.Model, spent, cancel)
+	t := transcript.New(r.stage, r.cfg.LLM.Provider, r.cfg.LLM.Model, promptMessages(prompts))
+	passCtx := usage.NewContext(transcript.NewContext(runCtx, t), meter)
+	out, err := runner.RunInputs(passCtx, prompts)
+	if err == nil && stageSpecs[r.stage].Diagrams {
+		out, err = r.repairDiagrams(passCtx, runner, prompts, out)
+	}
+	switch {
+	case meter.Err() != nil:
+		err = fmt.Errorf("%s: run stopped: %w", r.stage, meter.Err())
+	case ctx.Err() != nil:
+		r.interrupted = true
+		err = fmt.Errorf("%s: %w", r.stage, context.Cause(ctx))
+	}
+
+	t.Finish(out, err)
+	in, outTokens := meter.Tokens()
+	cost, priced := meter.Cost()
+	t.SetUsage(in, outTokens, cost, priced)
+	if saveErr := t.Save(r.runsDir); saveErr != nil {
+		r.log.Warn("could not save transcript", "run", t.ID, "err", saveErr)
+	}
+	attrs := []any{"run", t.ID, "input_tokens", in, "output_tokens", outTokens}
+	if priced {
+		attrs = append(attrs, "cost_usd", cost)
+	}
+	r.log.Info("agent run finished", attrs...)
+	fmt.Fprintf(stdout, "> %s used %s\n", r.stage, meter.Summary())
+	return err
+}
+
+// promptMessages converts the prompt items into transcript messages.
+func promptMessages(prompts []agents.TResponseInputItem) []transcript.Message {
+	msgs := make([]transcript.Message, 0, len(prompts))
+	for _, p := range prompts {
+		if p.OfMessage == nil {
+			continue
+		}
+		msgs = append(msgs, transcript.Message{
+			Role:    string(p.OfMessage.Role),
+			Content: p.OfMessage.Content.OfString.String(),
+		})
+	}
+	return msgs
+}
+
+func countBlocking(qs []questions.Question) int {
+	n := 0
+	for _, q := range qs {
+		if q.Blocking() {
+			n++
+		}
+	}
+	return n
+}
+
+// withAnswers appends the answers to this stage's earlier questions to
+// prompts, so re-runs build on them.
+func (r *stageRun) withAnswers(prompts []agents.TResponseInputItem) []agents.TResponseInputItem {
+	if len(r.answered) == 0 {
+		return prompts
+	}
+	return append(prompts, agents.UserMessage(questions.FormatAnswers(r.answered)))
+}
+
+// finish reports refused writes, post-processes and verifies the outputs,
+// logs what diagram lint still finds in them, records the questions the documents leave open, and pauses the stage if
+// the agent asked a human something. An interrupted run is rolled back
+// instead, so no half-finished documents are left behind.
+func (r *stageRun) finish(runErr error) error {
+	reportRefused(r.log, r.ws)
+	if r.interrupted {
+		restored, err := r.ws.Rollback()
+		if len(restored) > 0 {
+			r.log.Warn("run interrupted; rolled back agent writes", "files", strings.Join(restored, ", "))
+		}
+		if err != nil {
+			r.log.Error("rollback incomplete", "err", err)
+		}
+		return runErr
+	}
+	err := finishStage(r.log, r.stage, r.cfg, r.source, runErr)
+	if stageSpecs[r.stage].Diagrams {
+		// Scaffolds and patched sections are checked too, not only what
+		// the agent wrote.
+		for _, p := range r.lintDiagrams() {
+			r.log.Warn("diagram still broken", "file", p.Path, "line", p.Line, "lang", p.Lang, "problem", p.Message)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.trackQuestions(); err != nil {
+		return err
+	}
+	if n := r.ask.Pending(); n > 0 {
+		return fmt.Errorf("%s: %w: answer %d question(s) with `agentflow answer` or in %s and re-run", r.stage, tools.ErrAwaitingAnswers, n, questions.MarkdownPath(r.ask.Path))
+	}
+	return nil
+}
+
+// trackQuestions adds the questions and assumptions listed in the stage's
+// documents to the tracker. Items already answered are not raised again.
+func (r *stageRun) trackQuestions() error {
+	var found []questions.Question
+	for _, out := range stageSpecs[r.stage].Outputs {
+		data, err := os.ReadFile(filepath.Join(r.cfg.IO.OutputDir, out))
+		if err != nil {
+			continue // directory outputs, or documents verification already vetted
+		}
+		for _, q := range questions.Extract(string(data)) {
+			q.Stage, q.Origin = r.stage, out
+			found = append(found, q)
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	added := 0
+	err := questions.Update(r.ask.Path, func(s *questions.Store) error {
+		for _, q := range found {
+			if _, isNew := s.Add(q); isNew {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("track questions: %w", err)
+	}
+	if added > 0 {
+		r.log.Info("new questions raised; see `agentflow questions list`", "count", added)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"agentflow/internal/transcript"
+)
+
+type RunsListOptions struct {
+	ConfigPath string
+	Stage      string // only runs of this stage
+}
+
+// RunsList prints the recorded agent runs, oldest first.
+func RunsList(w io.Writer, opts RunsListOptions) error {
+	runs, err := transcript.List(runsPath(opts.ConfigPath))
+	if err != nil {
+		return fmt.Errorf("list runs: %w", err)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTAGE\tSTATUS\tMODEL\tDURATION\tTOOL CALLS")
+	n := 0
+	for _, t := range runs {
+		if opts.Stage != "" && t.Stage != opts.Stage {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", t.ID, t.Stage, t.Status, t.Model, duration(t.DurationMS), len(t.ToolCalls))
+		n++
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "No runs.")
+	}
+	return nil
+}
+
+type RunsShowOptions struct {
+	ConfigPath string
+	ID         string // run ID or a unique prefix of one
+	Full       bool   // print tool arguments and results untruncated
+}
+
+// showLimit caps tool arguments and results in `runs show` unless Full is
+// set; file contents can be long.
+const showLimit = 400
+
+// clipText cuts s to showLimit bytes, backing up to the start of a rune so
+// multi-byte text such as Thai is not split.
+func clipText(s string) string {
+	if len(s) <= showLimit {
+		return s
+	}
+	n := showLimit
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + fmt.Sprintf("… (%d bytes, use -full)", len(s))
+}
+
+// RunsShow prints the transcript of a single run.
+func RunsShow(w io.Writer, opts RunsShowOptions) error {
+	t, err := transcript.Load(runsPath(opts.ConfigPath), opts.ID)
+	if err != nil {
+		return err
+	}
+	clip := func(s string) string {
+		if opts.Full {
+			return s
+		}
+		return clipText(s)
+	}
+
+	fmt.Fprintf(w, "Run:      %s\n", t.ID)
+	fmt.Fprintf(w, "Stage:    %s\n", t.Stage)
+	fmt.Fprintf(w, "Model:    %s %s\n", t.Provider, t.Model)
+	fmt.Fprintf(w, "Started:  %s\n", t.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "Duration: %s\n", duration(t.DurationMS))
+	fmt.Fprintf(w, "Status:   %s\n", t.Status)
+	if t.Error != "" {
+		fmt.Fprintf(w, "Error:    %s\n", t.Error)
+	}
+
+	fmt.Fprintf(w, "\n## Prompt (%d messages)\n", len(t.Prompt))
+	for _, m := range t.Prompt {
+		fmt.Fprintf(w, "\n[%s]\n%s\n", m.Role, clip(m.Content))
+	}
+
+	fmt.Fprintf(w, "\n## Responses (%d)\n", len(t.Responses))
+	for i, r := range t.Responses {
+		fmt.Fprintf(w, "\n%d. %s", i+1, r.At.Format(time.TimeOnly))
+		if r.ID != "" {
+			fmt.Fprintf(w, " %s", r.ID)
+		}
+		if r.Usage != nil {
+			fmt.Fprintf(w, " (%d in / %d out tokens)", r.Usage.InputTokens, r.Usage.OutputTokens)
+		}
+		fmt.Fprintln(w)
+		if r.Text != "" {
+			fmt.Fprintln(w, clip(r.Text))
+		}
+	}
+
+	fmt.Fprintf(w, "\n## Tool calls (%d)\n", len(t.ToolCalls))
+	for i, c := range t.ToolCalls {
+		fmt.Fprintf(w, "\n%d. %s %s (%s)\n", i+1, c.StartedAt.Format(time.TimeOnly), c.Tool, duration(c.DurationMS))
+		fmt.Fprintf(w, "   args:   %s\n", clip(strings.TrimSpace(string(c.Args))))
+		if c.Error != "" {
+			fmt.Fprintf(w, "   error:  %s\n", c.Error)
+		} else {
+			fmt.Fprintf(w, "   result: %s\n", clip(c.Result))
+		}
+	}
+	if t.Output != "" {
+		fmt.Fprintf(w, "\n## Final output\n\n%s\n", clip(t.Output))
+	}
+	return nil
+}
+
+func duration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
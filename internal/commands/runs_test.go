@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/transcript"
+)
+
+func TestRuns_RecordsTranscripts(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"qa": {
+			Calls: []agents.MockCall{
+				createFileCall(filepath.Join(tempDir, "requirements.md"), "# not mine"),
+				createFileCall(filepath.Join(tempDir, "test-plan.md"), validDoc("test-plan.md")),
+			},
+			Output: "test plan written",
+		},
+	}})
+	if err := QA(context.Background(), QAOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider}); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := transcript.List(runsPath(configPath))
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("expected one transcript, got %d (%v)", len(runs), err)
+	}
+	run := runs[0]
+	if run.Stage != "qa" || run.Status != transcript.StatusOK || len(run.Prompt) == 0 || run.Output != "test plan written" {
+		t.Fatalf("transcript: %+v", run)
+	}
+	if len(run.ToolCalls) != 2 || run.ToolCalls[0].Error == "" || run.ToolCalls[1].Tool != "file_creator" || run.ToolCalls[1].Error != "" {
+		t.Fatalf("tool calls: %+v", run.ToolCalls)
+	}
+
+	var buf bytes.Buffer
+	if err := RunsList(&buf, RunsListOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), run.ID) {
+		t.Fatalf("runs list:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := RunsShow(&buf, RunsShowOptions{ConfigPath: configPath, ID: run.ID}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Stage:    qa", "## Tool calls (2)", "test-plan.md", "error:", "test plan written"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("runs show missing %q:\n%s", want, buf.String())
+		}
+	}
+	if err := RunsShow(&buf, RunsShowOptions{ConfigPath: configPath, ID: "nope"}); !errors.Is(err, transcript.ErrUnknownRun) {
+		t.Fatalf("expected ErrUnknownRun, got %v", err)
+	}
+}
+
+func TestClipText_KeepsRunesWhole(t *testing.T) {
+	thai := strings.Repeat("ภาษาไทย", 40) // three bytes per rune
+	got := clipText(thai)
+	if !utf8.ValidString(got) || !strings.HasPrefix(thai, strings.Split(got, "…")[0]) || !strings.Contains(got, "use -full") {
+		t.Fatalf("clipped text: %q", got)
+	}
+	if got := clipText("short"); got != "short" {
+		t.Fatalf("short text clipped: %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/config"
+	"agentflow/internal/pipeline"
+)
+
+// stageSpec declares the documents a command reads from its source
+// directory and writes to its output directory.
+type stageSpec struct {
+	Inputs  []string
+	Outputs []string
+	// Diagrams marks outputs that carry PlantUML or Mermaid diagrams; they
+	// are linted after the agent run and repaired within it.
+	Diagrams bool
+}
+
+var priorDocs = []string{"requirements.md", "srs.md", "stories.md"}
+
+func withPriorDocs(extra ...string) []string {
+	return append(append([]string{}, priorDocs...), extra...)
+}
+
+// stageSpecs is the single source of truth for what each command consumes
+// and produces. Intake reads the input directory rather than documents and
+// so declares no inputs here.
+var stageSpecs = map[string]stageSpec{
+	"intake":  {Outputs: []string{"requirements.md"}},
+	"plan":    {Inputs: []string{"requirements.md"}, Outputs: []string{"srs.md", "stories.md", "acceptance_criteria.md"}},
+	"design":  {Inputs: withPriorDocs(), Outputs: []string{"architecture.md"}, Diagrams: true},
+	"uml":     {Inputs: withPriorDocs(), Outputs: []string{"uml.md"}, Diagrams: true},
+	"qa":      {Inputs: withPriorDocs("acceptance_criteria.md"), Outputs: []string{"test-plan.md"}},
+	"entity":  {Inputs: withPriorDocs("architecture.md"), Outputs: []string{"entities.md"}, Diagrams: true},
+	"repo":    {Inputs: withPriorDocs("architecture.md", "entities.md"), Outputs: []string{"repository.md"}},
+	"devplan": {Inputs: withPriorDocs("acceptance_criteria.md", "architecture.md", "uml.md"), Outputs: []string{"task_list.md", "tasks"}},
+}
+
+func joinAll(dir string, names []string) []string {
+	out := make([]string, len(names))
+	for i, n := range names {
+		out[i] = filepath.Join(dir, n)
+	}
+	return out
+}
+
+// manifestPath returns the location of the build manifest, which lives next
+// to the config file.
+func manifestPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), "manifest.json")
+}
+
+// build tracks whether a command's previous result is still current. It is
+// keyed in .agentflow/manifest.json, next to the config file.
+type build struct {
+	manifest string
+	stage    string
+	fp       pipeline.Fingerprint
+}
+
+// newBuild fingerprints a stage from its input files, rendered prompts and
+// the effective config and role. Outputs are taken from stageSpecs.
+func newBuild(configPath, stage string, cfg *config.Config, role agents.Role, prompts []agents.TResponseInputItem, inputs []string) (*build, error) {
+	hashes, err := pipeline.HashFiles(inputs)
+	if err != nil {
+		return nil, err
+	}
+	var prompt strings.Builder
+	for _, p := range prompts {
+		if p.OfMessage != nil {
+			prompt.WriteString(p.OfMessage.Content.OfString.String())
+			prompt.WriteString("\n")
+		}
+	}
+	cfgJSON, err := json.Marshal(struct {
+		Config *config.Config
+		Role   agents.Role
+	}{cfg, role})
+	if err != nil {
+		return nil, err
+	}
+	return &build{
+		manifest: manifestPath(configPath),
+		stage:    stage,
+		fp: pipeline.Fingerprint{
+			Inputs:  hashes,
+			Prompt:  pipeline.HashPrompt(prompt.String()),
+			Config:  pipeline.HashString(string(cfgJSON)),
+			Outputs: joinAll(cfg.IO.OutputDir, stageSpecs[stage].Outputs),
+		},
+	}, nil
+}
+
+// skip reports whether the stage can be skipped, logging a note if so.
+func (b *build) skip(log *slog.Logger, force bool) bool {
+	if force {
+		return false
+	}
+	ok, err := pipeline.StageUpToDate(b.manifest, b.stage, b.fp)
+	if err != nil || !ok {
+		return false
+	}
+	log.Info("up to date, skipping (use --force to rebuild)")
+	return true
+}
+
+// record marks the stage as freshly built.
+func (b *build) record() error {
+	return pipeline.RecordStage(b.manifest, b.stage, b.fp)
+}
+
+// inputMarkdownFiles lists the .md files directly under dir in name order.
+func inputMarkdownFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".md") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
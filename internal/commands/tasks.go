@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"agentflow/internal/config"
+	"agentflow/internal/taskgraph"
+	"agentflow/internal/tasks"
+)
+
+// ErrTasksBlocked is returned by TasksNext when no open task can start.
+var ErrTasksBlocked = errors.New("every open task is blocked")
+
+// loadTasks loads the development plan from outputDir, or from the
+// configured output directory when it is empty.
+func loadTasks(configPath, outputDir string) (*tasks.Plan, error) {
+	if strings.TrimSpace(outputDir) == "" {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+		cfg.ApplyEnv()
+		outputDir = cfg.IO.OutputDir
+	}
+	plan, err := tasks.Load(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("load tasks: %w", err)
+	}
+	return plan, nil
+}
+
+func taskStatus(done bool) string {
+	if done {
+		return "done"
+	}
+	return "open"
+}
+
+// subtaskProgress renders "2/5", or "-" for a task without a file.
+func subtaskProgress(t *tasks.Task) string {
+	if t == nil {
+		return "-"
+	}
+	done := 0
+	for _, s := range t.Subtasks {
+		if s.Done {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d", done, len(t.Subtasks))
+}
+
+type TasksListOptions struct {
+	ConfigPath string
+	OutputDir  string // defaults to io.outputDir
+	Open       bool   // only tasks that are not done
+}
+
+// TasksList prints the task list with each task's status and subtask
+// progress, followed by any inconsistencies between the list and the task
+// files, including invalid dependencies.
+func TasksList(w io.Writer, opts TasksListOptions) error {
+	plan, err := loadTasks(opts.ConfigPath, opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tSUBTASKS\tTITLE")
+	n := 0
+	for _, it := range plan.List.Items {
+		if opts.Open && it.Done {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", it.ID, taskStatus(it.Done), subtaskProgress(plan.Tasks[it.ID]), it.Title)
+		n++
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Fprintln(w, "No tasks.")
+	}
+	printProblems(w, planProblems(plan))
+	return nil
+}
+
+// planProblems adds the dependency problems of the plan to the ones found
+// by tasks.Load.
+func planProblems(plan *tasks.Plan) []tasks.Problem {
+	problems := plan.Problems
+	for _, p := range taskgraph.FromPlan(plan).Problems() {
+		path := plan.List.Path
+		if t := plan.Tasks[p.ID]; t != nil {
+			path = t.Path
+		}
+		problems = append(problems, tasks.Problem{ID: p.ID, Path: path, Message: p.Message})
+	}
+	return problems
+}
+
+func printProblems(w io.Writer, problems []tasks.Problem) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%d problem(s):\n", len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(w, "  %s\n", p)
+	}
+}
+
+type TasksShowOptions struct {
+	ConfigPath string
+	OutputDir  string // defaults to io.outputDir
+	ID         string // "TASK-003", "task-3" or "3"
+	Full       bool   // print <context> untruncated
+}
+
+// sectionTitles names the standard sections in `tasks show`; other
+// sections are shown under their tag.
+var sectionTitles = map[string]string{
+	tasks.SectionTask:      "Task",
+	tasks.SectionDependsOn: "Depends on",
+	tasks.SectionContext:   "Context",
+	tasks.SectionImplement: "Implement",
+	tasks.SectionSubtask:   "Subtasks",
+	tasks.SectionDoD:       "Definition of Done",
+}
+
+// TasksShow prints a single task: its list entry and the sections of its
+// file. The context, which embeds whole documents, is clipped unless Full
+// is set.
+func TasksShow(w io.Writer, opts TasksShowOptions) error {
+	plan, err := loadTasks(opts.ConfigPath, opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	return showTask(w, plan, tasks.NormalizeID(opts.ID), opts.Full)
+}
+
+func showTask(w io.Writer, plan *tasks.Plan, id string, full bool) error {
+	it, listed := plan.List.Find(id)
+	t := plan.Tasks[id]
+	if !listed && t == nil {
+		return fmt.Errorf("%w: %s", tasks.ErrUnknownTask, id)
+	}
+	title := ""
+	switch {
+	case listed:
+		title = it.Title
+	case t != nil:
+		title = t.Title
+	}
+	fmt.Fprintf(w, "Task:     %s — %s\n", id, title)
+	if listed {
+		fmt.Fprintf(w, "Status:   %s\n", taskStatus(it.Done))
+	} else {
+		fmt.Fprintf(w, "Status:   not in %s\n", tasks.ListFile)
+	}
+	if t == nil {
+		fmt.Fprintf(w, "File:     none (expected %s/%s.md)\n", tasks.Dir, id)
+		return nil
+	}
+	fmt.Fprintf(w, "File:     %s\n", t.Path)
+	fmt.Fprintf(w, "Subtasks: %s done\n", subtaskProgress(t))
+	for _, name := range t.Order {
+		heading, ok := sectionTitles[name]
+		if !ok {
+			heading = name
+		}
+		body := t.Section(name)
+		if name == tasks.SectionContext && !full {
+			body = clipText(body)
+		}
+		fmt.Fprintf(w, "\n## %s\n\n%s\n", heading, body)
+	}
+	return nil
+}
+
+type TasksDoneOptions struct {
+	ConfigPath string
+	OutputDir  string // defaults to io.outputDir
+	ID         string
+	Undo       bool // uncheck instead
+}
+
+// TasksDone checks the task's box in task_list.md, or unchecks it with
+// Undo. The rest of the file is left exactly as it was.
+func TasksDone(opts TasksDoneOptions) error {
+	plan, err := loadTasks(opts.ConfigPath, opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	return plan.List.SetDone(tasks.NormalizeID(opts.ID), !opts.Undo)
+}
+
+type TasksNextOptions struct {
+	ConfigPath string
+	OutputDir  string // defaults to io.outputDir
+	Full       bool
+}
+
+// TasksNext shows the first open task, in list order, whose dependencies
+// are all done. It returns ErrTasksBlocked when tasks are left but every one
+// of them waits on another open task, which only a dependency cycle causes.
+func TasksNext(w io.Writer, opts TasksNextOptions) error {
+	plan, err := loadTasks(opts.ConfigPath, opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	g := taskgraph.FromPlan(plan)
+	if ready := g.Ready(); len(ready) > 0 {
+		return showTask(w, plan, ready[0], opts.Full)
+	}
+	var open []string
+	for _, n := range g.Nodes {
+		if !n.Done {
+			open = append(open, n.ID)
+		}
+	}
+	if len(open) == 0 {
+		fmt.Fprintln(w, "All tasks are done.")
+		return nil
+	}
+	var problems []string
+	for _, p := range g.Problems() {
+		problems = append(problems, p.String())
+	}
+	return fmt.Errorf("%w: %s all wait on open dependencies (%s)", ErrTasksBlocked, strings.Join(open, ", "), strings.Join(problems, "; "))
+}
+
+type TasksGraphOptions struct {
+	ConfigPath string
+	OutputDir  string // defaults to io.outputDir
+	Format     string // taskgraph.FormatMermaid (default), FormatDOT or FormatText
+}
+
+// TasksGraph renders the dependency graph of the task plan. It fails,
+// listing every problem, when a task depends on an unknown task or the
+// dependencies form a cycle.
+func TasksGraph(w io.Writer, opts TasksGraphOptions) error {
+	plan, err := loadTasks(opts.ConfigPath, opts.OutputDir)
+	if err != nil {
+		return err
+	}
+	format := opts.Format
+	if strings.TrimSpace(format) == "" {
+		format = taskgraph.FormatMermaid
+	}
+	return taskgraph.FromPlan(plan).Render(w, format)
+}
@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"agentflow/internal/taskgraph"
+	"agentflow/internal/tasks"
+)
+
+// writeTaskPlan writes a two-task development plan into dir.
+func writeTaskPlan(t *testing.T, dir string) {
+	t.Helper()
+	files := map[string]string{
+		tasks.ListFile:                          "# Tasks\n\n- [x] TASK-001 — Scaffold the service\n- [ ] TASK-002 — Add bookings\n",
+		filepath.Join(tasks.Dir, "TASK-001.md"): "# TASK-001 — Scaffold the service\n\n<task>\nScaffold.\n</task>\n<context>\nctx\n</context>\n<implement>\nGo.\n</implement>\n<subtask>\n- [x] init\n</subtask>\n<dod>\n- builds\n</dod>\n",
+		filepath.Join(tasks.Dir, "TASK-002.md"): "# TASK-002 — Add bookings\n\n<task>\nBookings.\n</task>\n<depends_on>\nTASK-001\n</depends_on>\n<context>\n" + strings.Repeat("ห้องพัก ", 100) + "\n</context>\n<implement>\nREST.\n</implement>\n<subtask>\n- [ ] model\n- [x] handler\n</subtask>\n<dod>\n- tested\n</dod>\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTasks_ListShowDoneNext(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	writeTaskPlan(t, tempDir)
+
+	var buf bytes.Buffer
+	if err := TasksList(&buf, TasksListOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"TASK-001  done", "TASK-002  open", "1/2", "Add bookings"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("tasks list missing %q:\n%s", want, buf.String())
+		}
+	}
+	if strings.Contains(buf.String(), "problem") {
+		t.Errorf("unexpected problems:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := TasksShow(&buf, TasksShowOptions{OutputDir: tempDir, ID: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Task:     TASK-002 — Add bookings", "Status:   open", "Subtasks: 1/2 done", "## Definition of Done", "use -full)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("tasks show missing %q:\n%s", want, buf.String())
+		}
+	}
+	if !utf8.ValidString(buf.String()) {
+		t.Error("clipped context is not valid UTF-8")
+	}
+	if err := TasksShow(&buf, TasksShowOptions{OutputDir: tempDir, ID: "TASK-042"}); !errors.Is(err, tasks.ErrUnknownTask) {
+		t.Fatalf("expected ErrUnknownTask, got %v", err)
+	}
+
+	buf.Reset()
+	if err := TasksNext(&buf, TasksNextOptions{OutputDir: tempDir}); err != nil || !strings.Contains(buf.String(), "TASK-002") {
+		t.Fatalf("tasks next: %v\n%s", err, buf.String())
+	}
+
+	if err := TasksDone(TasksDoneOptions{OutputDir: tempDir, ID: "task-2"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(tempDir, tasks.ListFile))
+	if string(data) != "# Tasks\n\n- [x] TASK-001 — Scaffold the service\n- [x] TASK-002 — Add bookings\n" {
+		t.Fatalf("task_list.md:\n%s", data)
+	}
+	buf.Reset()
+	if err := TasksNext(&buf, TasksNextOptions{OutputDir: tempDir}); err != nil || buf.String() != "All tasks are done.\n" {
+		t.Fatalf("tasks next: %v\n%s", err, buf.String())
+	}
+
+	if err := TasksDone(TasksDoneOptions{OutputDir: tempDir, ID: "TASK-001", Undo: true}); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := TasksList(&buf, TasksListOptions{OutputDir: tempDir, Open: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "TASK-001") || strings.Contains(buf.String(), "TASK-002") {
+		t.Fatalf("tasks list -open:\n%s", buf.String())
+	}
+}
+
+func TestTasks_NextFollowsDependencies(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		tasks.ListFile:                          "- [ ] TASK-001 — Checkout\n- [ ] TASK-002 — Payments\n- [ ] TASK-003 — Bookings\n",
+		filepath.Join(tasks.Dir, "TASK-001.md"): "# TASK-001 — Checkout\n\n<depends_on>\nTASK-002\n</depends_on>\n",
+		filepath.Join(tasks.Dir, "TASK-002.md"): "# TASK-002 — Payments\n\n<depends_on>\nTASK-003\n</depends_on>\n",
+		filepath.Join(tasks.Dir, "TASK-003.md"): "# TASK-003 — Bookings\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := TasksNext(&buf, TasksNextOptions{OutputDir: tempDir}); err != nil || !strings.Contains(buf.String(), "TASK-003") {
+		t.Fatalf("tasks next should skip the blocked tasks: %v\n%s", err, buf.String())
+	}
+
+	// Close the loop: TASK-003 now waits for TASK-001.
+	cyclic := "# TASK-003 — Bookings\n\n<depends_on>\nTASK-001\n</depends_on>\n"
+	if err := os.WriteFile(filepath.Join(tempDir, tasks.Dir, "TASK-003.md"), []byte(cyclic), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	err := TasksNext(&buf, TasksNextOptions{OutputDir: tempDir})
+	if !errors.Is(err, ErrTasksBlocked) || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("expected ErrTasksBlocked naming the cycle, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("nothing should be shown when every task is blocked:\n%s", buf.String())
+	}
+}
+
+func TestTasks_Graph(t *testing.T) {
+	tempDir := t.TempDir()
+	writeTaskPlan(t, tempDir)
+
+	var buf bytes.Buffer
+	if err := TasksGraph(&buf, TasksGraphOptions{OutputDir: tempDir}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"flowchart LR", "TASK_001 --> TASK_002", "class TASK_002 critical"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("mermaid missing %q:\n%s", want, buf.String())
+		}
+	}
+	buf.Reset()
+	if err := TasksGraph(&buf, TasksGraphOptions{OutputDir: tempDir, Format: "dot"}); err != nil || !strings.Contains(buf.String(), `"TASK-001" -> "TASK-002"`) {
+		t.Fatalf("dot: %v\n%s", err, buf.String())
+	}
+
+	// A dependency on a task that does not exist fails the graph and shows
+	// up in tasks list.
+	path := filepath.Join(tempDir, tasks.Dir, "TASK-001.md")
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, []byte(string(data)+"<depends_on>\nTASK-009\n</depends_on>\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := TasksGraph(&buf, TasksGraphOptions{OutputDir: tempDir}); !errors.Is(err, taskgraph.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	buf.Reset()
+	if err := TasksList(&buf, TasksListOptions{OutputDir: tempDir}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "TASK-001: depends on unknown task TASK-009") {
+		t.Fatalf("tasks list:\n%s", buf.String())
+	}
+}
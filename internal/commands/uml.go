@@ -5,6 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"log/slog"
 	"path/filepath"
 	"text/template"
 
@@ -21,9 +22,12 @@ type UmlOptions struct {
 	OutputDir  string // where to write uml.md
 	Role       string
 	DryRun     bool
+	Force      bool            // rebuild even if the manifest says the outputs are current
+	Provider   agents.Provider // nil selects the provider configured in llm.provider
+	Logger     *slog.Logger    // nil uses slog.Default()
 }
 
-func Uml(opts UmlOptions) error {
+func Uml(ctx context.Context, opts UmlOptions) error {
 	cfg, err := config.Load(opts.ConfigPath)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
@@ -38,6 +42,11 @@ func Uml(opts UmlOptions) error {
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
+	cfg.LLM = cfg.LLM.For("uml")
+	role, err := resolveRole(cfg, opts.Role, "sa")
+	if err != nil {
+		return err
+	}
 	if err := config.EnsureDirs(opts.ConfigPath, cfg); err != nil {
 		return err
 	}
@@ -51,11 +60,29 @@ func Uml(opts UmlOptions) error {
 		return nil
 	}
 
-	_, err = agents.SA.RunInputs(context.Background(), prompts)
+	run, err := newStageRun(opts.Logger, opts.ConfigPath, "uml", cfg, filepath.Join(opts.SourceDir, "requirements.md"), opts.SourceDir)
+	if err != nil {
+		return err
+	}
+	prompts = run.withAnswers(prompts)
+
+	b, err := newBuild(opts.ConfigPath, "uml", cfg, role, prompts, joinAll(opts.SourceDir, stageSpecs["uml"].Inputs))
+	if err != nil {
+		return err
+	}
+	if b.skip(run.log, opts.Force) {
+		return nil
+	}
+
+	runner, err := newRunner(opts.Provider, cfg, role, run)
 	if err != nil {
-		fmt.Printf("\n\n> Note: OpenAI call failed, wrote scaffold instead. Error: %v\n", err)
+		return err
+	}
+	err = run.execute(ctx, runner, prompts)
+	if err := run.finish(err); err != nil {
+		return err
 	}
-	return err
+	return b.record()
 }
 
 func buildUmlSystemMessage(sourceDir, outputDir string) ([]agents.TResponseInputItem, error) {
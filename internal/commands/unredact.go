@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agentflow/internal/config"
+	"agentflow/internal/redact"
+)
+
+type UnredactOptions struct {
+	ConfigPath string
+	Paths      []string // files or directories; empty restores the output directory
+	DryRun     bool     // report what would be restored without writing
+}
+
+// Unredact puts the original values back in place of redaction
+// placeholders in documents the agents wrote. The restored files hold the
+// secrets and personal data again, so this is meant for local copies only.
+func Unredact(w io.Writer, opts UnredactOptions) error {
+	cfg, err := config.Load(opts.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	cfg.ApplyEnv()
+	redactor, err := newRedactor(opts.ConfigPath, cfg)
+	if err != nil {
+		return fmt.Errorf("load redactions: %w", err)
+	}
+	paths := opts.Paths
+	if len(paths) == 0 {
+		paths = []string{cfg.IO.OutputDir}
+	}
+	files, restored := 0, 0
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".md") {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			before := redact.Count(string(data))
+			if before == 0 {
+				return nil
+			}
+			out := redactor.Restore(string(data))
+			n := before - redact.Count(out)
+			if n == 0 {
+				return nil
+			}
+			fmt.Fprintf(w, "%s: %d placeholder(s)\n", path, n)
+			files++
+			restored += n
+			if opts.DryRun {
+				return nil
+			}
+			return os.WriteFile(path, []byte(out), 0o644)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	verb := "Restored"
+	if opts.DryRun {
+		verb = "Would restore"
+	}
+	fmt.Fprintf(w, "%s %d placeholder(s) in %d file(s)\n", verb, restored, files)
+	return nil
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/config"
+	"agentflow/internal/transcript"
+)
+
+func TestIntake_RedactsPromptsAndReadsAndRestores(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Redact.Secrets, cfg.Redact.PII = true, true
+	cfg.Security.EnvKeys = []string{"CONSENT_DB_PASSWORD"}
+	if err := config.Save(configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONSENT_DB_PASSWORD", "p@ssw0rd-consent")
+
+	inputDir := filepath.Join(tempDir, "input")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	notes := "- Owner: somchai@example.co.th\n- Phone 081-234-5678\n- DB password p@ssw0rd-consent\n"
+	notesPath := filepath.Join(inputDir, "2025-01-15.md")
+	if err := os.WriteFile(notesPath, []byte(notes), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A later owner makes the timeline narrative quote both addresses.
+	if err := os.WriteFile(filepath.Join(inputDir, "2025-02-01.md"), []byte("- Owner: malee@example.co.th\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	readArgs, _ := json.Marshal(map[string]string{"Path": notesPath})
+	reqPath := filepath.Join(tempDir, "requirements.md")
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"intake": {Calls: []agents.MockCall{
+			{Tool: "file_reader", Args: readArgs},
+			createFileCall(reqPath, "# Requirements\n\nProduct owner: [REDACTED_EMAIL_1]\n"),
+		}},
+	}})
+	if err := Intake(context.Background(), IntakeOptions{ConfigPath: configPath, InputsDir: inputDir, OutputDir: tempDir, Provider: provider}); err != nil {
+		t.Fatal(err)
+	}
+
+	read := provider.Calls()[0].Result
+	for _, leaked := range []string{"somchai", "081-234-5678", "p@ssw0rd"} {
+		if strings.Contains(read, leaked) {
+			t.Errorf("file_reader leaked %q: %s", leaked, read)
+		}
+	}
+	runs, err := transcript.List(runsPath(configPath))
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("transcripts: %d (%v)", len(runs), err)
+	}
+	prompt := runs[0].Prompt[0].Content
+	if strings.Contains(prompt, "@example.co.th") || !strings.Contains(prompt, "[REDACTED_EMAIL_2]") {
+		t.Fatalf("prompt was not redacted:\n%s", prompt)
+	}
+
+	var out bytes.Buffer
+	if err := Unredact(&out, UnredactOptions{ConfigPath: configPath, Paths: []string{reqPath}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(reqPath)
+	if err != nil || !strings.Contains(string(data), "Product owner: somchai@example.co.th") {
+		t.Fatalf("requirements.md not restored: %q (%v)\n%s", data, err, out.String())
+	}
+}
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
+)
+
+type UsageReportOptions struct {
+	ConfigPath string
+	By         string // "stage" (default), "model" or "day"
+	Since      string // YYYY-MM-DD; only runs started on or after it
+}
+
+// UsageReport prints token usage and cost aggregated over the recorded
+// runs.
+func UsageReport(w io.Writer, opts UsageReportOptions) error {
+	var since time.Time
+	if s := strings.TrimSpace(opts.Since); s != "" {
+		var err error
+		if since, err = time.ParseInLocation(time.DateOnly, s, time.Local); err != nil {
+			return fmt.Errorf("invalid -since date %q: want YYYY-MM-DD", s)
+		}
+	}
+	runs, err := transcript.List(runsPath(opts.ConfigPath))
+	if err != nil {
+		return fmt.Errorf("list runs: %w", err)
+	}
+	report, err := usage.Summarize(runs, opts.By, since)
+	if err != nil {
+		return err
+	}
+	if len(report.Rows) == 0 {
+		fmt.Fprintln(w, "No runs.")
+		return nil
+	}
+	by := opts.By
+	if by == "" {
+		by = usage.ByStage
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tRUNS\tINPUT\tOUTPUT\tTOTAL\tCOST\t\n", strings.ToUpper(by))
+	for _, row := range append(report.Rows, report.Total) {
+		cost := usage.FormatCost(row.Cost)
+		if row.Unpriced > 0 {
+			cost += fmt.Sprintf(" (%d unpriced)", row.Unpriced)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\t\n", row.Key, row.Runs, row.Input, row.Output, row.Input+row.Output, cost)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+	"agentflow/internal/config"
+	"agentflow/internal/transcript"
+	"agentflow/internal/usage"
+)
+
+func TestUsage_MetersRunsAndEnforcesBudget(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Pricing = map[string]config.ModelPrice{"gpt-4": {InputPerMTok: 10, OutputPerMTok: 30}}
+	cfg.Budget.MaxTokensPerRun = 5000
+	if err := config.Save(configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	planPath := filepath.Join(tempDir, "test-plan.md")
+	write := createFileCall(planPath, validDoc("test-plan.md"))
+	write.InputTokens, write.OutputTokens = 1000, 500
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"qa": {Calls: []agents.MockCall{write}},
+	}})
+	var out bytes.Buffer
+	stdout = &out
+	defer func() { stdout = os.Stdout }()
+	if err := QA(context.Background(), QAOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "> qa used 1000 input + 500 output tokens, $0.0250\n"; got != want {
+		t.Fatalf("usage summary = %q, want %q", got, want)
+	}
+
+	// A runaway loop: every turn costs 2000 tokens, the budget stops the
+	// third one before its tool call runs.
+	var loop []agents.MockCall
+	for i := 0; i < 10; i++ {
+		c := createFileCall(planPath, validDoc("test-plan.md"))
+		c.InputTokens = 2000
+		loop = append(loop, c)
+	}
+	provider = agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"qa": {Calls: loop},
+	}})
+	err = QA(context.Background(), QAOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider, Force: true})
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if got := len(provider.Calls()); got != 2 {
+		t.Fatalf("expected the run to stop after 2 tool calls, got %d", got)
+	}
+
+	runs, err := transcript.List(runsPath(configPath))
+	if err != nil || len(runs) != 2 {
+		t.Fatalf("expected 2 transcripts, got %d (%v)", len(runs), err)
+	}
+	if u := runs[0].Usage; u == nil || u.TotalTokens != 1500 || runs[0].CostUSD == nil || *runs[0].CostUSD != 0.025 {
+		t.Fatalf("first run usage: %+v cost %v", u, runs[0].CostUSD)
+	}
+	if runs[1].Status != transcript.StatusFailed || runs[1].Usage.InputTokens != 6000 {
+		t.Fatalf("stopped run: %+v", runs[1])
+	}
+
+	var buf bytes.Buffer
+	if err := UsageReport(&buf, UsageReportOptions{ConfigPath: configPath}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"STAGE", "qa", "TOTAL", "7500", "$0.0850"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("usage report missing %q:\n%s", want, buf.String())
+		}
+	}
+	if err := UsageReport(&buf, UsageReportOptions{ConfigPath: configPath, Since: "yesterday"}); err == nil {
+		t.Fatal("expected invalid -since error")
+	}
+}
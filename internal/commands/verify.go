@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidOutputs is wrapped by OutputError so callers can tell a run that
+// finished without producing its documents from one that failed outright.
+var ErrInvalidOutputs = errors.New("stage outputs missing or invalid")
+
+// section is a heading a document is expected to contain. Key is matched
+// case-insensitively against the whole document; a Key of alternatives
+// separated by | is present when any of them is. Fallback is appended by the
+// ensure* helpers when the key is absent.
+type section struct {
+	Key      string
+	Fallback string
+}
+
+// in reports whether the lower-cased document mentions the section.
+func (sec section) in(lower string) bool {
+	for _, key := range strings.Split(sec.Key, "|") {
+		if strings.Contains(lower, key) {
+			return true
+		}
+	}
+	return false
+}
+
+var srsSections = []section{
+	{"use cases", "\n\n## Use Cases\n- UC-01 ..."},
+	{"interfaces", "\n\n## Interfaces\n- ..."},
+	{"constraints", "\n\n## Constraints\n- ..."},
+}
+
+var architectureSections = []section{
+	{"project file structure", "\n## Project File Structure\n\nThis section describes the directories and files of the project."},
+	{"```mermaid|```plantuml", "\n## Infrastructure Diagram\n\n```mermaid\nflowchart LR\n  User --> App[Application]\n  App --> DB[Database]\n```"},
+}
+
+var umlSections = []section{
+	{"sequence", "\n## Sequence: User Interactions\n\n```mermaid\nsequenceDiagram\n  actor User\n  User->>System: Request\n  System-->>User: Response\n```"},
+	{"class", "\n## Class: Domain Models\n\n```mermaid\nclassDiagram\n  class Entity {\n    +String id\n  }\n```"},
+	{"activity", "\n## Activity: Process Flow\n\n```mermaid\nflowchart TD\n  A[Receive request] --> B[Process request]\n  B --> C[Send response]\n```"},
+}
+
+var testPlanSections = []section{
+	{"test strategy", "\n## Test Strategy\n\n- ..."},
+	{"scope", "\n## Scope\n\n- ..."},
+	{"test types", "\n## Test Types\n\n- ..."},
+	{"mapping to acceptance criteria", "\n## Mapping to Acceptance Criteria\n\n| Test Case | Acceptance Criteria |\n|---|---|\n| TC-01 | STORY-1.1 |"},
+	{"test environments & data", "\n## Test Environments & Data\n\n- ..."},
+	{"entry/exit criteria", "\n## Entry/Exit Criteria\n\n- ..."},
+	{"risks & mitigations", "\n## Risks & Mitigations\n\n- ..."},
+	{"execution plan & responsibilities", "\n## Execution Plan & Responsibilities\n\n- ..."},
+}
+
+var entitySections = []section{
+	{"domain entities", "\n## Domain Entities\n\nThis section describes the core domain entities and their relationships."},
+	{"data models", "\n## Data Models\n\nDetailed schemas and data structures."},
+	{"relationships", "\n## Relationships\n\nEntity relationships and dependencies."},
+	{"database design", "\n## Database Design\n\nDatabase-specific design considerations."},
+}
+
+var repositorySections = []section{
+	{"repository pattern overview", "\n## Repository Pattern Overview\n\nThe Repository pattern encapsulates data access logic."},
+	{"repository interfaces", "\n## Repository Interfaces\n\nInterface definitions for data access operations."},
+	{"implementation guidelines", "\n## Implementation Guidelines\n\nBest practices for repository implementation."},
+	{"testing strategies", "\n## Testing Strategies\n\nApproaches for testing repository implementations."},
+}
+
+// missingSections returns the keys of secs that s does not mention.
+func missingSections(s string, secs []section) []string {
+	lower := strings.ToLower(s)
+	var missing []string
+	for _, sec := range secs {
+		if !sec.in(lower) {
+			missing = append(missing, strings.ReplaceAll(sec.Key, "|", " or "))
+		}
+	}
+	return missing
+}
+
+// appendMissingSections appends the fallback of every section s lacks.
+func appendMissingSections(s string, secs []section) string {
+	var additions []string
+	for _, sec := range secs {
+		if !sec.in(strings.ToLower(s)) {
+			additions = append(additions, sec.Fallback)
+		}
+	}
+	return s + strings.Join(additions, "")
+}
+
+// artifactCheck lists the sections a document must have. Tolerance is how
+// many may be missing before the document counts as malformed, mirroring
+// the leniency of the matching ensure* helper.
+type artifactCheck struct {
+	Sections  []section
+	Tolerance int
+}
+
+// artifactChecks holds the section requirements per output. Outputs not
+// listed only need to exist and be non-empty.
+var artifactChecks = map[string]artifactCheck{
+	"srs.md":          {Sections: srsSections, Tolerance: 1},
+	"architecture.md": {Sections: architectureSections},
+	"uml.md":          {Sections: umlSections},
+	"test-plan.md":    {Sections: testPlanSections},
+	"entities.md":     {Sections: entitySections},
+	"repository.md":   {Sections: repositorySections},
+}
+
+// ArtifactProblem describes one output that failed verification.
+type ArtifactProblem struct {
+	Path    string
+	Problem string   // "missing", "empty" or "missing sections"
+	Missing []string // section keys, when Problem is "missing sections"
+}
+
+func (p ArtifactProblem) String() string {
+	if len(p.Missing) > 0 {
+		return fmt.Sprintf("%s: %s (%s)", p.Path, p.Problem, strings.Join(p.Missing, ", "))
+	}
+	return fmt.Sprintf("%s: %s", p.Path, p.Problem)
+}
+
+// OutputError reports every declared output of a stage that was not
+// produced correctly.
+type OutputError struct {
+	Stage    string
+	Problems []ArtifactProblem
+}
+
+func (e *OutputError) Error() string {
+	parts := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		parts[i] = p.String()
+	}
+	return fmt.Sprintf("%s: %v: %s", e.Stage, ErrInvalidOutputs, strings.Join(parts, "; "))
+}
+
+func (e *OutputError) Unwrap() error { return ErrInvalidOutputs }
+
+// verifyOutputs checks that every output stageSpecs declares for stage
+// exists under outputDir, is non-empty and has its required sections.
+// Directory outputs must contain at least one file.
+func verifyOutputs(stage, outputDir string) error {
+	var problems []ArtifactProblem
+	for _, out := range stageSpecs[stage].Outputs {
+		path := filepath.Join(outputDir, out)
+		info, err := os.Stat(path)
+		if err != nil {
+			problems = append(problems, ArtifactProblem{Path: path, Problem: "missing"})
+			continue
+		}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil || len(entries) == 0 {
+				problems = append(problems, ArtifactProblem{Path: path, Problem: "empty"})
+			}
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			problems = append(problems, ArtifactProblem{Path: path, Problem: "empty"})
+			continue
+		}
+		check, ok := artifactChecks[out]
+		if !ok {
+			continue
+		}
+		if missing := missingSections(string(data), check.Sections); len(missing) > check.Tolerance {
+			problems = append(problems, ArtifactProblem{Path: path, Problem: "missing sections", Missing: missing})
+		}
+	}
+	if len(problems) > 0 {
+		return &OutputError{Stage: stage, Problems: problems}
+	}
+	return nil
+}
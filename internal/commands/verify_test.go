@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agentflow/internal/agents"
+)
+
+func TestVerifyOutputs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := verifyOutputs("plan", dir); !errors.Is(err, ErrInvalidOutputs) {
+		t.Fatalf("expected ErrInvalidOutputs for missing outputs, got %v", err)
+	}
+
+	// One missing SRS section is tolerated, as in ensureSRS.
+	write("srs.md", "## Use Cases\n## Interfaces")
+	write("stories.md", "   \n")
+	write("acceptance_criteria.md", "## STORY-1.1")
+	err := verifyOutputs("plan", dir)
+	var oe *OutputError
+	if !errors.As(err, &oe) || len(oe.Problems) != 1 || oe.Problems[0].Problem != "empty" || !strings.HasSuffix(oe.Problems[0].Path, "stories.md") {
+		t.Fatalf("expected only stories.md to be reported empty, got %v", err)
+	}
+
+	write("architecture.md", "# Architecture\n\n## Components")
+	err = verifyOutputs("design", dir)
+	if !errors.As(err, &oe) || len(oe.Problems[0].Missing) != 2 {
+		t.Fatalf("expected missing architecture sections, got %v", err)
+	}
+
+	write("task_list.md", "- [ ] TASK-001")
+	if err := os.MkdirAll(filepath.Join(dir, "tasks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyOutputs("devplan", dir); !errors.Is(err, ErrInvalidOutputs) {
+		t.Fatalf("expected empty tasks directory to fail, got %v", err)
+	}
+	write("tasks/TASK-001.md", "<task>\nScaffold\n</task>")
+	if err := verifyOutputs("devplan", dir); err != nil {
+		t.Fatalf("expected devplan outputs to verify, got %v", err)
+	}
+}
+
+func TestIntake_ReportsFailures(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := createTestConfig(t, tempDir)
+	if err := os.WriteFile(filepath.Join(tempDir, "notes.md"), []byte("# notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	provider := agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"intake": {Error: "model unavailable"},
+	}})
+	err := Intake(context.Background(), IntakeOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider})
+	if err == nil || !strings.Contains(err.Error(), "model unavailable") {
+		t.Fatalf("expected agent error to surface, got %v", err)
+	}
+
+	provider = agents.NewMockProvider(agents.MockProviderOptions{Scripts: map[string]agents.MockScript{
+		"intake": {Output: "done"},
+	}})
+	err = Intake(context.Background(), IntakeOptions{ConfigPath: configPath, OutputDir: tempDir, Provider: provider})
+	if !errors.Is(err, ErrInvalidOutputs) {
+		t.Fatalf("expected missing requirements.md to fail, got %v", err)
+	}
+}
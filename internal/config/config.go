@@ -8,34 +8,60 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config mirrors the schema described in docs.
 type Config struct {
-	SchemaVersion string `json:"schemaVersion"`
-	ProjectName   string `json:"projectName"`
-	LLM           struct {
-		Model       string  `json:"model"`
-		Temperature float64 `json:"temperature"`
-		MaxTokens   int     `json:"maxTokens"`
-	} `json:"llm"`
-	Roles map[string]string `json:"roles"`
-	IO    struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	ProjectName   string            `json:"projectName"`
+	LLM           LLMConfig         `json:"llm"`
+	Roles         map[string]string `json:"roles"`
+	IO            struct {
 		InputDir  string `json:"inputDir"`
 		OutputDir string `json:"outputDir"`
 	} `json:"io"`
 	Security struct {
 		EnvKeys []string `json:"envKeys"`
+		// MaxFileBytes caps a single file_reader/file_creator transfer;
+		// 0 uses the built-in default.
+		MaxFileBytes int64 `json:"maxFileBytes,omitempty"`
 	} `json:"security"`
+	// Redact controls what is replaced by placeholders before prompts and
+	// file_reader results reach the model: API keys and the values of the
+	// secret env keys (Secrets), and emails, phone numbers and Thai national
+	// IDs (PII).
 	Redact struct {
 		Secrets bool `json:"secrets"`
+		PII     bool `json:"pii"`
 	} `json:"redact"`
 	DevPlan struct {
 		MaxContextCharsPerTask int `json:"maxContextCharsPerTask"`
 	} `json:"devplan"`
+	// AskHuman.Mode decides how agent questions reach a person: prompted
+	// on the terminal ("interactive"), collected in questions.md ("file"),
+	// or not at all ("off"), in which case agents state assumptions.
 	AskHuman struct {
 		Mode string `json:"mode"`
 	} `json:"askHuman"`
+	// Pricing maps model names to their prices, used to cost agent runs.
+	// A model without an exact entry uses the longest key it starts with,
+	// so "gpt-5" also prices "gpt-5-2025-08-07".
+	Pricing map[string]ModelPrice `json:"pricing,omitempty"`
+	// Budget caps what agent runs may spend. Zero values mean no limit. A
+	// run that crosses a per-run limit is stopped; once the recorded runs
+	// have spent MaxCostPerProject no new run starts.
+	Budget struct {
+		MaxTokensPerRun   int64   `json:"maxTokensPerRun,omitempty"`
+		MaxCostPerRun     float64 `json:"maxCostPerRun,omitempty"`
+		MaxCostPerProject float64 `json:"maxCostPerProject,omitempty"`
+	} `json:"budget"`
+	// Stages holds per-stage settings, keyed by command name.
+	Stages map[string]StageConfig `json:"stages,omitempty"`
+	// Export configures `agentflow export tasks --push`.
+	Export struct {
+		GitHub GitHubExportConfig `json:"github,omitzero"`
+	} `json:"export,omitzero"`
 	Metadata struct {
 		Owner string   `json:"owner"`
 		Repo  string   `json:"repo"`
@@ -43,6 +69,175 @@ type Config struct {
 	} `json:"metadata"`
 }
 
+// GitHubExportConfig points task pushes at a GitHub-compatible issues API.
+// BaseURL defaults to https://api.github.com, Repo is "owner/name", and the
+// token is read from the TokenEnv variable (GITHUB_TOKEN when empty).
+// Labels are added to every pushed issue.
+type GitHubExportConfig struct {
+	BaseURL  string   `json:"baseURL,omitempty"`
+	Repo     string   `json:"repo,omitempty"`
+	TokenEnv string   `json:"tokenEnv,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// GitHubToken returns the token for task pushes.
+func (g GitHubExportConfig) GitHubToken() string {
+	return strings.TrimSpace(os.Getenv(g.tokenEnv()))
+}
+
+func (g GitHubExportConfig) tokenEnv() string {
+	if k := strings.TrimSpace(g.TokenEnv); k != "" {
+		return k
+	}
+	return "GITHUB_TOKEN"
+}
+
+// LLMConfig selects the model backend and the default model parameters.
+// Provider is one of "openai" (default), "openai-compatible" or "mock". The
+// openai-compatible provider talks to any server exposing the OpenAI Chat
+// Completions API (vLLM, Ollama, LiteLLM, ...) at BaseURL, sending Headers on
+// every request. The mock provider replays scripted runs from Fixtures and
+// never touches the network.
+type LLMConfig struct {
+	Provider    string            `json:"provider,omitempty"`
+	BaseURL     string            `json:"baseURL,omitempty"`
+	APIKeyEnv   string            `json:"apiKeyEnv,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Fixtures    string            `json:"fixtures,omitempty"`
+	Model       string            `json:"model"`
+	Temperature float64           `json:"temperature"`
+	MaxTokens   int               `json:"maxTokens"`
+	// PerCommand overrides model parameters for individual commands, keyed
+	// by command name (e.g. "plan"). Unset fields inherit from llm.*.
+	PerCommand map[string]LLMOverride `json:"perCommand,omitempty"`
+	// Retry controls how failed model requests are retried: rate limits,
+	// server errors and network failures are retried with exponential
+	// backoff; other errors fail at once. Zero values use the defaults.
+	Retry RetryConfig `json:"retry,omitzero"`
+	// RateLimit paces model requests across the whole process. Zero
+	// RequestsPerMinute means no limit.
+	RateLimit RateLimitConfig `json:"rateLimit,omitzero"`
+}
+
+// RetryConfig bounds retries of a single model request. MaxAttempts counts
+// the first request, so 1 disables retries; 0 uses the default of 4.
+// MaxRetryAfter caps the wait a server may ask for; 0 uses the default of
+// 2m.
+type RetryConfig struct {
+	MaxAttempts    int      `json:"maxAttempts,omitempty"`
+	InitialBackoff Duration `json:"initialBackoff,omitempty"`
+	MaxBackoff     Duration `json:"maxBackoff,omitempty"`
+	MaxRetryAfter  Duration `json:"maxRetryAfter,omitempty"`
+}
+
+// RateLimitConfig is a token bucket: RequestsPerMinute on average, with
+// bursts of up to Burst requests.
+type RateLimitConfig struct {
+	RequestsPerMinute float64 `json:"requestsPerMinute,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+}
+
+// LLMOverride holds per-command model parameters. Temperature is a pointer
+// so that an explicit 0 can be told apart from "not set".
+type LLMOverride struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"maxTokens,omitempty"`
+}
+
+// StageConfig holds the settings of one stage. Timeout bounds the stage's
+// agent run; zero means no limit.
+type StageConfig struct {
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+// Duration is a time.Duration that reads and writes JSON as a Go duration
+// string, such as "90s" or "10m".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// StageTimeout returns the timeout configured for stage, or 0 for none.
+func (c *Config) StageTimeout(stage string) time.Duration {
+	return time.Duration(c.Stages[stage].Timeout)
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	InputPerMTok  float64 `json:"inputPerMTok"`
+	OutputPerMTok float64 `json:"outputPerMTok"`
+}
+
+// Cost returns the price of the given token counts.
+func (p ModelPrice) Cost(inputTokens, outputTokens int64) float64 {
+	return (float64(inputTokens)*p.InputPerMTok + float64(outputTokens)*p.OutputPerMTok) / 1e6
+}
+
+// Price looks up model in c.Pricing. ok is false for unpriced models.
+func (c *Config) Price(model string) (price ModelPrice, ok bool) {
+	if p, found := c.Pricing[model]; found {
+		return p, true
+	}
+	best := ""
+	for name, p := range c.Pricing {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best, price, ok = name, p, true
+		}
+	}
+	return price, ok
+}
+
+// Commands lists the generating commands that accept per-command settings.
+var Commands = []string{"intake", "plan", "design", "uml", "qa", "entity", "repo", "devplan"}
+
+// For returns the LLM settings to use for the given command, with any
+// llm.perCommand override applied on top of the global values.
+func (l LLMConfig) For(command string) LLMConfig {
+	o, ok := l.PerCommand[command]
+	if !ok {
+		return l
+	}
+	if strings.TrimSpace(o.Model) != "" {
+		l.Model = strings.TrimSpace(o.Model)
+	}
+	if o.Temperature != nil {
+		l.Temperature = *o.Temperature
+	}
+	if o.MaxTokens > 0 {
+		l.MaxTokens = o.MaxTokens
+	}
+	return l
+}
+
+// Supported values for LLMConfig.Provider.
+const (
+	ProviderOpenAI           = "openai"
+	ProviderOpenAICompatible = "openai-compatible"
+	ProviderMock             = "mock"
+)
+
+// Supported values for Config.AskHuman.Mode.
+const (
+	AskHumanInteractive = "interactive"
+	AskHumanFile        = "file"
+	AskHumanOff         = "off"
+)
+
 // DefaultConfig constructs a Config with sensible defaults for the given
 // project name and LLM model. Call ApplyEnv to allow environment variables
 // to override specific fields.
@@ -50,27 +245,35 @@ func DefaultConfig(projectName, model string) *Config {
 	c := &Config{}
 	c.SchemaVersion = "0.1"
 	c.ProjectName = projectName
+	c.LLM.Provider = ProviderOpenAI
 	c.LLM.Model = model
 	c.LLM.Temperature = 0.2
 	c.LLM.MaxTokens = 4000
-	c.Roles = map[string]string{
-		"po_pm": "You are a PO/PM. Convert input context into formal requirements with sections: Goals, Scope, FR, NFR, Assumptions, Open Questions.",
-		"sa":    "You are a Solution Architect. Transform requirements into SRS/Stories/AC.",
-		"qa":    "You are a QA Lead. Produce a concise test plan.",
-		"dev":   "You are a Tech Lead. Produce dev task list and per-task context.",
-	}
+	c.Roles = DefaultRoles()
 	c.IO.InputDir = ".agentflow/input"
 	c.IO.OutputDir = ".agentflow/output"
 	c.Security.EnvKeys = []string{"OPENAI_API_KEY"}
 	c.Redact.Secrets = true
+	c.Redact.PII = true
 	c.DevPlan.MaxContextCharsPerTask = 4000
-	c.AskHuman.Mode = "interactive"
+	c.AskHuman.Mode = AskHumanInteractive
 	c.Metadata.Owner = ""
 	c.Metadata.Repo = ""
 	c.Metadata.Tags = []string{}
 	return c
 }
 
+// DefaultRoles returns the built-in persona prompts keyed by role name. The
+// roles section of config.json may override these or add new ones.
+func DefaultRoles() map[string]string {
+	return map[string]string{
+		"po_pm": "You are a PO/PM. Convert input context into formal requirements with sections: Goals, Scope, FR, NFR, Assumptions, Open Questions.",
+		"sa":    "You are a Solution Architect. Transform requirements into SRS/Stories/AC.",
+		"qa":    "You are a QA Lead. Produce a concise test plan.",
+		"dev":   "You are a Tech Lead. Produce dev task list and per-task context.",
+	}
+}
+
 // EnsureDirs creates the directory that will hold the config file as well as
 // the input/output directories referenced by the provided Config. If c is nil,
 // only the parent directory of configPath is created.
@@ -134,6 +337,15 @@ func (c *Config) Validate() error {
 	if strings.TrimSpace(c.ProjectName) == "" {
 		return errors.New("projectName is required")
 	}
+	switch strings.TrimSpace(c.LLM.Provider) {
+	case "", ProviderOpenAI, ProviderMock:
+	case ProviderOpenAICompatible:
+		if strings.TrimSpace(c.LLM.BaseURL) == "" {
+			return fmt.Errorf("llm.baseURL is required for provider %q", c.LLM.Provider)
+		}
+	default:
+		return fmt.Errorf("unsupported llm.provider: %s", c.LLM.Provider)
+	}
 	if strings.TrimSpace(c.LLM.Model) == "" {
 		return errors.New("llm.model is required")
 	}
@@ -143,20 +355,97 @@ func (c *Config) Validate() error {
 	if c.LLM.MaxTokens <= 0 {
 		return fmt.Errorf("llm.maxTokens must be > 0")
 	}
+	if r := c.LLM.Retry; r.MaxAttempts < 0 || r.InitialBackoff < 0 || r.MaxBackoff < 0 || r.MaxRetryAfter < 0 {
+		return fmt.Errorf("llm.retry values must be >= 0")
+	}
+	if r := c.LLM.Retry; r.InitialBackoff > 0 && r.MaxBackoff > 0 && r.InitialBackoff > r.MaxBackoff {
+		return fmt.Errorf("llm.retry.initialBackoff must not exceed maxBackoff")
+	}
+	if rl := c.LLM.RateLimit; rl.RequestsPerMinute < 0 || rl.Burst < 0 {
+		return fmt.Errorf("llm.rateLimit values must be >= 0")
+	}
+	for name, o := range c.LLM.PerCommand {
+		if !isCommand(name) {
+			return fmt.Errorf("llm.perCommand: unknown command %q", name)
+		}
+		if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+			return fmt.Errorf("llm.perCommand.%s.temperature out of range [0,2]: %v", name, *o.Temperature)
+		}
+		if o.MaxTokens < 0 {
+			return fmt.Errorf("llm.perCommand.%s.maxTokens must be >= 0", name)
+		}
+	}
+	for name, prompt := range c.Roles {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("roles: role name must not be empty")
+		}
+		if strings.TrimSpace(prompt) == "" {
+			return fmt.Errorf("roles.%s: instructions must not be empty", name)
+		}
+	}
+	switch strings.TrimSpace(c.AskHuman.Mode) {
+	case "", AskHumanInteractive, AskHumanFile, AskHumanOff:
+	default:
+		return fmt.Errorf("unsupported askHuman.mode: %s", c.AskHuman.Mode)
+	}
+	for model, p := range c.Pricing {
+		if p.InputPerMTok < 0 || p.OutputPerMTok < 0 {
+			return fmt.Errorf("pricing.%s: prices must be >= 0", model)
+		}
+	}
+	if c.Budget.MaxTokensPerRun < 0 || c.Budget.MaxCostPerRun < 0 || c.Budget.MaxCostPerProject < 0 {
+		return fmt.Errorf("budget limits must be >= 0")
+	}
+	for name, st := range c.Stages {
+		if !isCommand(name) {
+			return fmt.Errorf("stages: unknown stage %q", name)
+		}
+		if st.Timeout < 0 {
+			return fmt.Errorf("stages.%s.timeout must be >= 0", name)
+		}
+	}
+	if c.Security.MaxFileBytes < 0 {
+		return fmt.Errorf("security.maxFileBytes must be >= 0")
+	}
 	if strings.TrimSpace(c.IO.InputDir) == "" || strings.TrimSpace(c.IO.OutputDir) == "" {
 		return fmt.Errorf("io.inputDir and io.outputDir are required")
 	}
 	return nil
 }
 
+func isCommand(name string) bool {
+	for _, c := range Commands {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ApplyEnv overrides configuration fields from environment variables if set.
 // Supported variables:
+// - AGENTFLOW_PROVIDER → llm.provider
+// - AGENTFLOW_BASE_URL → llm.baseURL
+// - AGENTFLOW_FIXTURES → llm.fixtures
+// - AGENTFLOW_ASK_HUMAN → askHuman.mode
 // - AGENTFLOW_MODEL → llm.model
 // - AGENTFLOW_TEMPERATURE → llm.temperature (float)
 // - AGENTFLOW_MAX_TOKENS → llm.maxTokens (int)
 // - AGENTFLOW_INPUT_DIR → io.inputDir
 // - AGENTFLOW_OUTPUT_DIR → io.outputDir
 func (c *Config) ApplyEnv() {
+	if v := strings.TrimSpace(os.Getenv("AGENTFLOW_PROVIDER")); v != "" {
+		c.LLM.Provider = v
+	}
+	if v := strings.TrimSpace(os.Getenv("AGENTFLOW_BASE_URL")); v != "" {
+		c.LLM.BaseURL = v
+	}
+	if v := strings.TrimSpace(os.Getenv("AGENTFLOW_FIXTURES")); v != "" {
+		c.LLM.Fixtures = v
+	}
+	if v := strings.TrimSpace(os.Getenv("AGENTFLOW_ASK_HUMAN")); v != "" {
+		c.AskHuman.Mode = v
+	}
 	if v := strings.TrimSpace(os.Getenv("AGENTFLOW_MODEL")); v != "" {
 		c.LLM.Model = v
 	}
@@ -178,14 +467,13 @@ func (c *Config) ApplyEnv() {
 	}
 }
 
-// RedactedEnv returns a map of configured secret env keys with redacted values
-// Useful for safe logging/spec dumps without leaking secrets.
-func (c *Config) RedactedEnv() map[string]string {
-	m := map[string]string{}
+// SecretEnvKeys returns the names of the environment variables that hold
+// secrets: security.envKeys, llm.apiKeyEnv, OPENAI_API_KEY (used by the
+// OpenAI client), the legacy LANGGRAPH_API_KEY and the export token.
+func (c *Config) SecretEnvKeys() []string {
 	keys := append([]string{}, c.Security.EnvKeys...)
-	// Include OPENAI_API_KEY (used by OpenAI client) and legacy LANGGRAPH_API_KEY for backward compatibility
-	keys = append(keys, "OPENAI_API_KEY")
-	keys = append(keys, "LANGGRAPH_API_KEY")
+	keys = append(keys, c.LLM.APIKeyEnv, "OPENAI_API_KEY", "LANGGRAPH_API_KEY", c.Export.GitHub.tokenEnv())
+	var out []string
 	seen := map[string]bool{}
 	for _, k := range keys {
 		k = strings.TrimSpace(k)
@@ -193,6 +481,16 @@ func (c *Config) RedactedEnv() map[string]string {
 			continue
 		}
 		seen[k] = true
+		out = append(out, k)
+	}
+	return out
+}
+
+// RedactedEnv returns a map of configured secret env keys with redacted values
+// Useful for safe logging/spec dumps without leaking secrets.
+func (c *Config) RedactedEnv() map[string]string {
+	m := map[string]string{}
+	for _, k := range c.SecretEnvKeys() {
 		if _, ok := os.LookupEnv(k); ok {
 			m[k] = "***"
 		}
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfigAndSaveLoad(t *testing.T) {
@@ -104,6 +106,56 @@ func TestValidateConstraints(t *testing.T) {
 	}
 }
 
+func TestValidateProvider(t *testing.T) {
+	c := DefaultConfig("Demo", "gpt-4o-mini")
+	c.LLM.Provider = ProviderOpenAICompatible
+	if err := c.Validate(); err == nil {
+		t.Fatalf("expected baseURL required error")
+	}
+	c.LLM.BaseURL = "http://localhost:8000/v1"
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validate error: %v", err)
+	}
+	c.LLM.Provider = "anthropic-direct"
+	if err := c.Validate(); err == nil {
+		t.Fatalf("expected unsupported provider error")
+	}
+}
+
+func TestPerCommandOverrides(t *testing.T) {
+	c := DefaultConfig("Demo", "gpt-4o-mini")
+	zero := 0.0
+	c.LLM.PerCommand = map[string]LLMOverride{
+		"plan": {Model: "gpt-5", Temperature: &zero, MaxTokens: 8000},
+		"qa":   {MaxTokens: 2000},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected validate error: %v", err)
+	}
+	plan := c.LLM.For("plan")
+	if plan.Model != "gpt-5" || plan.Temperature != 0 || plan.MaxTokens != 8000 {
+		t.Fatalf("plan override not applied: %+v", plan)
+	}
+	qa := c.LLM.For("qa")
+	if qa.Model != "gpt-4o-mini" || qa.Temperature != 0.2 || qa.MaxTokens != 2000 {
+		t.Fatalf("qa override not applied: %+v", qa)
+	}
+	if got := c.LLM.For("design"); got.Model != "gpt-4o-mini" || got.MaxTokens != 4000 {
+		t.Fatalf("design should inherit globals: %+v", got)
+	}
+
+	c.LLM.PerCommand["deploy"] = LLMOverride{Model: "x"}
+	if err := c.Validate(); err == nil {
+		t.Fatalf("expected unknown command error")
+	}
+	delete(c.LLM.PerCommand, "deploy")
+	hot := 2.5
+	c.LLM.PerCommand["uml"] = LLMOverride{Temperature: &hot}
+	if err := c.Validate(); err == nil {
+		t.Fatalf("expected per-command temperature range error")
+	}
+}
+
 func TestRedactedEnv(t *testing.T) {
 	c := DefaultConfig("Demo", "gpt-4o-mini")
 	t.Setenv("OPENAI_API_KEY", "secret1")
@@ -116,3 +168,103 @@ func TestRedactedEnv(t *testing.T) {
 		t.Fatalf("expected LANGGRAPH_API_KEY to be redacted")
 	}
 }
+
+func TestValidateAskHumanMode(t *testing.T) {
+	c := DefaultConfig("Demo", "gpt-4o-mini")
+	for _, mode := range []string{"", AskHumanInteractive, AskHumanFile, AskHumanOff} {
+		c.AskHuman.Mode = mode
+		if err := c.Validate(); err != nil {
+			t.Fatalf("mode %q: unexpected validate error: %v", mode, err)
+		}
+	}
+	c.AskHuman.Mode = "email"
+	if err := c.Validate(); err == nil {
+		t.Fatalf("expected unsupported askHuman.mode error")
+	}
+}
+
+func TestPricingAndBudget(t *testing.T) {
+	c := DefaultConfig("Demo", "gpt-5")
+	c.Pricing = map[string]ModelPrice{
+		"gpt-5":      {InputPerMTok: 1.25, OutputPerMTok: 10},
+		"gpt-5-mini": {InputPerMTok: 0.25, OutputPerMTok: 2},
+	}
+	if p, ok := c.Price("gpt-5-mini-2025-08-07"); !ok || p.InputPerMTok != 0.25 {
+		t.Fatalf("longest prefix should win, got %+v %v", p, ok)
+	}
+	p, ok := c.Price("gpt-5")
+	if !ok {
+		t.Fatal("exact match not found")
+	}
+	if got := p.Cost(1_000_000, 100_000); got != 2.25 {
+		t.Fatalf("Cost = %v, want 2.25", got)
+	}
+	if _, ok := c.Price("llama3"); ok {
+		t.Fatal("unpriced model reported as priced")
+	}
+	c.Budget.MaxCostPerRun = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected negative budget error")
+	}
+	c.Budget.MaxCostPerRun = 0
+	c.Pricing["gpt-5"] = ModelPrice{InputPerMTok: -1}
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected negative price error")
+	}
+}
+
+func TestStageTimeout(t *testing.T) {
+	var c Config
+	if err := json.Unmarshal([]byte(`{"stages": {"plan": {"timeout": "90s"}}}`), &c); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.StageTimeout("plan"); got != 90*time.Second {
+		t.Fatalf("plan timeout = %v, want 90s", got)
+	}
+	if got := c.StageTimeout("qa"); got != 0 {
+		t.Fatalf("qa timeout = %v, want 0", got)
+	}
+	data, err := json.Marshal(c.Stages)
+	if err != nil || string(data) != `{"plan":{"timeout":"1m30s"}}` {
+		t.Fatalf("marshal = %s, %v", data, err)
+	}
+	if err := json.Unmarshal([]byte(`{"stages": {"plan": {"timeout": 90}}}`), &c); err == nil {
+		t.Fatal("expected error for numeric timeout")
+	}
+
+	d := DefaultConfig("Demo", "gpt-5")
+	d.Stages = map[string]StageConfig{"deploy": {Timeout: Duration(time.Minute)}}
+	if err := d.Validate(); err == nil {
+		t.Fatal("expected unknown stage error")
+	}
+	d.Stages = map[string]StageConfig{"plan": {Timeout: Duration(-time.Second)}}
+	if err := d.Validate(); err == nil {
+		t.Fatal("expected negative timeout error")
+	}
+}
+
+func TestValidateRetryAndRateLimit(t *testing.T) {
+	c := DefaultConfig("Demo", "gpt-5")
+	c.LLM.Retry = RetryConfig{MaxAttempts: 5, InitialBackoff: Duration(time.Second), MaxBackoff: Duration(time.Minute)}
+	c.LLM.RateLimit = RateLimitConfig{RequestsPerMinute: 60, Burst: 5}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("valid retry settings: %v", err)
+	}
+	if got := c.LLM.For("plan").Retry.MaxAttempts; got != 5 {
+		t.Fatalf("per-command settings should keep llm.retry, got maxAttempts %d", got)
+	}
+	c.LLM.Retry.InitialBackoff = Duration(2 * time.Minute)
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected initialBackoff > maxBackoff error")
+	}
+	c.LLM.Retry.InitialBackoff = 0
+	c.LLM.Retry.MaxRetryAfter = Duration(-time.Second)
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected negative maxRetryAfter error")
+	}
+	c.LLM.Retry.MaxRetryAfter = 0
+	c.LLM.RateLimit.Burst = -1
+	if err := c.Validate(); err == nil {
+		t.Fatal("expected negative burst error")
+	}
+}
@@ -0,0 +1,114 @@
+// Package diagram finds the fenced PlantUML and Mermaid blocks in the
+// generated Markdown documents and checks that they will render: paired
+// @start/@end markers, a known Mermaid diagram type, no forbidden
+// characters and balanced brackets.
+package diagram
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Diagram languages, as written after the opening fence.
+const (
+	PlantUML = "plantuml"
+	Mermaid  = "mermaid"
+)
+
+// langs maps fence info strings to a diagram language.
+var langs = map[string]string{"plantuml": PlantUML, "puml": PlantUML, "mermaid": Mermaid}
+
+// Block is one fenced diagram.
+type Block struct {
+	Lang    string
+	Code    string
+	Line    int    // line of the opening fence, 1-based
+	Heading string // text of the nearest heading above the block, if any
+	Closed  bool   // false when the fence runs to the end of the document
+}
+
+var (
+	fenceRe   = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+	headingRe = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+)
+
+// Extract returns the PlantUML and Mermaid blocks of a Markdown document in
+// order. Other fenced blocks are skipped, and headings inside them are not
+// taken for section headings.
+func Extract(doc string) []Block {
+	var blocks []Block
+	lines := strings.Split(doc, "\n")
+	heading := ""
+	for i := 0; i < len(lines); i++ {
+		m := fenceRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			if h := headingRe.FindStringSubmatch(lines[i]); h != nil {
+				heading = h[1]
+			}
+			continue
+		}
+		fence := m[1]
+		lang, isDiagram := langs[strings.ToLower(m[2])]
+		start := i
+		var code []string
+		closed := false
+		for i++; i < len(lines); i++ {
+			t := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+				closed = true
+				break
+			}
+			code = append(code, lines[i])
+		}
+		if isDiagram {
+			blocks = append(blocks, Block{Lang: lang, Code: strings.Join(code, "\n"), Line: start + 1, Heading: heading, Closed: closed})
+		}
+	}
+	return blocks
+}
+
+// Problem is one lint finding.
+type Problem struct {
+	Path    string
+	Line    int // line in the document, 1-based
+	Lang    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.Path, p.Line, p.Lang, p.Message)
+}
+
+// Lint checks every diagram in doc, read from path.
+func Lint(path, doc string) []Problem {
+	var problems []Problem
+	for _, b := range Extract(doc) {
+		for _, p := range LintBlock(b) {
+			p.Path = path
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// LintFile checks the diagrams in the document at path.
+func LintFile(path string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Lint(path, string(data)), nil
+}
+
+// FormatRepair asks the agent to fix the listed problems, for the repair
+// pass after a stage.
+func FormatRepair(problems []Problem) string {
+	var b strings.Builder
+	b.WriteString("The diagrams in the documents you wrote do not pass lint. Fix every problem below and write the corrected documents again with file_creator. Change only the broken diagrams; keep the rest of each document as it is. In Mermaid, parentheses may appear inside quoted labels and in class diagram method signatures, nowhere else.\n\n")
+	for _, p := range problems {
+		fmt.Fprintf(&b, "- %s\n", p)
+	}
+	return b.String()
+}
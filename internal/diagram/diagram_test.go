@@ -0,0 +1,190 @@
+package diagram
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fenced(lang, code string) string { return "```" + lang + "\n" + code + "\n```\n" }
+
+func TestExtract(t *testing.T) {
+	doc := "# Design\n\n## Components\n\n" + fenced("mermaid", "flowchart LR\n  A --> B") +
+		"\n```go\n// # not a heading\n```\n\n### Data model\n" + fenced("PlantUML", "@startuml\nclass A\n@enduml") +
+		"~~~~puml\n@startuml\n~~~\n"
+	blocks := Extract(doc)
+	if len(blocks) != 3 {
+		t.Fatalf("blocks: %+v", blocks)
+	}
+	want := []Block{
+		{Lang: Mermaid, Code: "flowchart LR\n  A --> B", Line: 5, Heading: "Components", Closed: true},
+		{Lang: PlantUML, Code: "@startuml\nclass A\n@enduml", Line: 15, Heading: "Data model", Closed: true},
+		{Lang: PlantUML, Code: "@startuml\n~~~\n", Line: 20, Heading: "Data model"},
+	}
+	for i, w := range want {
+		if blocks[i] != w {
+			t.Errorf("block %d = %+v, want %+v", i, blocks[i], w)
+		}
+	}
+}
+
+func TestLintAcceptsValidDiagrams(t *testing.T) {
+	for name, doc := range map[string]string{
+		"flowchart":    fenced("mermaid", "%% services\nflowchart TD\n  A[Web] -->|calls| B{API}\n  B --> C[\"DB [primary]\"]\n  D>Flag] --> A"),
+		"front matter": fenced("mermaid", "---\ntitle: Orders\n---\nsequenceDiagram\n  Alice->>Bob: Hi"),
+		"er":           fenced("mermaid", "erDiagram\n  CUSTOMER ||--o{ ORDER : places\n  ORDER }|..|{ LINE : has\n  CUSTOMER {\n    string id\n  }"),
+		"plantuml":     fenced("plantuml", "' two diagrams\n@startuml\nactor User\n(Login) --> [Auth]\n@enduml\n\n@startmindmap\n* root\n@endmindmap"),
+		"plantuml er":  fenced("plantuml", "@startuml\nentity Order {\n  id\n}\nCustomer ||--o{ Order\n@enduml"),
+		"scaffolds":    scaffoldDiagrams(),
+		"class":        fenced("mermaid", "classDiagram\n  class Order {\n    +String id\n    +total() Money\n  }"),
+		"quoted":       fenced("mermaid", "flowchart LR\n  A[\"Web (SPA)\"] --> B"),
+	} {
+		if p := Lint("doc.md", doc); len(p) != 0 {
+			t.Errorf("%s: unexpected problems %v", name, p)
+		}
+	}
+}
+
+// scaffoldDiagrams mirrors the PlantUML fallbacks the commands write.
+func scaffoldDiagrams() string {
+	return fenced("plantuml", "@startuml\nclass Entity {\n  +id: string\n  +method()\n}\n@enduml") +
+		fenced("plantuml", "@startuml\nstart\n:Process Request;\nstop\n@enduml")
+}
+
+func TestLintFindsProblems(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"missing start", fenced("plantuml", "class A\n@enduml"), []string{
+			"doc.md:3: plantuml: @enduml without a matching @startuml",
+			"doc.md:2: plantuml: missing @startuml",
+		}},
+		{"unclosed start", fenced("plantuml", "@startuml\nA -> B"), []string{
+			"doc.md:2: plantuml: @startuml is never closed with @enduml",
+		}},
+		{"mismatched end", "text\n" + fenced("puml", "@startuml\nA -> B\n@endmindmap"), []string{
+			"doc.md:5: plantuml: @endmindmap closes @startuml from line 3",
+		}},
+		{"content outside", fenced("plantuml", "title X\n@startuml\n@enduml\nA -> B"), []string{
+			"doc.md:5: plantuml: content after @end; diagrams must sit between @start and @end",
+			"doc.md:2: plantuml: content before @start",
+		}},
+		{"unknown type", fenced("mermaid", "grph TD\n  A --> B"), []string{
+			`doc.md:2: mermaid: unknown diagram type "grph"`,
+		}},
+		{"plantuml in mermaid", fenced("mermaid", "@startuml\nA -> B\n@enduml"), []string{
+			"doc.md:2: mermaid: PlantUML @startuml in a mermaid block",
+		}},
+		{"parentheses", fenced("mermaid", "flowchart LR\n  A[Start] --> B(Round)"), []string{
+			"doc.md:3: mermaid: parentheses are not allowed in Mermaid; use [] or {} instead",
+		}},
+		{"invisible", fenced("mermaid", "flowchart LR\n  A\u00a0--> B"), []string{
+			"doc.md:3: mermaid: invisible character U+00A0 (no-break space)",
+		}},
+		{"brackets", fenced("mermaid", "flowchart LR\n  A[Web --> B\n  C} --> D"), []string{
+			"doc.md:4: mermaid: '}' closes '[' from line 3",
+		}},
+		{"unclosed bracket", fenced("plantuml", "@startuml\nclass A {\n@enduml"), []string{
+			"doc.md:3: plantuml: '{' is never closed",
+		}},
+		{"empty and unclosed fence", "```mermaid\n", []string{
+			"doc.md:1: mermaid: code fence is never closed",
+			"doc.md:1: mermaid: diagram is empty",
+		}},
+	} {
+		var got []string
+		for _, p := range Lint("doc.md", tc.doc) {
+			got = append(got, p.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tc.name, strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+		}
+	}
+}
+
+func TestFormatRepair(t *testing.T) {
+	msg := FormatRepair([]Problem{{Path: "uml.md", Line: 4, Lang: Mermaid, Message: "unknown diagram type \"grph\""}})
+	if !strings.Contains(msg, "file_creator") || !strings.Contains(msg, "- uml.md:4: mermaid: unknown diagram type \"grph\"\n") {
+		t.Fatalf("repair message:\n%s", msg)
+	}
+}
+
+func TestSlugAndFileName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Sequence: User Interactions": "sequence-user-interactions",
+		"  C4 -- Context (v2)  ":      "c4-context-v2",
+		"ภาพรวมระบบ":                  "ภาพรวมระบบ",
+		"!!!":                         "diagram",
+		strings.Repeat("a", 60):       strings.Repeat("a", maxSlug),
+	} {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := FileName("out/uml.md", 2, Block{Lang: PlantUML, Heading: "Class: Domain Models"}); got != "uml-2-class-domain-models.puml" {
+		t.Errorf("FileName = %q", got)
+	}
+}
+
+func TestExtractFiles(t *testing.T) {
+	dir := t.TempDir()
+	uml := filepath.Join(dir, "uml.md")
+	arch := filepath.Join(dir, "architecture.md")
+	writeDoc := func(path, doc string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(doc), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeDoc(uml, "# UML\n\n## Sequence: Login\n"+fenced("plantuml", "@startuml\nA -> B\n@enduml")+"\n## Class\n"+fenced("mermaid", "classDiagram\n  class A"))
+	writeDoc(arch, "# Architecture\n"+fenced("mermaid", "flowchart LR\n  A --> B(C)"))
+	out := filepath.Join(dir, "diagrams")
+
+	idx, err := ExtractFiles([]string{uml, arch}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{File: "uml-1-sequence-login.puml", Lang: PlantUML, Source: "uml.md", Section: "Sequence: Login", Index: 1, Line: 4},
+		{File: "uml-2-class.mmd", Lang: Mermaid, Source: "uml.md", Section: "Class", Index: 2, Line: 11},
+		{File: "architecture-1-architecture.mmd", Lang: Mermaid, Source: "architecture.md", Section: "Architecture", Index: 1, Line: 2,
+			Problems: []string{"line 4: parentheses are not allowed in Mermaid; use [] or {} instead"}},
+	}
+	if len(idx.Diagrams) != len(want) {
+		t.Fatalf("index: %+v", idx.Diagrams)
+	}
+	for i, w := range want {
+		if got := idx.Diagrams[i]; got.File != w.File || got.Lang != w.Lang || got.Source != w.Source || got.Section != w.Section ||
+			got.Index != w.Index || got.Line != w.Line || strings.Join(got.Problems, "|") != strings.Join(w.Problems, "|") {
+			t.Errorf("entry %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "uml-1-sequence-login.puml")); string(data) != "@startuml\nA -> B\n@enduml\n" {
+		t.Errorf("puml file = %q", data)
+	}
+	var onDisk Index
+	data, _ := os.ReadFile(filepath.Join(out, IndexFile))
+	if err := json.Unmarshal(data, &onDisk); err != nil || len(onDisk.Diagrams) != 3 {
+		t.Fatalf("index.json: %v\n%s", err, data)
+	}
+
+	// Re-extracting after a diagram was dropped removes its old file but
+	// leaves files the index never listed.
+	writeDoc(uml, "# UML\n\n## Sequence: Login\n"+fenced("plantuml", "@startuml\nA -> B\n@enduml"))
+	writeDoc(filepath.Join(out, "README.md"), "mine")
+	if _, err := ExtractFiles([]string{uml}, out); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(out)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got := strings.Join(names, ","); got != "README.md,index.json,uml-1-sequence-login.puml" {
+		t.Errorf("files after re-extract: %s", got)
+	}
+}
@@ -0,0 +1,143 @@
+package diagram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// IndexFile is written next to the extracted diagrams.
+const IndexFile = "index.json"
+
+// maxSlug caps the heading part of a file name, in runes.
+const maxSlug = 48
+
+// Slug turns a heading into a file name fragment: lower case, letters and
+// digits of any script kept, everything else collapsed into dashes.
+// "Sequence: User Interactions" becomes "sequence-user-interactions".
+func Slug(heading string) string {
+	var b strings.Builder
+	n, dash := 0, false
+	for _, r := range strings.ToLower(heading) {
+		if n >= maxSlug {
+			break
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+				n++
+			}
+			b.WriteRune(r)
+			n++
+			dash = false
+		default:
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "diagram"
+	}
+	return b.String()
+}
+
+// Ext returns the file extension for the block's language.
+func (b Block) Ext() string {
+	if b.Lang == PlantUML {
+		return ".puml"
+	}
+	return ".mmd"
+}
+
+// FileName names the n-th diagram (1-based) of the document doc, e.g.
+// "uml-2-class-domain-models.puml".
+func FileName(doc string, n int, b Block) string {
+	doc = strings.TrimSuffix(filepath.Base(doc), filepath.Ext(doc))
+	return fmt.Sprintf("%s-%d-%s%s", doc, n, Slug(b.Heading), b.Ext())
+}
+
+// Entry maps an extracted diagram back to where it came from.
+type Entry struct {
+	File     string   `json:"file"`    // name inside the diagrams directory
+	Lang     string   `json:"lang"`    // PlantUML or Mermaid
+	Source   string   `json:"source"`  // document name, e.g. "uml.md"
+	Section  string   `json:"section"` // nearest heading above the block
+	Index    int      `json:"index"`   // position among the document's diagrams, from 1
+	Line     int      `json:"line"`    // line of the opening fence in Source
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Index lists every extracted diagram, in document order.
+type Index struct {
+	Diagrams []Entry `json:"diagrams"`
+}
+
+// ExtractFiles writes each diagram of docs to its own file in dir and
+// writes dir/index.json. Files listed in a previous index are removed
+// first, so diagrams that were dropped from a document do not linger.
+// Diagrams that fail lint are still extracted, with their problems in the
+// index.
+func ExtractFiles(docs []string, dir string) (*Index, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := removeExtracted(dir); err != nil {
+		return nil, err
+	}
+	idx := &Index{Diagrams: []Entry{}}
+	for _, doc := range docs {
+		data, err := os.ReadFile(doc)
+		if err != nil {
+			return nil, err
+		}
+		source := filepath.Base(doc)
+		for n, b := range Extract(string(data)) {
+			e := Entry{File: FileName(source, n+1, b), Lang: b.Lang, Source: source, Section: b.Heading, Index: n + 1, Line: b.Line}
+			for _, p := range LintBlock(b) {
+				e.Problems = append(e.Problems, fmt.Sprintf("line %d: %s", p.Line, p.Message))
+			}
+			if err := os.WriteFile(filepath.Join(dir, e.File), []byte(strings.TrimRight(b.Code, "\n")+"\n"), 0o644); err != nil {
+				return nil, err
+			}
+			idx.Diagrams = append(idx.Diagrams, e)
+		}
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, IndexFile), append(data, '\n'), 0o644); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// removeExtracted deletes the files named in dir's index, if there is one.
+func removeExtracted(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var old Index
+	if err := json.Unmarshal(data, &old); err != nil {
+		return fmt.Errorf("read %s: %w", filepath.Join(dir, IndexFile), err)
+	}
+	for _, e := range old.Diagrams {
+		// Only plain diagram file names are ours; never follow a path out
+		// of dir.
+		if ext := filepath.Ext(e.File); e.File != filepath.Base(e.File) || ext != ".puml" && ext != ".mmd" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package diagram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// mermaidTypes are the diagram declarations Mermaid accepts on the first
+// line of a diagram.
+var mermaidTypes = map[string]bool{
+	"graph": true, "flowchart": true, "sequenceDiagram": true, "classDiagram": true,
+	"classDiagram-v2": true, "stateDiagram": true, "stateDiagram-v2": true,
+	"erDiagram": true, "journey": true, "gantt": true, "pie": true, "gitGraph": true,
+	"mindmap": true, "timeline": true, "quadrantChart": true, "requirementDiagram": true,
+	"C4Context": true, "C4Container": true, "C4Component": true, "C4Dynamic": true,
+	"C4Deployment": true, "sankey-beta": true, "xychart-beta": true, "block-beta": true,
+	"packet-beta": true, "architecture-beta": true, "kanban": true,
+}
+
+// invisible are characters that look like spaces, or like nothing, but
+// break both parsers.
+var invisible = map[rune]string{'\u00a0': "no-break space", '\u200b': "zero-width space", '\ufeff': "byte order mark"}
+
+var (
+	plantMarkerRe = regexp.MustCompile(`^@(start|end)(\w+)`)
+	// erCardinalityRe matches crow's-foot relationships such as ||--o{,
+	// whose braces are not brackets.
+	erCardinalityRe = regexp.MustCompile(`[|}][o|](?:--|\.\.)[o|][|{]`)
+	// asymmetricRe matches Mermaid's asymmetric node shape, id>label].
+	asymmetricRe = regexp.MustCompile(`\w>[^\[\]"]*\]`)
+	quotedRe     = regexp.MustCompile(`"[^"]*"`)
+)
+
+// LintBlock checks one diagram. Problem lines are lines of the document.
+func LintBlock(b Block) []Problem {
+	var problems []Problem
+	add := func(offset int, format string, args ...any) {
+		problems = append(problems, Problem{Line: b.Line + offset, Lang: b.Lang, Message: fmt.Sprintf(format, args...)})
+	}
+	if !b.Closed {
+		add(0, "code fence is never closed")
+	}
+	lines := strings.Split(b.Code, "\n")
+	if strings.TrimSpace(b.Code) == "" {
+		add(0, "diagram is empty")
+		return problems
+	}
+	comment := "%%"
+	if b.Lang == PlantUML {
+		comment = "'"
+		lintPlantUML(lines, b.Line, add)
+	} else {
+		lintMermaid(lines, add)
+	}
+	for i, line := range lines {
+		for _, r := range line {
+			if name, bad := invisible[r]; bad {
+				add(i+1, "invisible character U+%04X (%s)", r, name)
+				break
+			}
+		}
+	}
+	lintBrackets(b.Lang, lines, comment, b.Line, add)
+	return problems
+}
+
+// lintPlantUML checks that the diagram is one or more @startX ... @endX
+// pairs with nothing outside them. base is the document line of the fence.
+func lintPlantUML(lines []string, base int, add func(int, string, ...any)) {
+	open, openLine := "", 0
+	sawStart, strayReported := false, false
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		m := plantMarkerRe.FindStringSubmatch(strings.ToLower(t))
+		switch {
+		case m != nil && m[1] == "start":
+			if open != "" {
+				add(i+1, "@start%s inside @start%s from line %d", m[2], open, base+openLine)
+				continue
+			}
+			open, openLine, sawStart = m[2], i+1, true
+		case m != nil && m[1] == "end":
+			switch {
+			case open == "":
+				add(i+1, "@end%s without a matching @start%s", m[2], m[2])
+			case m[2] != open:
+				add(i+1, "@end%s closes @start%s from line %d", m[2], open, base+openLine)
+			}
+			open = ""
+		case open == "" && t != "" && !strings.HasPrefix(t, "'") && sawStart && !strayReported:
+			add(i+1, "content after @end; diagrams must sit between @start and @end")
+			strayReported = true
+		}
+	}
+	if !sawStart {
+		add(1, "missing @startuml")
+		return
+	}
+	if open != "" {
+		add(openLine, "@start%s is never closed with @end%s", open, open)
+	}
+	for i, line := range lines {
+		if t := strings.TrimSpace(line); t != "" && !strings.HasPrefix(t, "'") {
+			if !strings.HasPrefix(strings.ToLower(t), "@start") {
+				add(i+1, "content before @start")
+			}
+			break
+		}
+	}
+}
+
+// lintMermaid checks the diagram type and the characters this project's
+// prompts rule out: GitHub's renderer rejects parentheses in many diagram
+// types, so none are allowed outside quoted labels. Class diagrams are
+// exempt, as their method signatures need them.
+func lintMermaid(lines []string, add func(int, string, ...any)) {
+	// Skip a front matter block and comments to reach the declaration.
+	start := max(firstNonBlank(lines), 0)
+	if strings.TrimSpace(lines[start]) == "---" {
+		for start++; start < len(lines) && strings.TrimSpace(lines[start]) != "---"; start++ {
+		}
+		start++
+	}
+	header, kind := -1, ""
+	for i := start; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); t != "" && !strings.HasPrefix(t, "%%") {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		add(1, "missing diagram type")
+	} else {
+		kind = strings.Fields(lines[header])[0]
+		switch {
+		case strings.HasPrefix(strings.ToLower(kind), "@start"):
+			add(header+1, "PlantUML %s in a mermaid block", kind)
+		case !mermaidTypes[kind]:
+			add(header+1, "unknown diagram type %q", kind)
+		}
+	}
+	if strings.HasPrefix(kind, "classDiagram") {
+		return
+	}
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, "%%") {
+			continue
+		}
+		if strings.ContainsAny(quotedRe.ReplaceAllString(t, `""`), "()") {
+			add(i+1, "parentheses are not allowed in Mermaid; use [] or {} instead")
+		}
+	}
+}
+
+func firstNonBlank(lines []string) int {
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// lintBrackets checks that brackets pair up across the diagram, ignoring
+// comments, quoted labels and arrow syntax that uses bracket characters.
+// Mermaid parentheses are already reported by lintMermaid.
+func lintBrackets(lang string, lines []string, comment string, base int, add func(int, string, ...any)) {
+	pairs := map[rune]rune{']': '[', '}': '{'}
+	if lang == PlantUML {
+		pairs[')'] = '('
+	}
+	type open struct {
+		r    rune
+		line int
+	}
+	var stack []open
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if strings.HasPrefix(t, comment) {
+			continue
+		}
+		t = quotedRe.ReplaceAllString(t, `""`)
+		t = erCardinalityRe.ReplaceAllString(t, "--")
+		if lang == Mermaid {
+			t = asymmetricRe.ReplaceAllString(t, "")
+		}
+		for _, r := range t {
+			switch r {
+			case '[', '{', '(':
+				if r == '(' && lang != PlantUML {
+					continue
+				}
+				stack = append(stack, open{r, i + 1})
+			case ']', '}', ')':
+				want, ok := pairs[r]
+				if !ok {
+					continue
+				}
+				if len(stack) == 0 {
+					add(i+1, "unexpected %q", r)
+					continue
+				}
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if top.r != want {
+					add(i+1, "%q closes %q from line %d", r, top.r, base+top.line)
+				}
+			}
+		}
+	}
+	for _, o := range stack {
+		add(o.line, "%q is never closed", o.r)
+	}
+}
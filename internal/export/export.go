@@ -0,0 +1,278 @@
+// Package export turns the development plan into issues for a tracker:
+// GitHub Issues, a Jira CSV import or Linear JSON, and pushes them to a
+// GitHub-compatible issues API.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"agentflow/internal/taskgraph"
+	"agentflow/internal/tasks"
+)
+
+// Formats accepted by Write.
+const (
+	FormatGitHub     = "github"
+	FormatJiraCSV    = "jira-csv"
+	FormatLinearJSON = "linear-json"
+)
+
+// Issue is one task, ready to be rendered for a tracker.
+type Issue struct {
+	TaskID    string
+	Title     string
+	Done      bool
+	Summary   string // <task>
+	Implement string // <implement>
+	Context   string // <context>
+	Subtasks  []tasks.Subtask
+	DoD       []string
+	DependsOn []string
+	Labels    []string
+}
+
+// FromPlan returns an issue per task, in plan order. A listed task without
+// a file becomes an issue with only a title.
+func FromPlan(plan *tasks.Plan, labels []string) []Issue {
+	var issues []Issue
+	for _, n := range taskgraph.FromPlan(plan).Nodes {
+		is := Issue{
+			TaskID:    n.ID,
+			Title:     n.ID + ": " + n.Title,
+			Done:      n.Done,
+			DependsOn: n.DependsOn,
+			Labels:    labels,
+		}
+		if t := plan.Tasks[n.ID]; t != nil {
+			is.Summary = t.Section(tasks.SectionTask)
+			is.Implement = t.Section(tasks.SectionImplement)
+			is.Context = t.Section(tasks.SectionContext)
+			is.Subtasks = t.Subtasks
+			is.DoD = t.DoD
+		}
+		issues = append(issues, is)
+	}
+	return issues
+}
+
+// marker tags an issue body with its task so a later push finds the issue
+// again, whatever happened to its title.
+func marker(id string) string { return "<!-- agentflow:task " + id + " -->" }
+
+var markerRe = regexp.MustCompile(`<!-- agentflow:task (TASK-\d+) -->`)
+
+// taskIDOf returns the task an issue body was exported from, if any.
+func taskIDOf(body string) string {
+	if m := markerRe.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Markdown renders the issue body for GitHub and Linear: subtasks become a
+// checklist, the definition of done the acceptance criteria, and each
+// dependency a line rendered by link.
+func (is Issue) Markdown(link func(id string) string) string {
+	var b strings.Builder
+	if is.Summary != "" {
+		b.WriteString(is.Summary + "\n")
+	}
+	if is.Implement != "" {
+		b.WriteString("\n## Implementation\n\n" + is.Implement + "\n")
+	}
+	if len(is.Subtasks) > 0 {
+		b.WriteString("\n## Checklist\n\n")
+		for _, s := range is.Subtasks {
+			b.WriteString(checkbox(s) + "\n")
+		}
+	}
+	if len(is.DoD) > 0 {
+		b.WriteString("\n## Acceptance criteria\n\n")
+		for _, d := range is.DoD {
+			b.WriteString("- " + d + "\n")
+		}
+	}
+	if len(is.DependsOn) > 0 {
+		b.WriteString("\n## Dependencies\n\n")
+		for _, id := range is.DependsOn {
+			b.WriteString("- Depends on " + link(id) + "\n")
+		}
+	}
+	if is.Context != "" {
+		b.WriteString("\n<details>\n<summary>Context</summary>\n\n" + is.Context + "\n\n</details>\n")
+	}
+	b.WriteString("\n" + marker(is.TaskID) + "\n")
+	return strings.TrimLeft(b.String(), "\n")
+}
+
+// Jira renders the issue body in Jira wiki markup. Dependencies are
+// carried by the link columns of the CSV, so they are not repeated here.
+func (is Issue) Jira() string {
+	var b strings.Builder
+	if is.Summary != "" {
+		b.WriteString(is.Summary + "\n")
+	}
+	if is.Implement != "" {
+		b.WriteString("\nh3. Implementation\n" + is.Implement + "\n")
+	}
+	if len(is.Subtasks) > 0 {
+		b.WriteString("\nh3. Checklist\n")
+		for _, s := range is.Subtasks {
+			b.WriteString("*" + strings.TrimPrefix(checkbox(s), "-") + "\n")
+		}
+	}
+	if len(is.DoD) > 0 {
+		b.WriteString("\nh3. Acceptance criteria\n")
+		for _, d := range is.DoD {
+			b.WriteString("* " + d + "\n")
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func checkbox(s tasks.Subtask) string {
+	if s.Done {
+		return "- [x] " + s.Text
+	}
+	return "- [ ] " + s.Text
+}
+
+// plainLink refers to a task by its ID, for exports made before the
+// tracker has assigned numbers.
+func plainLink(id string) string { return id }
+
+// Write renders issues in format.
+func Write(w io.Writer, format string, issues []Issue) error {
+	switch format {
+	case FormatGitHub:
+		return writeGitHub(w, issues)
+	case FormatJiraCSV:
+		return writeJiraCSV(w, issues)
+	case FormatLinearJSON:
+		return writeLinear(w, issues)
+	}
+	return fmt.Errorf("unknown export format %q (want %s, %s or %s)", format, FormatGitHub, FormatJiraCSV, FormatLinearJSON)
+}
+
+func state(done bool) string {
+	if done {
+		return "closed"
+	}
+	return "open"
+}
+
+// githubIssue is the payload of the GitHub create and update issue calls.
+type githubIssue struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	State  string   `json:"state,omitempty"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+func writeGitHub(w io.Writer, issues []Issue) error {
+	out := make([]githubIssue, len(issues))
+	for i, is := range issues {
+		out[i] = githubIssue{Title: is.Title, Body: is.Markdown(plainLink), State: state(is.Done), Labels: is.Labels}
+	}
+	return writeJSON(w, out)
+}
+
+// linearIssue follows the fields of Linear's issueCreate input, with the
+// dependencies as task IDs for the importer to resolve.
+type linearIssue struct {
+	ExternalID  string   `json:"externalId"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	State       string   `json:"state"`
+	Labels      []string `json:"labels,omitempty"`
+	BlockedBy   []string `json:"blockedBy,omitempty"`
+}
+
+func writeLinear(w io.Writer, issues []Issue) error {
+	out := make([]linearIssue, len(issues))
+	for i, is := range issues {
+		st := "Todo"
+		if is.Done {
+			st = "Done"
+		}
+		out[i] = linearIssue{
+			ExternalID:  is.TaskID,
+			Title:       is.Title,
+			Description: is.Markdown(plainLink),
+			State:       st,
+			Labels:      is.Labels,
+			BlockedBy:   is.DependsOn,
+		}
+	}
+	return writeJSON(w, out)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// jiraID is the number of a task, used as its "Issue Id" so that the link
+// columns can refer to other rows of the same import.
+func jiraID(taskID string) string {
+	n, err := strconv.Atoi(strings.TrimPrefix(taskID, "TASK-"))
+	if err != nil {
+		return taskID
+	}
+	return strconv.Itoa(n)
+}
+
+// writeJiraCSV writes a file for Jira's CSV importer. Labels and
+// dependencies take one repeated column per value, which is how the
+// importer expects multi-valued fields; map the "Blocked By" columns to an
+// inward "Blocks" link.
+func writeJiraCSV(w io.Writer, issues []Issue) error {
+	nLabels, nDeps := 0, 0
+	for _, is := range issues {
+		nLabels = max(nLabels, len(is.Labels))
+		nDeps = max(nDeps, len(is.DependsOn))
+	}
+	header := []string{"Issue Id", "Summary", "Issue Type", "Status", "Description"}
+	for range nLabels {
+		header = append(header, "Labels")
+	}
+	for range nDeps {
+		header = append(header, "Blocked By")
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, is := range issues {
+		status := "To Do"
+		if is.Done {
+			status = "Done"
+		}
+		row := []string{jiraID(is.TaskID), is.Title, "Task", status, is.Jira()}
+		row = append(row, pad(is.Labels, nLabels)...)
+		deps := make([]string, len(is.DependsOn))
+		for i, id := range is.DependsOn {
+			deps[i] = jiraID(id)
+		}
+		row = append(row, pad(deps, nDeps)...)
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func pad(vals []string, n int) []string {
+	out := make([]string, n)
+	copy(out, vals)
+	return out
+}
@@ -0,0 +1,244 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"agentflow/internal/tasks"
+)
+
+func samplePlan() *tasks.Plan {
+	return &tasks.Plan{
+		List: tasks.ParseList("task_list.md", "- [x] TASK-001 — Scaffold\n- [ ] TASK-002 — Bookings API\n"),
+		Tasks: map[string]*tasks.Task{
+			"TASK-001": tasks.ParseTask("tasks/TASK-001.md", "<task>\nSet up the repo.\n</task>\n<subtask>\n- [x] go mod init\n</subtask>\n"),
+			"TASK-002": tasks.ParseTask("tasks/TASK-002.md", "<task>\nExpose bookings.\n</task>\n<depends_on>\nTASK-001\n</depends_on>\n"+
+				"<context>\nSee srs.md.\n</context>\n<implement>\nREST handlers.\n</implement>\n"+
+				"<subtask>\n- [ ] model\n- [x] handler\n</subtask>\n<dod>\n- Tests pass\n- Documented\n</dod>\n"),
+		},
+	}
+}
+
+func TestMarkdownBody(t *testing.T) {
+	is := FromPlan(samplePlan(), []string{"agentflow"})[1]
+	body := is.Markdown(func(id string) string { return "#" + strings.TrimPrefix(id, "TASK-00") })
+	want := "Expose bookings.\n\n## Implementation\n\nREST handlers.\n\n## Checklist\n\n- [ ] model\n- [x] handler\n\n" +
+		"## Acceptance criteria\n\n- Tests pass\n- Documented\n\n## Dependencies\n\n- Depends on #1\n\n" +
+		"<details>\n<summary>Context</summary>\n\nSee srs.md.\n\n</details>\n\n<!-- agentflow:task TASK-002 -->\n"
+	if body != want {
+		t.Fatalf("body:\n%s\nwant:\n%s", body, want)
+	}
+	if taskIDOf(body) != "TASK-002" {
+		t.Error("marker not found in body")
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	issues := FromPlan(samplePlan(), []string{"agentflow", "backend"})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatGitHub, issues); err != nil {
+		t.Fatal(err)
+	}
+	var gh []githubIssue
+	if err := json.Unmarshal(buf.Bytes(), &gh); err != nil {
+		t.Fatal(err)
+	}
+	if len(gh) != 2 || gh[0].Title != "TASK-001: Scaffold" || gh[0].State != "closed" || !strings.Contains(gh[1].Body, "- Depends on TASK-001") {
+		t.Fatalf("github: %+v", gh)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatLinearJSON, issues); err != nil {
+		t.Fatal(err)
+	}
+	var lin []linearIssue
+	if err := json.Unmarshal(buf.Bytes(), &lin); err != nil {
+		t.Fatal(err)
+	}
+	if len(lin) != 2 || lin[1].ExternalID != "TASK-002" || lin[1].State != "Todo" || strings.Join(lin[1].BlockedBy, ",") != "TASK-001" {
+		t.Fatalf("linear: %+v", lin)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatJiraCSV, issues); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rows[0], ","); got != "Issue Id,Summary,Issue Type,Status,Description,Labels,Labels,Blocked By" {
+		t.Fatalf("header = %s", got)
+	}
+	if r := rows[2]; r[0] != "2" || r[3] != "To Do" || r[7] != "1" || !strings.Contains(r[4], "h3. Checklist\n* [ ] model\n* [x] handler") {
+		t.Fatalf("row: %q", r)
+	}
+	if r := rows[1]; r[3] != "Done" || r[7] != "" {
+		t.Fatalf("row: %q", r)
+	}
+
+	if err := Write(&buf, "trello", issues); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+// fakeGitHub is an in-memory stand-in for the GitHub issues API.
+type fakeGitHub struct {
+	mu     sync.Mutex
+	issues []map[string]any
+	writes []string // "POST" and "PATCH #n" in order
+	// loseCreates makes that many creates open the issue but answer 502,
+	// as when the response is lost on the way back.
+	loseCreates int
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer s3cret" {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	const prefix = "/api/v3/repos/acme/shop/issues"
+	var in map[string]any
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&in)
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == prefix:
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		all := append([]map[string]any{{"number": 99, "title": "A pull request", "body": "<!-- agentflow:task TASK-001 -->", "pull_request": map[string]any{}}}, f.issues...)
+		var out []map[string]any
+		for i := (page - 1) * 100; i < len(all) && i < page*100; i++ {
+			out = append(out, all[i])
+		}
+		if out == nil {
+			out = []map[string]any{}
+		}
+		json.NewEncoder(w).Encode(out)
+	case r.Method == http.MethodPost && r.URL.Path == prefix:
+		n := len(f.issues) + 1
+		issue := map[string]any{"number": n, "title": in["title"], "body": in["body"], "state": "open", "html_url": fmt.Sprintf("https://example.test/acme/shop/issues/%d", n), "labels": labelObjects(in["labels"])}
+		f.issues = append(f.issues, issue)
+		f.writes = append(f.writes, "POST")
+		if f.loseCreates > 0 {
+			f.loseCreates--
+			http.Error(w, `{"message":"Bad Gateway"}`, http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(issue)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, prefix+"/"):
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, prefix+"/"))
+		issue := f.issues[n-1]
+		for k, v := range in {
+			if k == "labels" {
+				v = labelObjects(v)
+			}
+			issue[k] = v
+		}
+		f.writes = append(f.writes, fmt.Sprintf("PATCH #%d", n))
+		json.NewEncoder(w).Encode(issue)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func labelObjects(v any) []map[string]any {
+	names, _ := v.([]any)
+	out := []map[string]any{}
+	for _, n := range names {
+		out = append(out, map[string]any{"name": n})
+	}
+	return out
+}
+
+func TestPushIsIdempotent(t *testing.T) {
+	fake := &fakeGitHub{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	gh := &GitHub{BaseURL: srv.URL + "/api/v3/", Repo: "acme/shop", Token: "s3cret"}
+	plan := samplePlan()
+
+	results, err := gh.Push(context.Background(), FromPlan(plan, []string{"agentflow"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Action != ActionCreated || results[1].Number != 2 || results[1].URL == "" {
+		t.Fatalf("results: %+v", results)
+	}
+	// TASK-001 is done, so it is closed; TASK-002 links to it by number once
+	// both exist.
+	if got := strings.Join(fake.writes, ","); got != "POST,POST,PATCH #1,PATCH #2" {
+		t.Fatalf("writes = %s", got)
+	}
+	if fake.issues[0]["state"] != "closed" || !strings.Contains(fake.issues[1]["body"].(string), "- Depends on #1\n") {
+		t.Fatalf("issues: %+v", fake.issues)
+	}
+
+	// Pushing again changes nothing, even with a label added by hand.
+	fake.issues[1]["labels"] = []map[string]any{{"name": "agentflow"}, {"name": "p1"}}
+	fake.writes = nil
+	results, err = gh.Push(context.Background(), FromPlan(plan, []string{"agentflow"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.writes) != 0 || results[0].Action != ActionUnchanged || results[1].Action != ActionUnchanged {
+		t.Fatalf("second push: writes %v, results %+v", fake.writes, results)
+	}
+
+	// A finished task closes its issue, without dropping the label.
+	plan.List = tasks.ParseList("task_list.md", "- [x] TASK-001 — Scaffold\n- [x] TASK-002 — Bookings API\n")
+	results, err = gh.Push(context.Background(), FromPlan(plan, []string{"agentflow"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fake.writes, ","); got != "PATCH #2" || results[1].Action != ActionUpdated {
+		t.Fatalf("third push: writes %s, results %+v", got, results)
+	}
+	if fake.issues[1]["state"] != "closed" || len(fake.issues[1]["labels"].([]map[string]any)) != 2 {
+		t.Fatalf("issue: %+v", fake.issues[1])
+	}
+
+	gh.Token = "wrong"
+	if _, err := gh.Push(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Fatalf("expected the API's error message, got %v", err)
+	}
+	gh.Repo = "shop"
+	if _, err := gh.Push(context.Background(), nil); err == nil {
+		t.Fatal("expected an error for a repo without an owner")
+	}
+}
+
+func TestPushDoesNotRetryCreates(t *testing.T) {
+	fake := &fakeGitHub{loseCreates: 1}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	gh := &GitHub{BaseURL: srv.URL + "/api/v3", Repo: "acme/shop", Token: "s3cret"}
+	issues := FromPlan(samplePlan(), nil)
+
+	if _, err := gh.Push(context.Background(), issues); err == nil || !strings.Contains(err.Error(), "Bad Gateway") {
+		t.Fatalf("expected the lost create to fail the push, got %v", err)
+	}
+	if got := strings.Join(fake.writes, ","); got != "POST" {
+		t.Fatalf("writes = %s; a create must not be sent twice", got)
+	}
+	// The issue was opened anyway; the next push finds it by its marker.
+	results, err := gh.Push(context.Background(), issues)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.issues) != 2 || results[0].Number != 1 || results[0].Action == ActionCreated {
+		t.Fatalf("issues %d, results %+v", len(fake.issues), results)
+	}
+}
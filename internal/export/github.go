@@ -0,0 +1,232 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"agentflow/internal/logging"
+	"agentflow/internal/retry"
+)
+
+// DefaultGitHubURL is the API that pushes go to when no base URL is set.
+const DefaultGitHubURL = "https://api.github.com"
+
+// Push actions reported per task.
+const (
+	ActionCreated   = "created"
+	ActionUpdated   = "updated"
+	ActionUnchanged = "unchanged"
+)
+
+var repoRe = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// GitHub pushes issues to a GitHub-compatible REST API.
+type GitHub struct {
+	BaseURL string // empty uses DefaultGitHubURL
+	Repo    string // "owner/name"
+	Token   string // sent as a bearer token when set
+	Client  *http.Client
+	Log     *slog.Logger // nil uses slog.Default()
+}
+
+// PushResult is what a push did with one task.
+type PushResult struct {
+	TaskID string
+	Number int
+	URL    string
+	Action string
+}
+
+// remoteIssue is the part of a GitHub issue a push looks at.
+type remoteIssue struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	State   string `json:"state"`
+	HTMLURL string `json:"html_url"`
+	Labels  []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	PullRequest json.RawMessage `json:"pull_request,omitempty"`
+}
+
+func (r remoteIssue) labelNames() []string {
+	names := make([]string, len(r.Labels))
+	for i, l := range r.Labels {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// Push creates or updates one issue per task. Issues are matched to tasks
+// by the marker in their body, so pushing the same plan again updates the
+// issues it created instead of opening new ones, and an issue already up
+// to date is left alone. Dependencies link to the issues' numbers, which
+// is why new issues are created first and their bodies written after.
+func (g *GitHub) Push(ctx context.Context, issues []Issue) ([]PushResult, error) {
+	if !repoRe.MatchString(g.Repo) {
+		return nil, fmt.Errorf("github repo must be owner/name, got %q", g.Repo)
+	}
+	existing, err := g.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]PushResult, len(issues))
+	numbers := map[string]int{}
+	for i, is := range issues {
+		results[i].TaskID = is.TaskID
+		if r, ok := existing[is.TaskID]; ok {
+			numbers[is.TaskID] = r.Number
+			results[i].Number, results[i].URL, results[i].Action = r.Number, r.HTMLURL, ActionUnchanged
+			continue
+		}
+		var r remoteIssue
+		payload := githubIssue{Title: is.Title, Body: is.Markdown(plainLink), Labels: is.Labels}
+		if err := g.do(ctx, http.MethodPost, g.issuesPath(), payload, &r); err != nil {
+			return results[:i], fmt.Errorf("create issue for %s: %w", is.TaskID, err)
+		}
+		existing[is.TaskID] = r
+		numbers[is.TaskID] = r.Number
+		results[i].Number, results[i].URL, results[i].Action = r.Number, r.HTMLURL, ActionCreated
+		logging.Or(g.Log).Info("created issue", "task", is.TaskID, "number", r.Number)
+	}
+
+	link := func(id string) string {
+		if n, ok := numbers[id]; ok {
+			return fmt.Sprintf("#%d", n)
+		}
+		return id
+	}
+	for i, is := range issues {
+		r := existing[is.TaskID]
+		want := githubIssue{Title: is.Title, Body: is.Markdown(link), State: state(is.Done)}
+		labels := r.labelNames()
+		for _, l := range is.Labels {
+			if !slices.Contains(labels, l) {
+				labels = append(labels, l)
+			}
+		}
+		labelsChanged := len(labels) != len(r.Labels)
+		if r.Title == want.Title && r.Body == want.Body && r.State == want.State && !labelsChanged {
+			continue
+		}
+		if labelsChanged {
+			want.Labels = labels
+		}
+		if err := g.do(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", g.issuesPath(), r.Number), want, nil); err != nil {
+			return results, fmt.Errorf("update issue #%d for %s: %w", r.Number, is.TaskID, err)
+		}
+		if results[i].Action == ActionUnchanged {
+			results[i].Action = ActionUpdated
+			logging.Or(g.Log).Info("updated issue", "task", is.TaskID, "number", r.Number)
+		}
+	}
+	return results, nil
+}
+
+func (g *GitHub) issuesPath() string { return "/repos/" + g.Repo + "/issues" }
+
+// perPage is the page size of issue listings, GitHub's maximum.
+const perPage = 100
+
+// list returns the repository's issues that were exported from a task,
+// keyed by task ID, open and closed alike.
+func (g *GitHub) list(ctx context.Context) (map[string]remoteIssue, error) {
+	found := map[string]remoteIssue{}
+	for page := 1; ; page++ {
+		var batch []remoteIssue
+		path := fmt.Sprintf("%s?state=all&per_page=%d&page=%d", g.issuesPath(), perPage, page)
+		if err := g.do(ctx, http.MethodGet, path, nil, &batch); err != nil {
+			return nil, fmt.Errorf("list issues: %w", err)
+		}
+		for _, r := range batch {
+			if len(r.PullRequest) > 0 {
+				continue
+			}
+			// The oldest issue wins if a task was ever pushed twice.
+			if id := taskIDOf(r.Body); id != "" {
+				if prev, dup := found[id]; !dup || r.Number < prev.Number {
+					found[id] = r
+				}
+			}
+		}
+		if len(batch) < perPage {
+			return found, nil
+		}
+	}
+}
+
+func (g *GitHub) client() *http.Client {
+	if g.Client != nil {
+		return g.Client
+	}
+	return &http.Client{Transport: &retry.Transport{Log: g.Log, Retry: safeToRetry}}
+}
+
+// safeToRetry keeps creates to one attempt. A POST whose response was lost
+// may still have opened the issue, and sending it again would open a
+// duplicate; the next push finds the issue by its marker instead. Listing
+// is read-only and updates set absolute values, so both can be repeated.
+func safeToRetry(req *http.Request) bool {
+	return req.Method != http.MethodPost
+}
+
+// do sends a JSON request and decodes the JSON response into out, if out
+// is not nil.
+func (g *GitHub) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	base := strings.TrimRight(g.BaseURL, "/")
+	if base == "" {
+		base = DefaultGitHubURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	resp, err := g.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, e.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: decode response: %w", method, path, err)
+	}
+	return nil
+}
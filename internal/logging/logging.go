@@ -0,0 +1,130 @@
+// Package logging builds the process logger from the global CLI flags
+// (--verbose, --quiet, --log-level, --log-format, --log-file).
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures the logger. File, when set, receives the log instead
+// of the writer passed to New; it is appended to.
+type Options struct {
+	Level  slog.Level
+	Format string
+	File   string
+}
+
+// ParseLevel accepts debug, info, warn (or warning) and error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+// New returns a logger writing to w, or to opts.File when set. The returned
+// close function closes the log file, if any.
+func New(opts Options, w io.Writer) (*slog.Logger, func() error, error) {
+	closeFn := func() error { return nil }
+	if opts.File != "" {
+		f, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open log file: %w", err)
+		}
+		w, closeFn = f, f.Close
+	}
+	ho := &slog.HandlerOptions{Level: opts.Level}
+	var h slog.Handler
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", FormatText:
+		h = slog.NewTextHandler(w, ho)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, ho)
+	default:
+		closeFn()
+		return nil, nil, fmt.Errorf("unknown log format %q (want %s or %s)", opts.Format, FormatText, FormatJSON)
+	}
+	return slog.New(h), closeFn, nil
+}
+
+// Or returns l, or slog.Default() when l is nil. Library code takes an
+// optional logger and resolves it with Or.
+func Or(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// Level returns the lowest standard level l logs at.
+func Level(l *slog.Logger) slog.Level {
+	l = Or(l)
+	for _, lv := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+		if l.Enabled(context.Background(), lv) {
+			return lv
+		}
+	}
+	return slog.LevelError
+}
+
+// Tee returns a handler that passes every record to each of hs that is
+// enabled for its level.
+func Tee(hs ...slog.Handler) slog.Handler {
+	return tee(hs)
+}
+
+type tee []slog.Handler
+
+func (t tee) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t tee) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(tee, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	out := make(tee, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug, "": slog.LevelInfo, "INFO": slog.LevelInfo,
+		"warning": slog.LevelWarn, "error": slog.LevelError,
+	} {
+		got, err := ParseLevel(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) should fail")
+	}
+}
+
+func TestNewJSONFiltersLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l, closeFn, err := New(Options{Level: slog.LevelWarn, Format: FormatJSON}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeFn()
+	l.Info("hidden")
+	l.Warn("shown", "stage", "plan")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1: %q", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["msg"] != "shown" || rec["stage"] != "plan" {
+		t.Errorf("record = %v", rec)
+	}
+}
+
+func TestNewFileAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agentflow.log")
+	for _, msg := range []string{"first", "second"} {
+		var w bytes.Buffer
+		l, closeFn, err := New(Options{File: path}, &w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Info(msg)
+		if err := closeFn(); err != nil {
+			t.Fatal(err)
+		}
+		if w.Len() != 0 {
+			t.Errorf("writer got output with a log file set: %q", w.String())
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "msg=first") || !strings.Contains(string(data), "msg=second") {
+		t.Errorf("log file = %q", data)
+	}
+}
+
+func TestNewRejectsUnknownFormat(t *testing.T) {
+	if _, _, err := New(Options{Format: "xml"}, &bytes.Buffer{}); err == nil {
+		t.Error("want error for unknown format")
+	}
+}
+
+func TestTeeAndLevel(t *testing.T) {
+	var all, warn bytes.Buffer
+	l := slog.New(Tee(
+		slog.NewTextHandler(&all, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&warn, &slog.HandlerOptions{Level: slog.LevelWarn}),
+	)).With("stage", "qa")
+	l.Debug("detail")
+	l.Warn("careful")
+	if !strings.Contains(all.String(), "msg=detail") || !strings.Contains(all.String(), "msg=careful") {
+		t.Errorf("debug handler got %q", all.String())
+	}
+	if strings.Contains(warn.String(), "detail") || !strings.Contains(warn.String(), "stage=qa") {
+		t.Errorf("warn handler got %q", warn.String())
+	}
+	if got := Level(l); got != slog.LevelDebug {
+		t.Errorf("Level(tee) = %v, want debug", got)
+	}
+	quiet := slog.New(slog.NewTextHandler(&all, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	if got := Level(quiet); got != slog.LevelWarn {
+		t.Errorf("Level(quiet) = %v, want warn", got)
+	}
+}
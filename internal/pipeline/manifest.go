@@ -0,0 +1,152 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fingerprint captures everything a stage's result depends on. Two runs with
+// equal fingerprints produce equivalent documents, so the second can be
+// skipped.
+type Fingerprint struct {
+	Inputs    map[string]string `json:"inputs"`
+	Prompt    string            `json:"prompt"`
+	Config    string            `json:"config"`
+	Outputs   []string          `json:"outputs"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// Same reports whether f and other describe the same build, ignoring when it
+// happened.
+func (f Fingerprint) Same(other Fingerprint) bool {
+	if f.Prompt != other.Prompt || f.Config != other.Config || len(f.Inputs) != len(other.Inputs) {
+		return false
+	}
+	for k, v := range f.Inputs {
+		if other.Inputs[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Manifest records the last successful fingerprint of each stage. It lives
+// at .agentflow/manifest.json.
+type Manifest struct {
+	Stages map[string]Fingerprint `json:"stages"`
+}
+
+// manifestMu serialises read-modify-write cycles on manifest files so stages
+// running concurrently in one process do not lose each other's updates.
+var manifestMu sync.Mutex
+
+// LoadManifest reads the manifest at path. A missing file yields an empty
+// manifest.
+func LoadManifest(path string) (*Manifest, error) {
+	m := &Manifest{Stages: map[string]Fingerprint{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return m, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Stages == nil {
+		m.Stages = map[string]Fingerprint{}
+	}
+	return m, nil
+}
+
+// UpToDate reports whether stage was last built with fp and all of its
+// outputs still exist.
+func (m *Manifest) UpToDate(stage string, fp Fingerprint) bool {
+	prev, ok := m.Stages[stage]
+	if !ok || !prev.Same(fp) {
+		return false
+	}
+	for _, out := range prev.Outputs {
+		if _, err := os.Stat(out); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordStage stores fp as the latest build of stage in the manifest at path.
+func RecordStage(path, stage string, fp Fingerprint) error {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	m, err := LoadManifest(path)
+	if err != nil {
+		return err
+	}
+	fp.UpdatedAt = time.Now().UTC()
+	m.Stages[stage] = fp
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// StageUpToDate loads the manifest at path and checks stage against fp.
+func StageUpToDate(path, stage string, fp Fingerprint) (bool, error) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	m, err := LoadManifest(path)
+	if err != nil {
+		return false, err
+	}
+	return m.UpToDate(stage, fp), nil
+}
+
+// HashString returns the hex SHA-256 of s.
+func HashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashFiles hashes each path. Missing files hash to "" so that a file
+// appearing later invalidates the fingerprint.
+func HashFiles(paths []string) (map[string]string, error) {
+	out := make(map[string]string, len(paths))
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				out[p] = ""
+				continue
+			}
+			return nil, err
+		}
+		out[p] = HashString(string(data))
+	}
+	return out, nil
+}
+
+// HashPrompt hashes a rendered prompt. Timestamp lines, such as the one in
+// the run-metadata block, are dropped so re-renders compare equal.
+func HashPrompt(prompt string) string {
+	lines := strings.Split(prompt, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if strings.HasPrefix(strings.TrimSpace(l), "Timestamp:") {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return HashString(strings.Join(kept, "\n"))
+}
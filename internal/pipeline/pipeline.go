@@ -0,0 +1,427 @@
+// Package pipeline runs AgentFlow stages in dependency order, independent
+// stages side by side. Stages declare the documents they read and write;
+// the graph between them is derived from those declarations.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"agentflow/internal/logging"
+)
+
+var (
+	ErrUnknownStage = errors.New("unknown stage")
+	ErrCycle        = errors.New("stage dependency cycle")
+)
+
+// Stage is a single step of the pipeline. Inputs and Outputs are paths
+// relative to the output directory (e.g. "srs.md", "tasks").
+type Stage struct {
+	Name    string
+	Inputs  []string
+	Outputs []string
+	Run     func(ctx context.Context) error
+}
+
+// Graph is a validated set of stages.
+type Graph struct {
+	stages []Stage
+	index  map[string]int
+	deps   map[string][]string
+}
+
+// New builds a Graph. A stage depends on every stage that produces one of
+// its inputs. Each output may be produced by a single stage only.
+func New(stages ...Stage) (*Graph, error) {
+	g := &Graph{
+		stages: stages,
+		index:  make(map[string]int, len(stages)),
+		deps:   make(map[string][]string, len(stages)),
+	}
+	producer := map[string]string{}
+	for i, s := range stages {
+		if strings.TrimSpace(s.Name) == "" {
+			return nil, errors.New("stage name is required")
+		}
+		if _, dup := g.index[s.Name]; dup {
+			return nil, fmt.Errorf("duplicate stage %q", s.Name)
+		}
+		g.index[s.Name] = i
+		for _, out := range s.Outputs {
+			out = filepath.Clean(out)
+			if other, ok := producer[out]; ok {
+				return nil, fmt.Errorf("%s is produced by both %s and %s", out, other, s.Name)
+			}
+			producer[out] = s.Name
+		}
+	}
+	for _, s := range stages {
+		seen := map[string]bool{}
+		for _, in := range s.Inputs {
+			p, ok := producer[filepath.Clean(in)]
+			if !ok || p == s.Name || seen[p] {
+				continue
+			}
+			seen[p] = true
+			g.deps[s.Name] = append(g.deps[s.Name], p)
+		}
+	}
+	if _, err := g.Order(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Stage returns the stage registered under name.
+func (g *Graph) Stage(name string) (Stage, bool) {
+	i, ok := g.index[name]
+	if !ok {
+		return Stage{}, false
+	}
+	return g.stages[i], true
+}
+
+// Names returns the stage names in declaration order.
+func (g *Graph) Names() []string {
+	names := make([]string, len(g.stages))
+	for i, s := range g.stages {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Deps returns the stages name directly depends on.
+func (g *Graph) Deps(name string) []string {
+	return append([]string(nil), g.deps[name]...)
+}
+
+// Producer returns the stage that declares output, if any.
+func (g *Graph) Producer(output string) (string, bool) {
+	output = filepath.Clean(output)
+	for _, s := range g.stages {
+		for _, out := range s.Outputs {
+			if filepath.Clean(out) == output {
+				return s.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Order returns every stage in dependency order. Ties are broken by
+// declaration order so the result is deterministic.
+func (g *Graph) Order() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(g.stages))
+	order := make([]string, 0, len(g.stages))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		for _, d := range g.deps[name] {
+			if err := visit(d, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, s := range g.stages {
+		if err := visit(s.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Selection narrows a run to part of the graph. Only, when set, wins over
+// From/To. From and To are inclusive bounds in dependency order.
+type Selection struct {
+	From string
+	To   string
+	Only []string
+}
+
+// Select returns the stages picked by sel, in dependency order.
+func (g *Graph) Select(sel Selection) ([]string, error) {
+	order, err := g.Order()
+	if err != nil {
+		return nil, err
+	}
+	if len(sel.Only) > 0 {
+		want := map[string]bool{}
+		for _, n := range sel.Only {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+			if _, ok := g.index[n]; !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownStage, n)
+			}
+			want[n] = true
+		}
+		var out []string
+		for _, n := range order {
+			if want[n] {
+				out = append(out, n)
+			}
+		}
+		return out, nil
+	}
+	start, end := 0, len(order)-1
+	if sel.From != "" {
+		if start = indexOf(order, sel.From); start < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStage, sel.From)
+		}
+	}
+	if sel.To != "" {
+		if end = indexOf(order, sel.To); end < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStage, sel.To)
+		}
+	}
+	if start > end {
+		return nil, fmt.Errorf("--from %s comes after --to %s", sel.From, sel.To)
+	}
+	return append([]string(nil), order[start:end+1]...), nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// Status is the outcome of a stage within a run.
+type Status string
+
+const (
+	StatusOK        Status = "ok"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled" // stopped by --fail-fast
+	StatusPending   Status = "not run"
+	StatusPlanned   Status = "planned" // listed by a dry run
+)
+
+// Result records how a single stage went.
+type Result struct {
+	Stage    string
+	Status   Status
+	Duration time.Duration
+	Err      error
+	After    []string // selected stages it waits for; set by Plan
+}
+
+// Report summarises a pipeline run.
+type Report struct {
+	Results []Result
+}
+
+// Failed returns the first failed stage, or nil if none failed.
+func (r *Report) Failed() *Result {
+	for i := range r.Results {
+		if r.Results[i].Status == StatusFailed {
+			return &r.Results[i]
+		}
+	}
+	return nil
+}
+
+// String renders the report as one line per stage.
+func (r *Report) String() string {
+	var b strings.Builder
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "%-8s %-9s", res.Stage, res.Status)
+		switch res.Status {
+		case StatusPending:
+		case StatusPlanned:
+			if len(res.After) > 0 {
+				fmt.Fprintf(&b, " after %s", strings.Join(res.After, ", "))
+			}
+		default:
+			fmt.Fprintf(&b, " %s", res.Duration.Round(time.Millisecond))
+		}
+		if res.Err != nil {
+			fmt.Fprintf(&b, "  %v", res.Err)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Plan reports what Execute would run for names without running anything:
+// each stage, in the given order, with the selected stages it waits for.
+func (g *Graph) Plan(names []string) *Report {
+	selected := make(map[string]bool, len(names))
+	for _, n := range names {
+		selected[n] = true
+	}
+	report := &Report{Results: make([]Result, len(names))}
+	for i, n := range names {
+		report.Results[i] = Result{Stage: n, Status: StatusPlanned}
+		for _, d := range g.deps[n] {
+			if selected[d] {
+				report.Results[i].After = append(report.Results[i].After, d)
+			}
+		}
+	}
+	return report
+}
+
+// ExecuteOptions control a run. When CheckInputs is set, each stage's
+// declared inputs must exist under Dir before it starts.
+//
+// Jobs bounds how many independent stages run at once; values below 1 mean
+// one at a time. Once a stage fails no further stages start. Stages already
+// running are left to finish, unless FailFast is set, in which case they
+// are cancelled.
+type ExecuteOptions struct {
+	Dir         string
+	CheckInputs bool
+	Jobs        int
+	FailFast    bool
+	Log         *slog.Logger // nil uses slog.Default()
+}
+
+// Execute runs the named stages, each as soon as the stages it depends on
+// have succeeded, on up to opts.Jobs workers. Dependencies on stages that
+// are not named are assumed to be satisfied. The returned error is that of
+// the first stage to fail; stages that never started are reported as not
+// run.
+func (g *Graph) Execute(ctx context.Context, names []string, opts ExecuteOptions) (*Report, error) {
+	report := &Report{Results: make([]Result, len(names))}
+	pos := make(map[string]int, len(names))
+	for i, n := range names {
+		report.Results[i] = Result{Stage: n, Status: StatusPending}
+		pos[n] = i
+	}
+	for i, n := range names {
+		if _, ok := g.Stage(n); !ok {
+			err := fmt.Errorf("%w: %s", ErrUnknownStage, n)
+			report.Results[i].Status, report.Results[i].Err = StatusFailed, err
+			return report, err
+		}
+	}
+	log := logging.Or(opts.Log)
+	jobs := max(opts.Jobs, 1)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		i   int
+		err error
+		d   time.Duration
+	}
+	var (
+		done     = make(chan outcome)
+		started  = make([]bool, len(names))
+		ok       = make([]bool, len(names))
+		running  int
+		firstErr error
+	)
+	ready := func() int {
+		for i, n := range names {
+			if started[i] {
+				continue
+			}
+			waiting := false
+			for _, d := range g.deps[n] {
+				if j, selected := pos[d]; selected && !ok[j] {
+					waiting = true
+					break
+				}
+			}
+			if !waiting {
+				return i
+			}
+		}
+		return -1
+	}
+	for {
+		for firstErr == nil && runCtx.Err() == nil && running < jobs {
+			i := ready()
+			if i < 0 {
+				break
+			}
+			s, _ := g.Stage(names[i])
+			started[i] = true
+			running++
+			log.Debug("stage started", "stage", s.Name)
+			go func() {
+				start := time.Now()
+				var err error
+				if opts.CheckInputs {
+					err = g.checkInputs(s, opts.Dir)
+				}
+				if err == nil {
+					err = s.Run(runCtx)
+				}
+				done <- outcome{i: i, err: err, d: time.Since(start)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+		o := <-done
+		running--
+		res := &report.Results[o.i]
+		res.Duration = o.d
+		switch {
+		case o.err == nil:
+			res.Status = StatusOK
+			ok[o.i] = true
+			log.Debug("stage finished", "stage", res.Stage, "duration", o.d)
+		case firstErr != nil && opts.FailFast && ctx.Err() == nil && errors.Is(o.err, context.Canceled):
+			res.Status, res.Err = StatusCancelled, o.err
+		default:
+			res.Status, res.Err = StatusFailed, o.err
+			if firstErr == nil {
+				firstErr = fmt.Errorf("stage %s: %w", res.Stage, o.err)
+				if opts.FailFast && running > 0 {
+					log.Warn("stage failed; cancelling running stages", "stage", res.Stage)
+					cancel()
+				}
+			}
+		}
+	}
+	if firstErr == nil && ctx.Err() != nil {
+		return report, context.Cause(ctx)
+	}
+	return report, firstErr
+}
+
+func (g *Graph) checkInputs(s Stage, dir string) error {
+	var missing []string
+	for _, in := range s.Inputs {
+		if _, err := os.Stat(filepath.Join(dir, in)); err != nil {
+			if p, ok := g.Producer(in); ok {
+				missing = append(missing, fmt.Sprintf("%s (produced by %s)", in, p))
+			} else {
+				missing = append(missing, in)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing inputs: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func noop(context.Context) error { return nil }
+
+func sampleStages(run func(name string) func(context.Context) error) []Stage {
+	return []Stage{
+		{Name: "devplan", Inputs: []string{"srs.md", "architecture.md"}, Outputs: []string{"task_list.md"}, Run: run("devplan")},
+		{Name: "intake", Outputs: []string{"requirements.md"}, Run: run("intake")},
+		{Name: "plan", Inputs: []string{"requirements.md"}, Outputs: []string{"srs.md"}, Run: run("plan")},
+		{Name: "design", Inputs: []string{"srs.md"}, Outputs: []string{"architecture.md"}, Run: run("design")},
+		{Name: "qa", Inputs: []string{"srs.md"}, Outputs: []string{"test-plan.md"}, Run: run("qa")},
+	}
+}
+
+func TestOrderFollowsDeclaredFiles(t *testing.T) {
+	g, err := New(sampleStages(func(string) func(context.Context) error { return noop })...)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	order, err := g.Order()
+	if err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+	want := []string{"intake", "plan", "design", "devplan", "qa"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("order got %v want %v", order, want)
+	}
+	if deps := g.Deps("devplan"); !reflect.DeepEqual(deps, []string{"plan", "design"}) {
+		t.Fatalf("devplan deps got %v", deps)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	g, _ := New(sampleStages(func(string) func(context.Context) error { return noop })...)
+	cases := []struct {
+		sel  Selection
+		want []string
+	}{
+		{Selection{From: "plan", To: "devplan"}, []string{"plan", "design", "devplan"}},
+		{Selection{To: "plan"}, []string{"intake", "plan"}},
+		{Selection{Only: []string{"qa", "plan"}}, []string{"plan", "qa"}},
+	}
+	for _, c := range cases {
+		got, err := g.Select(c.sel)
+		if err != nil {
+			t.Fatalf("Select(%+v): %v", c.sel, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("Select(%+v) got %v want %v", c.sel, got, c.want)
+		}
+	}
+	if _, err := g.Select(Selection{Only: []string{"deploy"}}); !errors.Is(err, ErrUnknownStage) {
+		t.Fatalf("expected ErrUnknownStage, got %v", err)
+	}
+	if _, err := g.Select(Selection{From: "devplan", To: "plan"}); err == nil {
+		t.Fatal("expected error when from comes after to")
+	}
+}
+
+func TestPlanRunsNothing(t *testing.T) {
+	ran := false
+	g, _ := New(sampleStages(func(string) func(context.Context) error {
+		return func(context.Context) error { ran = true; return nil }
+	})...)
+	names, _ := g.Select(Selection{From: "plan", To: "devplan"})
+	report := g.Plan(names)
+	if ran {
+		t.Fatal("Plan must not run stages")
+	}
+	want := "plan     planned  \ndesign   planned   after plan\ndevplan  planned   after plan, design\n"
+	if got := report.String(); got != want {
+		t.Fatalf("plan report got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestNewRejectsCyclesAndDuplicateOutputs(t *testing.T) {
+	_, err := New(
+		Stage{Name: "a", Inputs: []string{"b.md"}, Outputs: []string{"a.md"}, Run: noop},
+		Stage{Name: "b", Inputs: []string{"a.md"}, Outputs: []string{"b.md"}, Run: noop},
+	)
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("expected ErrCycle, got %v", err)
+	}
+	_, err = New(
+		Stage{Name: "a", Outputs: []string{"x.md"}, Run: noop},
+		Stage{Name: "b", Outputs: []string{"x.md"}, Run: noop},
+	)
+	if err == nil {
+		t.Fatal("expected duplicate output error")
+	}
+}
+
+func TestExecuteStopsAtFirstFailure(t *testing.T) {
+	var ran []string
+	boom := errors.New("boom")
+	g, _ := New(sampleStages(func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			ran = append(ran, name)
+			if name == "design" {
+				return boom
+			}
+			return nil
+		}
+	})...)
+	names, _ := g.Select(Selection{})
+	report, err := g.Execute(context.Background(), names, ExecuteOptions{})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{"intake", "plan", "design"}) {
+		t.Fatalf("ran %v", ran)
+	}
+	failed := report.Failed()
+	if failed == nil || failed.Stage != "design" {
+		t.Fatalf("expected design reported as failed, got %+v", failed)
+	}
+	if report.Results[3].Status != StatusPending || report.Results[4].Status != StatusPending {
+		t.Fatalf("later stages should be not run: %+v", report.Results)
+	}
+	if !strings.Contains(report.String(), "design   failed") {
+		t.Fatalf("report missing failure line:\n%s", report)
+	}
+}
+
+func TestExecuteChecksInputs(t *testing.T) {
+	dir := t.TempDir()
+	g, _ := New(sampleStages(func(string) func(context.Context) error { return noop })...)
+	_, err := g.Execute(context.Background(), []string{"design"}, ExecuteOptions{Dir: dir, CheckInputs: true})
+	if err == nil || !strings.Contains(err.Error(), "srs.md (produced by plan)") {
+		t.Fatalf("expected missing input error, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "srs.md"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Execute(context.Background(), []string{"design"}, ExecuteOptions{Dir: dir, CheckInputs: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestManifestUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.json")
+	in := filepath.Join(dir, "requirements.md")
+	out := filepath.Join(dir, "srs.md")
+	if err := os.WriteFile(in, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := func() Fingerprint {
+		hashes, err := HashFiles([]string{in})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Fingerprint{
+			Inputs:  hashes,
+			Prompt:  HashPrompt("render\nTimestamp: " + t.Name()),
+			Config:  HashString("cfg"),
+			Outputs: []string{out},
+		}
+	}
+
+	if ok, _ := StageUpToDate(manifest, "plan", fingerprint()); ok {
+		t.Fatal("empty manifest should not be up to date")
+	}
+	if err := RecordStage(manifest, "plan", fingerprint()); err != nil {
+		t.Fatalf("RecordStage: %v", err)
+	}
+	if ok, _ := StageUpToDate(manifest, "plan", fingerprint()); ok {
+		t.Fatal("missing output should make the stage stale")
+	}
+	if err := os.WriteFile(out, []byte("srs"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := StageUpToDate(manifest, "plan", fingerprint()); !ok {
+		t.Fatal("expected stage to be up to date")
+	}
+	if HashPrompt("a\nTimestamp: 1") != HashPrompt("a\nTimestamp: 2") {
+		t.Fatal("timestamp lines should not affect the prompt hash")
+	}
+	if err := os.WriteFile(in, []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := StageUpToDate(manifest, "plan", fingerprint()); ok {
+		t.Fatal("changed input should make the stage stale")
+	}
+}
+
+func TestExecuteRunsIndependentStagesInParallel(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		active  int
+		maxSeen int
+		barrier sync.WaitGroup
+	)
+	barrier.Add(2)
+	bothRunning := make(chan struct{})
+	go func() { barrier.Wait(); close(bothRunning) }()
+	g, _ := New(sampleStages(func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			active++
+			maxSeen = max(maxSeen, active)
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				active--
+				mu.Unlock()
+			}()
+			if name != "design" && name != "qa" {
+				return nil
+			}
+			// design and qa both depend only on plan; each waits to see
+			// the other running.
+			barrier.Done()
+			select {
+			case <-bothRunning:
+				return nil
+			case <-time.After(5 * time.Second):
+				return errors.New("sibling never started")
+			}
+		}
+	})...)
+	names, _ := g.Select(Selection{})
+	report, err := g.Execute(context.Background(), names, ExecuteOptions{Jobs: 2})
+	if err != nil {
+		t.Fatalf("execute: %v\n%s", err, report)
+	}
+	if maxSeen != 2 {
+		t.Fatalf("max concurrent stages = %d, want 2", maxSeen)
+	}
+	for _, r := range report.Results {
+		if r.Status != StatusOK {
+			t.Fatalf("stage %s: %s", r.Stage, r.Status)
+		}
+	}
+}
+
+func TestExecuteFailFast(t *testing.T) {
+	boom := errors.New("boom")
+	for _, failFast := range []bool{false, true} {
+		qaStarted := make(chan struct{})
+		g, _ := New(sampleStages(func(name string) func(context.Context) error {
+			return func(ctx context.Context) error {
+				switch name {
+				case "design":
+					<-qaStarted
+					return boom
+				case "qa":
+					close(qaStarted)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(50 * time.Millisecond):
+						return nil
+					}
+				}
+				return nil
+			}
+		})...)
+		names, _ := g.Select(Selection{})
+		report, err := g.Execute(context.Background(), names, ExecuteOptions{Jobs: 4, FailFast: failFast})
+		if !errors.Is(err, boom) {
+			t.Fatalf("fail-fast=%v: err = %v, want boom", failFast, err)
+		}
+		status := map[string]Status{}
+		for _, r := range report.Results {
+			status[r.Stage] = r.Status
+		}
+		wantQA := StatusOK
+		if failFast {
+			wantQA = StatusCancelled
+		}
+		if status["design"] != StatusFailed || status["qa"] != wantQA || status["devplan"] != StatusPending {
+			t.Fatalf("fail-fast=%v: statuses %v", failFast, status)
+		}
+	}
+}
+
+func TestExecuteHonoursCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	g, _ := New(sampleStages(func(string) func(context.Context) error { return noop })...)
+	report, err := g.Execute(ctx, []string{"intake", "plan"}, ExecuteOptions{Jobs: 2})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if report.Results[0].Status != StatusPending {
+		t.Fatalf("no stage should start, got %+v", report.Results)
+	}
+}
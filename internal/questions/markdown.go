@@ -0,0 +1,160 @@
+package questions
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Markdown renders the store as questions.md. Open questions come first.
+func (s *Store) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Agent Questions\n\n")
+	b.WriteString("Answer with `agentflow answer <ID> \"...\"`, or write the answer below its \"Answer:\" line, then re-run the stage.\n")
+	for _, status := range []string{StatusOpen, StatusAnswered} {
+		for _, q := range s.Questions {
+			if q.Status != status {
+				continue
+			}
+			fmt.Fprintf(&b, "\n## %s [%s] %s\n\n", q.ID, q.Stage, q.Text)
+			if q.Kind == KindAssumption {
+				b.WriteString("Kind: assumption (confirm or correct it)\n\n")
+			}
+			if q.Context != "" {
+				fmt.Fprintf(&b, "Context: %s\n\n", q.Context)
+			}
+			b.WriteString("Answer:")
+			if q.Answer != "" {
+				b.WriteString("\n" + q.Answer)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+var mdHeading = regexp.MustCompile(`^## (Q-\d+) \[[^\]]*\]`)
+
+// readMarkdownAnswers returns the non-empty answers in a questions.md file,
+// keyed by question ID.
+func readMarkdownAnswers(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	answers := map[string]string{}
+	var (
+		id       string
+		inAnswer bool
+		lines    []string
+	)
+	flush := func() {
+		if a := strings.TrimSpace(strings.Join(lines, "\n")); id != "" && a != "" {
+			answers[id] = a
+		}
+		id, inAnswer, lines = "", false, nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			id = m[1]
+			continue
+		}
+		if id == "" {
+			continue
+		}
+		if inAnswer {
+			lines = append(lines, line)
+		} else if strings.HasPrefix(strings.TrimSpace(line), "Answer:") {
+			inAnswer = true
+			lines = append(lines, strings.TrimPrefix(strings.TrimSpace(line), "Answer:"))
+		}
+	}
+	flush()
+	return answers, nil
+}
+
+var (
+	headingLine = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletLine  = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.*)$`)
+)
+
+// pseudoLevel marks a section opened by a bold bullet rather than a heading.
+const pseudoLevel = 7
+
+// Extract returns the questions and assumptions listed in doc. Items are
+// the top-level bullets under any heading that mentions questions or
+// assumptions (in English or Thai), up to the next heading of the same or
+// a higher level. A bold bullet such as "- **Questions to Human**", as in
+// the intake format, opens a section of its nested bullets.
+func Extract(doc string) []Question {
+	var (
+		out     []Question
+		kind    string
+		level   int
+		inFence bool
+	)
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if m := headingLine.FindStringSubmatch(line); m != nil {
+			l := len(m[1])
+			if k := sectionKind(m[2]); k != "" {
+				kind, level = k, l
+			} else if kind != "" && l <= level {
+				kind = ""
+			}
+			continue
+		}
+		m := bulletLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		top := len(strings.ReplaceAll(m[1], "\t", "  ")) <= 1
+		text := cleanItem(m[2])
+		if top && strings.HasPrefix(m[2], "**") {
+			if k := sectionKind(text); k != "" {
+				kind, level = k, pseudoLevel
+				continue
+			}
+		}
+		if top && level == pseudoLevel {
+			kind = ""
+		}
+		// Heading sections list top-level bullets, pseudo sections nested ones.
+		if wantTop := level != pseudoLevel; kind == "" || top != wantTop {
+			continue
+		}
+		if text == "" || strings.Trim(text, ". …") == "" {
+			continue
+		}
+		out = append(out, Question{Kind: kind, Text: text})
+	}
+	return out
+}
+
+func sectionKind(title string) string {
+	t := strings.ToLower(title)
+	switch {
+	case strings.Contains(t, "question"), strings.Contains(t, "คำถาม"):
+		return KindQuestion
+	case strings.Contains(t, "assumption"), strings.Contains(t, "สมมติฐาน"):
+		return KindAssumption
+	}
+	return ""
+}
+
+func cleanItem(s string) string {
+	s = strings.ReplaceAll(s, "**", "")
+	return strings.TrimSpace(s)
+}
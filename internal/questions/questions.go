@@ -0,0 +1,242 @@
+// Package questions tracks the open questions and assumptions agents raise
+// while generating documents, and the answers humans give to them. The
+// tracker lives in .agentflow/questions.json; questions.md next to it is a
+// readable view that also accepts answers.
+package questions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status values.
+const (
+	StatusOpen     = "open"
+	StatusAnswered = "answered"
+)
+
+// Kind values.
+const (
+	KindQuestion   = "question"
+	KindAssumption = "assumption"
+)
+
+// OriginAskHuman marks questions asked through the ask_human tool. Other
+// questions are extracted from documents and carry the document name.
+const OriginAskHuman = "ask_human"
+
+var ErrUnknownQuestion = errors.New("unknown question")
+
+// Question is a single tracked question.
+type Question struct {
+	ID         string     `json:"id"`
+	Stage      string     `json:"stage"`
+	Origin     string     `json:"origin"`
+	Kind       string     `json:"kind"`
+	Text       string     `json:"text"`
+	Context    string     `json:"context,omitempty"`
+	Status     string     `json:"status"`
+	Answer     string     `json:"answer,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	AnsweredAt *time.Time `json:"answeredAt,omitempty"`
+}
+
+// Answered reports whether a human has answered q.
+func (q Question) Answered() bool { return q.Status == StatusAnswered }
+
+// Blocking reports whether the stage that raised q must wait for an answer.
+// Only ask_human questions block; extracted ones are informational.
+func (q Question) Blocking() bool { return q.Origin == OriginAskHuman && !q.Answered() }
+
+// Store is the content of questions.json.
+type Store struct {
+	Questions []Question `json:"questions"`
+}
+
+// mu serialises read-modify-write cycles on the tracker files.
+var mu sync.Mutex
+
+// MarkdownPath returns the questions.md view that belongs to the tracker at
+// path.
+func MarkdownPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "questions.md")
+}
+
+// Load reads the tracker at path. A missing file yields an empty store.
+// Answers written into questions.md since the last save are picked up.
+func Load(path string) (*Store, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load(path)
+}
+
+func load(path string) (*Store, error) {
+	s := &Store{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, s); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	answers, err := readMarkdownAnswers(MarkdownPath(path))
+	if err != nil {
+		return nil, err
+	}
+	for id, answer := range answers {
+		if q := s.Get(id); q != nil && q.Answer != answer {
+			s.answer(q, answer)
+		}
+	}
+	return s, nil
+}
+
+// Update loads the tracker at path, applies fn and saves the result.
+func Update(path string, fn func(*Store) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	s, err := load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.save(path)
+}
+
+func (s *Store) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(MarkdownPath(path), []byte(s.Markdown()), 0o644)
+}
+
+// Get returns the question with the given ID. "Q-12", "q-12" and "12" all
+// name the same question.
+func (s *Store) Get(id string) *Question {
+	id = normaliseID(id)
+	for i := range s.Questions {
+		if s.Questions[i].ID == id {
+			return &s.Questions[i]
+		}
+	}
+	return nil
+}
+
+// Find returns the question stage raised with the same text, if any.
+func (s *Store) Find(stage, text string) *Question {
+	text = strings.TrimSpace(text)
+	for i := range s.Questions {
+		if s.Questions[i].Stage == stage && strings.EqualFold(s.Questions[i].Text, text) {
+			return &s.Questions[i]
+		}
+	}
+	return nil
+}
+
+// Add records q under the next free ID unless the stage already raised the
+// same text. It returns the stored question and whether it is new.
+func (s *Store) Add(q Question) (*Question, bool) {
+	q.Text = strings.TrimSpace(q.Text)
+	if existing := s.Find(q.Stage, q.Text); existing != nil {
+		return existing, false
+	}
+	q.ID = fmt.Sprintf("Q-%d", s.nextID())
+	if q.Kind == "" {
+		q.Kind = KindQuestion
+	}
+	if q.Status == "" {
+		q.Status = StatusOpen
+	}
+	if q.CreatedAt.IsZero() {
+		q.CreatedAt = time.Now().UTC()
+	}
+	s.Questions = append(s.Questions, q)
+	return &s.Questions[len(s.Questions)-1], true
+}
+
+// Answer records answer for the question with the given ID.
+func (s *Store) Answer(id, answer string) error {
+	q := s.Get(id)
+	if q == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownQuestion, id)
+	}
+	if strings.TrimSpace(answer) == "" {
+		return errors.New("answer must not be empty")
+	}
+	s.answer(q, answer)
+	return nil
+}
+
+func (s *Store) answer(q *Question, answer string) {
+	now := time.Now().UTC()
+	q.Answer = strings.TrimSpace(answer)
+	q.Status = StatusAnswered
+	q.AnsweredAt = &now
+}
+
+// ForStage returns the questions stage raised, split into answered and
+// still open.
+func (s *Store) ForStage(stage string) (answered, open []Question) {
+	for _, q := range s.Questions {
+		if q.Stage != stage {
+			continue
+		}
+		if q.Answered() {
+			answered = append(answered, q)
+		} else {
+			open = append(open, q)
+		}
+	}
+	return answered, open
+}
+
+func (s *Store) nextID() int {
+	max := 0
+	for _, q := range s.Questions {
+		if n, err := strconv.Atoi(strings.TrimPrefix(q.ID, "Q-")); err == nil && n > max {
+			max = n
+		}
+	}
+	return max + 1
+}
+
+func normaliseID(id string) string {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if !strings.HasPrefix(id, "Q-") {
+		id = "Q-" + id
+	}
+	return id
+}
+
+// FormatAnswers renders answered questions so they can be given back to
+// the agent on its next run.
+func FormatAnswers(qs []Question) string {
+	var b strings.Builder
+	b.WriteString("Answers from the product team to questions and assumptions raised in earlier runs. Treat them as decisions and do not list them as open again:\n")
+	for _, q := range qs {
+		label := "Q"
+		if q.Kind == KindAssumption {
+			label = "Assumption"
+		}
+		fmt.Fprintf(&b, "\n%s (%s): %s\nA: %s\n", label, q.ID, q.Text, q.Answer)
+	}
+	return b.String()
+}
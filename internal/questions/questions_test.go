@@ -0,0 +1,96 @@
+package questions
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStoreAddAnswerAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "questions.json")
+	err := Update(path, func(s *Store) error {
+		a, isNew := s.Add(Question{Stage: "intake", Origin: "requirements.md", Text: "Which regions are in scope?"})
+		if !isNew || a.ID != "Q-1" {
+			t.Fatalf("first question: %+v, new=%v", a, isNew)
+		}
+		if _, isNew := s.Add(Question{Stage: "intake", Text: "which regions are in scope? "}); isNew {
+			t.Fatal("same stage and text should not be added twice")
+		}
+		b, _ := s.Add(Question{Stage: "plan", Text: "Which regions are in scope?", Kind: KindAssumption})
+		if b.ID != "Q-2" || b.Status != StatusOpen {
+			t.Fatalf("second question: %+v", b)
+		}
+		return s.Answer("q-1", "Thailand only")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answered, open := s.ForStage("intake")
+	if len(answered) != 1 || len(open) != 0 || answered[0].Answer != "Thailand only" || answered[0].AnsweredAt == nil {
+		t.Fatalf("intake questions: answered=%+v open=%+v", answered, open)
+	}
+	if err := s.Answer("Q-9", "x"); !errors.Is(err, ErrUnknownQuestion) {
+		t.Fatalf("expected ErrUnknownQuestion, got %v", err)
+	}
+	if !strings.Contains(FormatAnswers(answered), "Thailand only") {
+		t.Fatal("FormatAnswers should include the answer")
+	}
+}
+
+func TestLoadPicksUpMarkdownAnswers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "questions.json")
+	if err := Update(path, func(s *Store) error {
+		s.Add(Question{Stage: "plan", Origin: OriginAskHuman, Text: "Launch date?"})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	md, err := os.ReadFile(MarkdownPath(path))
+	if err != nil || !strings.Contains(string(md), "## Q-1 [plan] Launch date?") {
+		t.Fatalf("questions.md view missing question: %q (%v)", md, err)
+	}
+	edited := strings.TrimRight(string(md), "\n") + "\nQ3 2026\n"
+	if err := os.WriteFile(MarkdownPath(path), []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := s.Get("Q-1"); q == nil || !q.Answered() || q.Answer != "Q3 2026" || q.Blocking() {
+		t.Fatalf("answer from questions.md not applied: %+v", q)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	doc := "# Requirements\n\n" +
+		"- **Constraints**\n  - Thailand only\n" +
+		"- **Questions to Human (Stakeholders)**  \n  - Who owns consent data?\n  - **Retention** period for logs?\n" +
+		"- **Deliverables to SA**\n  - Use cases\n\n" +
+		"## Assumptions\n- Mobile apps ship monthly\n  - detail that is not an item\n- ...\n\n" +
+		"### Open Questions\n1. Is SSO required?\n\n" +
+		"## Scope\n- Not a question\n\n" +
+		"```\n## Questions\n- inside a code block\n```\n"
+	got := Extract(doc)
+	want := []Question{
+		{Kind: KindQuestion, Text: "Who owns consent data?"},
+		{Kind: KindQuestion, Text: "Retention period for logs?"},
+		{Kind: KindAssumption, Text: "Mobile apps ship monthly"},
+		{Kind: KindQuestion, Text: "Is SSO required?"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Extract returned %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("item %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Package redact replaces secrets and personal data with placeholders
+// before text leaves the process, e.g. "[REDACTED_EMAIL_1]". The mapping is
+// kept in a local vault, so the same value always gets the same placeholder
+// and documents written by agents can be restored.
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder kinds.
+const (
+	KindAPIKey = "API_KEY"
+	KindSecret = "SECRET" // value of a configured env key
+	KindEmail  = "EMAIL"
+	KindPhone  = "PHONE"
+	KindThaiID = "THAI_ID"
+)
+
+// minSecretLen keeps short env values, such as "1" or "true", from being
+// redacted everywhere they appear.
+const minSecretLen = 8
+
+// Options selects what a Redactor replaces.
+type Options struct {
+	Secrets   bool     // API-key patterns and EnvValues
+	PII       bool     // emails, phone numbers and Thai national IDs
+	EnvValues []string // values of the configured secret env keys
+}
+
+type pattern struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+var (
+	secretPatterns = []pattern{
+		{kind: KindAPIKey, re: regexp.MustCompile(`\bsk-(?:proj-|ant-)?[A-Za-z0-9_-]{20,}`)},
+		{kind: KindAPIKey, re: regexp.MustCompile(`\b(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}`)},
+		{kind: KindAPIKey, re: regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`)},
+		{kind: KindAPIKey, re: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+		{kind: KindAPIKey, re: regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{40,}`)},
+		{kind: KindAPIKey, re: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+		{kind: KindAPIKey, re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	}
+	piiPatterns = []pattern{
+		{kind: KindEmail, re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+		// Thai IDs before phones: 13 digits would otherwise contain a phone.
+		{kind: KindThaiID, re: regexp.MustCompile(`\b\d(?:[ -]?\d){12}\b`), valid: validThaiID},
+		{kind: KindPhone, re: regexp.MustCompile(`(?:\+66[ -]?|\b0)(?:[689]\d[ -]?\d{3}[ -]?\d{4}|[2-7][ -]?\d{3}[ -]?\d{4})\b`)},
+		{kind: KindPhone, re: regexp.MustCompile(`\+[1-9]\d{0,2}(?:[ -]?\d){7,12}\b`)},
+	}
+	placeholderRe = regexp.MustCompile(`\[REDACTED_([A-Z_]+?)_(\d+)\]`)
+)
+
+// validThaiID checks the mod-11 check digit of a Thai national ID, which
+// keeps order numbers and other 13-digit strings from being redacted.
+func validThaiID(s string) bool {
+	var d []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			d = append(d, int(r-'0'))
+		}
+	}
+	if len(d) != 13 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += d[i] * (13 - i)
+	}
+	return (11-sum%11)%10 == d[12]
+}
+
+// vault is the on-disk form of the placeholder mapping.
+type vault struct {
+	Values map[string]string `json:"values"` // placeholder → original
+	Next   map[string]int    `json:"next"`   // kind → last number used
+}
+
+// store is a vault shared by every Redactor opened on its path.
+type store struct {
+	path string
+
+	mu      sync.Mutex
+	v       *vault
+	byValue map[string]string
+	dirty   bool
+}
+
+// Redactor replaces sensitive values with placeholders and restores them.
+// It is safe for concurrent use.
+type Redactor struct {
+	opts Options
+	s    *store
+}
+
+var (
+	openMu sync.Mutex
+	opened = map[string]*store{}
+)
+
+// Open returns a Redactor backed by the vault at path. Redactors opened on
+// the same path within a process share their mapping, so stages running
+// side by side number placeholders consistently.
+func Open(path string, opts Options) (*Redactor, error) {
+	openMu.Lock()
+	defer openMu.Unlock()
+	st, ok := opened[path]
+	if !ok {
+		v, err := readVault(path)
+		if err != nil {
+			return nil, err
+		}
+		st = &store{path: path, v: v, byValue: map[string]string{}}
+		for ph, val := range v.Values {
+			st.byValue[val] = ph
+		}
+		opened[path] = st
+	}
+	return &Redactor{opts: opts, s: st}, nil
+}
+
+func readVault(path string) (*vault, error) {
+	v := &vault{Values: map[string]string{}, Next: map[string]int{}}
+	if path == "" {
+		return v, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return v, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if v.Values == nil {
+		v.Values = map[string]string{}
+	}
+	if v.Next == nil {
+		v.Next = map[string]int{}
+	}
+	return v, nil
+}
+
+// Enabled reports whether the redactor replaces anything.
+func (r *Redactor) Enabled() bool {
+	return r != nil && (r.opts.Secrets || r.opts.PII)
+}
+
+// Redact returns s with every sensitive value replaced by its placeholder.
+func (r *Redactor) Redact(s string) string {
+	if !r.Enabled() || s == "" {
+		return s
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.opts.Secrets {
+		values := append([]string(nil), r.opts.EnvValues...)
+		// Longest first, so a value containing another is replaced whole.
+		sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+		for _, v := range values {
+			if len(v) >= minSecretLen && strings.Contains(s, v) {
+				s = strings.ReplaceAll(s, v, r.placeholder(KindSecret, v))
+			}
+		}
+		s = r.apply(s, secretPatterns)
+	}
+	if r.opts.PII {
+		s = r.apply(s, piiPatterns)
+	}
+	return s
+}
+
+func (r *Redactor) apply(s string, patterns []pattern) string {
+	for _, p := range patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(m string) string {
+			if p.valid != nil && !p.valid(m) {
+				return m
+			}
+			return r.placeholder(p.kind, m)
+		})
+	}
+	return s
+}
+
+// placeholder returns the placeholder for value, allocating one if needed.
+// r.s.mu must be held.
+func (r *Redactor) placeholder(kind, value string) string {
+	st := r.s
+	if ph, ok := st.byValue[value]; ok {
+		return ph
+	}
+	st.v.Next[kind]++
+	ph := fmt.Sprintf("[REDACTED_%s_%d]", kind, st.v.Next[kind])
+	st.v.Values[ph] = value
+	st.byValue[value] = ph
+	st.dirty = true
+	return ph
+}
+
+// Restore replaces the placeholders in s with the original values. Unknown
+// placeholders are left alone.
+func (r *Redactor) Restore(s string) string {
+	if r == nil {
+		return s
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return placeholderRe.ReplaceAllStringFunc(s, func(ph string) string {
+		if v, ok := r.s.v.Values[ph]; ok {
+			return v
+		}
+		return ph
+	})
+}
+
+// Count returns the number of placeholders in s.
+func Count(s string) int {
+	return len(placeholderRe.FindAllStringIndex(s, -1))
+}
+
+// Save writes the vault if new placeholders were allocated. The vault holds
+// the original values, so it is written with owner-only permissions.
+func (r *Redactor) Save() error {
+	if r == nil || r.s.path == "" {
+		return nil
+	}
+	st := r.s
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(st.v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(st.path, data, 0o600); err != nil {
+		return err
+	}
+	st.dirty = false
+	return nil
+}
@@ -0,0 +1,97 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket: it holds up to burst tokens, refilled at rate
+// tokens per second, and each request takes one. A nil Limiter never
+// waits. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+	now    func() time.Time // tests replace it
+}
+
+// NewLimiter returns a limiter allowing requestsPerMinute on average and
+// bursts of up to burst requests (at least 1). It returns nil, meaning no
+// limit, when requestsPerMinute is not positive.
+func NewLimiter(requestsPerMinute float64, burst int) *Limiter {
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+	b := float64(max(burst, 1))
+	return &Limiter{rate: requestsPerMinute / 60, burst: b, tokens: b, now: time.Now}
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   = map[[2]float64]*Limiter{}
+)
+
+// Shared returns the process-wide limiter for the given settings, so that
+// every provider, and every stage running in parallel, draws from the same
+// bucket.
+func Shared(requestsPerMinute float64, burst int) *Limiter {
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+	key := [2]float64{requestsPerMinute, float64(burst)}
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	l, ok := shared[key]
+	if !ok {
+		l = NewLimiter(requestsPerMinute, burst)
+		shared[key] = l
+	}
+	return l
+}
+
+// reserve takes a token and returns how long the caller must wait before
+// using it.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by reserve that was not used.
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.burst, l.tokens+1)
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return context.Cause(ctx)
+	}
+	d := l.reserve()
+	if d == 0 {
+		return nil
+	}
+	if err := wait(ctx, d); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+// Package retry retries failed model API requests with exponential backoff
+// and jitter, honouring Retry-After, and paces requests with a token bucket
+// shared by the whole process.
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"agentflow/internal/logging"
+)
+
+// Defaults used for zero Policy fields.
+const (
+	DefaultMaxAttempts    = 4
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = 30 * time.Second
+	DefaultMaxRetryAfter  = 2 * time.Minute
+)
+
+// Policy decides how often and how long to wait between attempts.
+// MaxAttempts counts the first request, so 1 disables retries.
+// MaxRetryAfter is the longest wait a server may ask for with Retry-After;
+// a request told to wait longer fails at once rather than sleeping.
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	MaxRetryAfter  time.Duration
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = DefaultMaxBackoff
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = p.InitialBackoff
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = DefaultMaxRetryAfter
+	}
+	return p
+}
+
+// Backoff returns the wait before retry number attempt (1 for the first
+// retry): InitialBackoff doubled per attempt, capped at MaxBackoff, with
+// full jitter so that parallel callers do not retry in lockstep.
+func (p Policy) Backoff(attempt int) time.Duration {
+	p = p.withDefaults()
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+// Retryable reports whether a request that ended with resp and err may
+// succeed if sent again. Rate limits, server errors and transient network
+// failures are retryable; client errors, a spent quota and cancellation
+// are not. resp's body may be read and is restored.
+func Retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var ne net.Error
+		return errors.As(err, &ne) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// OpenAI reports an exhausted quota as a 429 too; waiting will not
+		// help with that.
+		return !strings.Contains(peekBody(resp), "insufficient_quota")
+	case http.StatusRequestTimeout, http.StatusConflict,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// maxPeek bounds how much of an error body Retryable inspects.
+const maxPeek = 64 << 10
+
+func peekBody(resp *http.Response) string {
+	if resp.Body == nil {
+		return ""
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxPeek))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	return string(data)
+}
+
+// RetryAfter returns the wait the server asked for in the Retry-After-Ms or
+// Retry-After header, or 0 if it did not say.
+func RetryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	if ms, err := strconv.ParseFloat(resp.Header.Get("Retry-After-Ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if s, err := strconv.ParseFloat(v, 64); err == nil {
+		return max(0, time.Duration(s*float64(time.Second)))
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now))
+	}
+	return 0
+}
+
+// Transport is an http.RoundTripper that waits for the limiter before each
+// attempt and retries retryable failures according to Policy. When the
+// attempts run out, the last response or error is returned unchanged, so
+// the API client reports it as usual.
+type Transport struct {
+	Base    http.RoundTripper // nil uses http.DefaultTransport
+	Policy  Policy
+	Limiter *Limiter     // nil means no rate limit
+	Log     *slog.Logger // nil uses slog.Default()
+	// Retry reports whether req may be sent again after a retryable
+	// failure; requests it rejects get a single attempt. Nil retries
+	// every request.
+	Retry func(req *http.Request) bool
+
+	// sleep waits for d or until ctx is done; tests replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	sleep := t.sleep
+	if sleep == nil {
+		sleep = wait
+	}
+	policy := t.Policy.withDefaults()
+	ctx := req.Context()
+
+	if req.Body != nil && req.GetBody == nil {
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+		req.Body, _ = req.GetBody()
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := t.Limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		r := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+		resp, err := base.RoundTrip(r)
+		if attempt >= policy.MaxAttempts || !Retryable(resp, err) || (t.Retry != nil && !t.Retry(req)) {
+			return resp, err
+		}
+		after := RetryAfter(resp, time.Now())
+		if after > policy.MaxRetryAfter {
+			logging.Or(t.Log).Warn("model request failed; not retrying, server asked to wait too long",
+				"url", req.URL.Redacted(), "status", resp.StatusCode, "retry_after", after, "max_retry_after", policy.MaxRetryAfter)
+			return resp, err
+		}
+		delay := max(policy.Backoff(attempt), after)
+		attrs := []any{"url", req.URL.Redacted(), "attempt", attempt, "max_attempts", policy.MaxAttempts, "delay", delay}
+		if err != nil {
+			attrs = append(attrs, "err", err)
+		} else {
+			attrs = append(attrs, "status", resp.StatusCode)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxPeek))
+			resp.Body.Close()
+		}
+		logging.Or(t.Log).Warn("model request failed; retrying", attrs...)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,242 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// server answers with the given statuses in turn, then 200. Every request
+// body is checked to be the full payload.
+func server(t *testing.T, statuses []int, header http.Header, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if string(got) != `{"model":"gpt-5"}` {
+			t.Errorf("attempt %d: body = %q", n.Load()+1, got)
+		}
+		i := int(n.Add(1)) - 1
+		if i >= len(statuses) {
+			io.WriteString(w, "ok")
+			return
+		}
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(statuses[i])
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &n
+}
+
+func send(t *testing.T, tr *Transport, url string) (*http.Response, error) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(`{"model":"gpt-5"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return (&http.Client{Transport: tr}).Do(req)
+}
+
+func recordSleeps(tr *Transport) *[]time.Duration {
+	var slept []time.Duration
+	tr.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return &slept
+}
+
+func TestTransportRetriesServerErrors(t *testing.T) {
+	srv, n := server(t, []int{503, 502}, nil, "busy")
+	tr := &Transport{Policy: Policy{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond}}
+	slept := recordSleeps(tr)
+	resp, err := send(t, tr, srv.URL)
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("got %v, %v", resp, err)
+	}
+	if n.Load() != 3 || len(*slept) != 2 {
+		t.Fatalf("requests = %d, sleeps = %v", n.Load(), *slept)
+	}
+	if d := (*slept)[1]; d < 100*time.Millisecond || d > 200*time.Millisecond {
+		t.Errorf("second backoff = %v, want within [100ms, 200ms]", d)
+	}
+}
+
+func TestTransportHonoursRetryAfter(t *testing.T) {
+	srv, n := server(t, []int{429}, http.Header{"Retry-After": {"7"}}, `{"error":{"code":"rate_limit_exceeded"}}`)
+	tr := &Transport{Policy: Policy{InitialBackoff: time.Millisecond}}
+	slept := recordSleeps(tr)
+	if resp, err := send(t, tr, srv.URL); err != nil || resp.StatusCode != 200 {
+		t.Fatalf("got %v, %v", resp, err)
+	}
+	if n.Load() != 2 || len(*slept) != 1 || (*slept)[0] != 7*time.Second {
+		t.Fatalf("requests = %d, sleeps = %v; want one 7s wait", n.Load(), *slept)
+	}
+}
+
+func TestTransportFailsPastMaxRetryAfter(t *testing.T) {
+	srv, n := server(t, []int{429}, http.Header{"Retry-After": {"86400"}}, `{"error":{"code":"rate_limit_exceeded"}}`)
+	tr := &Transport{Policy: Policy{InitialBackoff: time.Millisecond, MaxRetryAfter: time.Minute}}
+	slept := recordSleeps(tr)
+	resp, err := send(t, tr, srv.URL)
+	if err != nil || resp.StatusCode != 429 {
+		t.Fatalf("got %v, %v; want the 429 returned", resp, err)
+	}
+	if body, _ := io.ReadAll(resp.Body); !strings.Contains(string(body), "rate_limit_exceeded") {
+		t.Errorf("body = %q", body)
+	}
+	if n.Load() != 1 || len(*slept) != 0 {
+		t.Fatalf("requests = %d, sleeps = %v; want one request and no wait", n.Load(), *slept)
+	}
+}
+
+func TestTransportFatalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"bad request", 400, `{"error":"bad"}`},
+		{"unauthorized", 401, `{"error":"key"}`},
+		{"quota", 429, `{"error":{"code":"insufficient_quota"}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, n := server(t, []int{tc.status}, nil, tc.body)
+			tr := &Transport{}
+			slept := recordSleeps(tr)
+			resp, err := send(t, tr, srv.URL)
+			if err != nil || resp.StatusCode != tc.status {
+				t.Fatalf("got %v, %v", resp, err)
+			}
+			if body, _ := io.ReadAll(resp.Body); string(body) != tc.body {
+				t.Errorf("body = %q, want it intact", body)
+			}
+			if n.Load() != 1 || len(*slept) != 0 {
+				t.Errorf("requests = %d, sleeps = %v; want no retry", n.Load(), *slept)
+			}
+		})
+	}
+}
+
+func TestTransportGivesUpAfterMaxAttempts(t *testing.T) {
+	srv, n := server(t, []int{500, 500, 500, 500}, nil, "down")
+	tr := &Transport{Policy: Policy{MaxAttempts: 3}}
+	recordSleeps(tr)
+	resp, err := send(t, tr, srv.URL)
+	if err != nil || resp.StatusCode != 500 || n.Load() != 3 {
+		t.Fatalf("got %v, %v after %d requests; want the third 500", resp, err, n.Load())
+	}
+}
+
+func TestTransportRetryPredicate(t *testing.T) {
+	srv, n := server(t, []int{502, 502}, nil, "bad gateway")
+	tr := &Transport{Retry: func(req *http.Request) bool { return req.Method != http.MethodPost }}
+	slept := recordSleeps(tr)
+	resp, err := send(t, tr, srv.URL)
+	if err != nil || resp.StatusCode != 502 || n.Load() != 1 || len(*slept) != 0 {
+		t.Fatalf("got %v, %v after %d requests; want one attempt", resp, err, n.Load())
+	}
+}
+
+func TestTransportStopsWhenCancelled(t *testing.T) {
+	srv, n := server(t, []int{503, 503}, nil, "busy")
+	ctx, cancel := context.WithCancel(context.Background())
+	tr := &Transport{Policy: Policy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}}
+	tr.sleep = func(ctx context.Context, d time.Duration) error {
+		cancel()
+		return wait(ctx, d)
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL, strings.NewReader(`{"model":"gpt-5"}`))
+	if _, err := (&http.Client{Transport: tr}).Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if n.Load() != 1 {
+		t.Errorf("requests = %d, want 1", n.Load())
+	}
+}
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	p := Policy{InitialBackoff: time.Second, MaxBackoff: 8 * time.Second}
+	for attempt, ceil := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 8 * time.Second, 10: 8 * time.Second} {
+		for range 20 {
+			if d := p.Backoff(attempt); d < ceil/2 || d > ceil {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", attempt, d, ceil/2, ceil)
+			}
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		header http.Header
+		want   time.Duration
+	}{
+		{http.Header{"Retry-After": {"2"}}, 2 * time.Second},
+		{http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"9"}}, 250 * time.Millisecond},
+		{http.Header{"Retry-After": {now.Add(30 * time.Second).Format(http.TimeFormat)}}, 30 * time.Second},
+		{http.Header{"Retry-After": {"soon"}}, 0},
+		{http.Header{}, 0},
+	} {
+		if got := RetryAfter(&http.Response{Header: tc.header}, now); got != tc.want {
+			t.Errorf("RetryAfter(%v) = %v, want %v", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestRetryableNetworkErrors(t *testing.T) {
+	if !Retryable(nil, io.ErrUnexpectedEOF) {
+		t.Error("unexpected EOF should be retryable")
+	}
+	if Retryable(nil, context.Canceled) || Retryable(nil, errors.New("unsupported protocol scheme")) {
+		t.Error("cancellation and unknown errors should be fatal")
+	}
+}
+
+func TestLimiterTokenBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(60, 2) // one request per second, bursts of two
+	l.now = func() time.Time { return now }
+	for i, want := range []time.Duration{0, 0, time.Second, 2 * time.Second} {
+		if got := l.reserve(); got != want {
+			t.Fatalf("request %d waits %v, want %v", i+1, got, want)
+		}
+	}
+	now = now.Add(10 * time.Second) // refills, but only up to the burst
+	for i, want := range []time.Duration{0, 0, time.Second} {
+		if got := l.reserve(); got != want {
+			t.Fatalf("after refill, request %d waits %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestLimiterWaitAndShared(t *testing.T) {
+	var nilLimiter *Limiter
+	if err := nilLimiter.Wait(context.Background()); err != nil {
+		t.Fatalf("nil limiter: %v", err)
+	}
+	if NewLimiter(0, 5) != nil || Shared(0, 5) != nil {
+		t.Fatal("zero rate should mean no limiter")
+	}
+	if Shared(120, 3) != Shared(120, 3) {
+		t.Fatal("Shared should return one limiter per setting")
+	}
+	l := NewLimiter(1, 1) // one request per minute
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,179 @@
+package taskgraph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Formats accepted by Render.
+const (
+	FormatMermaid = "mermaid"
+	FormatDOT     = "dot"
+	FormatText    = "text"
+)
+
+// Render writes the graph in format. Edges point from a dependency to the
+// task that needs it; the critical path is highlighted and done tasks are
+// greyed out.
+func (g *Graph) Render(w io.Writer, format string) error {
+	path, err := g.CriticalPath()
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatMermaid:
+		return g.mermaid(w, path)
+	case FormatDOT:
+		return g.dot(w, path)
+	case FormatText:
+		return g.text(w, path)
+	}
+	return fmt.Errorf("unknown graph format %q (want %s, %s or %s)", format, FormatMermaid, FormatDOT, FormatText)
+}
+
+// edges lists every dependency edge in node order, calling fn with the
+// indices of the dependency and the dependent task.
+func (g *Graph) edges(fn func(from, to int)) {
+	for i := range g.Nodes {
+		for _, j := range g.deps[i] {
+			fn(j, i)
+		}
+	}
+}
+
+// onPath reports which nodes and which edges lie on path.
+func (g *Graph) onPath(path []string) (nodes map[int]bool, edges map[[2]int]bool) {
+	nodes, edges = map[int]bool{}, map[[2]int]bool{}
+	for k, id := range path {
+		nodes[g.index[id]] = true
+		if k > 0 {
+			edges[[2]int{g.index[path[k-1]], g.index[id]}] = true
+		}
+	}
+	return nodes, edges
+}
+
+func label(n Node) string {
+	if n.Title == "" {
+		return n.ID
+	}
+	return n.ID + ": " + n.Title
+}
+
+// mermaidID turns TASK-001 into TASK_001; Mermaid reads a dash in a bare
+// node ID as the start of an edge.
+func mermaidID(id string) string { return strings.ReplaceAll(id, "-", "_") }
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+func (g *Graph) mermaid(w io.Writer, path []string) error {
+	critical, criticalEdges := g.onPath(path)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	if len(path) > 0 {
+		fmt.Fprintf(&b, "  %%%% critical path: %s\n", strings.Join(path, " -> "))
+	}
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", mermaidID(n.ID), mermaidEscaper.Replace(label(n)))
+	}
+	var hot []string
+	k := 0
+	g.edges(func(from, to int) {
+		fmt.Fprintf(&b, "  %s --> %s\n", mermaidID(g.Nodes[from].ID), mermaidID(g.Nodes[to].ID))
+		if criticalEdges[[2]int{from, to}] {
+			hot = append(hot, fmt.Sprint(k))
+		}
+		k++
+	})
+	var done, crit []string
+	for i, n := range g.Nodes {
+		switch {
+		case n.Done:
+			done = append(done, mermaidID(n.ID))
+		case critical[i]:
+			crit = append(crit, mermaidID(n.ID))
+		}
+	}
+	if len(done) > 0 {
+		b.WriteString("  classDef done fill:#eee,stroke:#999,color:#999\n")
+		fmt.Fprintf(&b, "  class %s done\n", strings.Join(done, ","))
+	}
+	if len(crit) > 0 {
+		b.WriteString("  classDef critical stroke:#d33,stroke-width:3px\n")
+		fmt.Fprintf(&b, "  class %s critical\n", strings.Join(crit, ","))
+	}
+	if len(hot) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:#d33,stroke-width:3px\n", strings.Join(hot, ","))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (g *Graph) dot(w io.Writer, path []string) error {
+	critical, criticalEdges := g.onPath(path)
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n  rankdir=LR;\n  node [shape=box];\n")
+	if len(path) > 0 {
+		fmt.Fprintf(&b, "  // critical path: %s\n", strings.Join(path, " -> "))
+	}
+	for i, n := range g.Nodes {
+		attrs := fmt.Sprintf(`label="%s"`, dotEscaper.Replace(label(n)))
+		switch {
+		case n.Done:
+			attrs += `, style=filled, fillcolor="#eeeeee", fontcolor="#999999"`
+		case critical[i]:
+			attrs += `, color="#dd3333", penwidth=3`
+		}
+		fmt.Fprintf(&b, "  %q [%s];\n", n.ID, attrs)
+	}
+	g.edges(func(from, to int) {
+		attrs := ""
+		if criticalEdges[[2]int{from, to}] {
+			attrs = ` [color="#dd3333", penwidth=3]`
+		}
+		fmt.Fprintf(&b, "  %q -> %q%s;\n", g.Nodes[from].ID, g.Nodes[to].ID, attrs)
+	})
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// text lists the waves and the critical path, for planning who works on
+// what.
+func (g *Graph) text(w io.Writer, path []string) error {
+	waves, err := g.Levels()
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	for k, wave := range waves {
+		fmt.Fprintf(&b, "Wave %d:\n", k+1)
+		for _, id := range wave {
+			i := g.index[id]
+			n := g.Nodes[i]
+			mark := " "
+			if n.Done {
+				mark = "x"
+			}
+			deps := ""
+			if len(g.deps[i]) > 0 {
+				ids := make([]string, len(g.deps[i]))
+				for m, j := range g.deps[i] {
+					ids[m] = g.Nodes[j].ID
+				}
+				deps = " (after " + strings.Join(ids, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "  [%s] %s%s\n", mark, label(n), deps)
+		}
+	}
+	if len(path) == 0 {
+		b.WriteString("\nCritical path: none, every task is done.\n")
+	} else {
+		fmt.Fprintf(&b, "\nCritical path (%d tasks): %s\n", len(path), strings.Join(path, " -> "))
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,264 @@
+// Package taskgraph checks the dependencies between development tasks and
+// derives a work order from them: the topological order, the waves of tasks
+// that can be worked on in parallel, and the critical path. It renders the
+// graph as Mermaid or Graphviz DOT.
+package taskgraph
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"agentflow/internal/tasks"
+)
+
+// ErrInvalid is wrapped by the error of every operation that needs a valid
+// graph, when the graph has unknown dependencies or cycles.
+var ErrInvalid = errors.New("invalid task dependencies")
+
+// Node is one task.
+type Node struct {
+	ID        string
+	Title     string
+	Done      bool
+	DependsOn []string
+}
+
+// Problem is a reason the graph cannot be ordered.
+type Problem struct {
+	ID      string
+	Message string
+}
+
+func (p Problem) String() string { return p.ID + ": " + p.Message }
+
+// Graph is a set of tasks and their dependencies. Nodes keep the order they
+// were given in, which breaks ties everywhere an order is chosen.
+type Graph struct {
+	Nodes []Node
+	index map[string]int
+	deps  [][]int // known dependencies of each node, by index
+	bad   []Problem
+}
+
+// New builds a graph from nodes. Duplicate IDs keep their first node;
+// dependencies on unknown tasks are dropped and reported by Problems.
+func New(nodes []Node) *Graph {
+	g := &Graph{index: map[string]int{}}
+	for _, n := range nodes {
+		if _, dup := g.index[n.ID]; dup {
+			continue
+		}
+		g.index[n.ID] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, n)
+	}
+	g.deps = make([][]int, len(g.Nodes))
+	for i, n := range g.Nodes {
+		seen := map[int]bool{}
+		for _, d := range n.DependsOn {
+			j, ok := g.index[d]
+			if !ok {
+				g.bad = append(g.bad, Problem{n.ID, fmt.Sprintf("depends on unknown task %s", d)})
+				continue
+			}
+			if !seen[j] {
+				seen[j] = true
+				g.deps[i] = append(g.deps[i], j)
+			}
+		}
+	}
+	g.bad = append(g.bad, g.cycles()...)
+	return g
+}
+
+// FromPlan builds the graph of a development plan: the listed tasks in list
+// order, then any task files missing from the list. A listed task without a
+// file has no dependencies.
+func FromPlan(p *tasks.Plan) *Graph {
+	var nodes []Node
+	listed := map[string]bool{}
+	for _, it := range p.List.Items {
+		n := Node{ID: it.ID, Title: it.Title, Done: it.Done}
+		if t := p.Tasks[it.ID]; t != nil {
+			n.DependsOn = t.DependsOn
+		}
+		listed[it.ID] = true
+		nodes = append(nodes, n)
+	}
+	for _, id := range p.IDs() {
+		if t := p.Tasks[id]; !listed[id] {
+			nodes = append(nodes, Node{ID: id, Title: t.Title, DependsOn: t.DependsOn})
+		}
+	}
+	return New(nodes)
+}
+
+// cycles finds the dependency cycles with a depth-first search and reports
+// each once, as the path that closes it.
+func (g *Graph) cycles() []Problem {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.Nodes))
+	var stack []int
+	var problems []Problem
+	var visit func(i int)
+	visit = func(i int) {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range g.deps[i] {
+			switch state[j] {
+			case unvisited:
+				visit(j)
+			case visiting:
+				start := len(stack) - 1
+				for stack[start] != j {
+					start--
+				}
+				var path []string
+				for _, k := range stack[start:] {
+					path = append(path, g.Nodes[k].ID)
+				}
+				path = append(path, g.Nodes[j].ID)
+				problems = append(problems, Problem{g.Nodes[j].ID, "dependency cycle " + strings.Join(path, " -> ")})
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+	}
+	for i := range g.Nodes {
+		if state[i] == unvisited {
+			visit(i)
+		}
+	}
+	return problems
+}
+
+// Problems returns every unknown dependency and cycle; none means the graph
+// can be ordered.
+func (g *Graph) Problems() []Problem { return g.bad }
+
+func (g *Graph) check() error {
+	if len(g.bad) == 0 {
+		return nil
+	}
+	parts := make([]string, len(g.bad))
+	for i, p := range g.bad {
+		parts[i] = p.String()
+	}
+	return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(parts, "; "))
+}
+
+// Ready returns the open tasks whose dependencies are all done, in node
+// order. Tasks on a cycle never become ready; dependencies on unknown tasks
+// do not hold a task back.
+func (g *Graph) Ready() []string {
+	var ready []string
+	for i, n := range g.Nodes {
+		if n.Done {
+			continue
+		}
+		blocked := false
+		for _, j := range g.deps[i] {
+			if !g.Nodes[j].Done {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			ready = append(ready, n.ID)
+		}
+	}
+	return ready
+}
+
+// levels returns each node's wave: 0 for tasks without dependencies, else
+// one more than the latest of its dependencies. The graph must be acyclic.
+func (g *Graph) levels() []int {
+	level := make([]int, len(g.Nodes))
+	for i := range level {
+		level[i] = -1
+	}
+	var visit func(i int) int
+	visit = func(i int) int {
+		if level[i] < 0 {
+			l := 0
+			for _, j := range g.deps[i] {
+				l = max(l, visit(j)+1)
+			}
+			level[i] = l
+		}
+		return level[i]
+	}
+	for i := range g.Nodes {
+		visit(i)
+	}
+	return level
+}
+
+// Levels groups the tasks into waves. Every task's dependencies are in
+// earlier waves, so the tasks of one wave can be worked on in parallel.
+func (g *Graph) Levels() ([][]string, error) {
+	if err := g.check(); err != nil {
+		return nil, err
+	}
+	var waves [][]string
+	for i, l := range g.levels() {
+		for len(waves) <= l {
+			waves = append(waves, nil)
+		}
+		waves[l] = append(waves[l], g.Nodes[i].ID)
+	}
+	return waves, nil
+}
+
+// Order returns the tasks in topological order: every task after all of
+// its dependencies, wave by wave, in node order within a wave.
+func (g *Graph) Order() ([]string, error) {
+	waves, err := g.Levels()
+	if err != nil {
+		return nil, err
+	}
+	var order []string
+	for _, w := range waves {
+		order = append(order, w...)
+	}
+	return order, nil
+}
+
+// CriticalPath returns the longest chain of open tasks, first to last.
+// Every task counts the same, and dependencies that are done are already
+// satisfied, so this is the shortest number of steps in which the rest of
+// the plan can be finished. It is empty when every task is done.
+func (g *Graph) CriticalPath() ([]string, error) {
+	order, err := g.Order()
+	if err != nil {
+		return nil, err
+	}
+	length := make([]int, len(g.Nodes))
+	prev := make([]int, len(g.Nodes))
+	end := -1
+	for _, id := range order {
+		i := g.index[id]
+		prev[i] = -1
+		if g.Nodes[i].Done {
+			continue
+		}
+		length[i] = 1
+		for _, j := range g.deps[i] {
+			if length[j]+1 > length[i] {
+				length[i], prev[i] = length[j]+1, j
+			}
+		}
+		if end < 0 || length[i] > length[end] || (length[i] == length[end] && i < end) {
+			end = i
+		}
+	}
+	var path []string
+	for i := end; i >= 0; i = prev[i] {
+		path = append([]string{g.Nodes[i].ID}, path...)
+	}
+	return path, nil
+}
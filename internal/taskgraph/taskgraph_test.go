@@ -0,0 +1,158 @@
+package taskgraph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"agentflow/internal/tasks"
+)
+
+// sample is a diamond with a tail: 1 -> {2, 3} -> 4, 3 -> 5 -> 6.
+func sample() *Graph {
+	return New([]Node{
+		{ID: "TASK-001", Title: "Scaffold", Done: true},
+		{ID: "TASK-002", Title: "Bookings API", DependsOn: []string{"TASK-001"}},
+		{ID: "TASK-003", Title: `Payments "v2"`, DependsOn: []string{"TASK-001"}},
+		{ID: "TASK-004", Title: "Checkout", DependsOn: []string{"TASK-002", "TASK-003", "TASK-002"}},
+		{ID: "TASK-005", Title: "Refunds", DependsOn: []string{"TASK-003"}},
+		{ID: "TASK-006", Title: "Reports", DependsOn: []string{"TASK-005"}},
+	})
+}
+
+func TestOrderLevelsAndCriticalPath(t *testing.T) {
+	g := sample()
+	if p := g.Problems(); len(p) != 0 {
+		t.Fatalf("problems: %v", p)
+	}
+	order, err := g.Order()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "TASK-001,TASK-002,TASK-003,TASK-004,TASK-005,TASK-006" {
+		t.Errorf("order = %s", got)
+	}
+	waves, _ := g.Levels()
+	if len(waves) != 4 || strings.Join(waves[1], ",") != "TASK-002,TASK-003" || strings.Join(waves[2], ",") != "TASK-004,TASK-005" {
+		t.Errorf("waves = %v", waves)
+	}
+	path, err := g.CriticalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TASK-001 is done, so the chain of open work starts at TASK-003.
+	if got := strings.Join(path, ","); got != "TASK-003,TASK-005,TASK-006" {
+		t.Errorf("critical path = %s", got)
+	}
+
+	allDone := New([]Node{{ID: "TASK-001", Done: true}})
+	if path, err := allDone.CriticalPath(); err != nil || len(path) != 0 {
+		t.Errorf("all done: %v, %v", path, err)
+	}
+}
+
+func TestReady(t *testing.T) {
+	g := sample()
+	if got := strings.Join(g.Ready(), ","); got != "TASK-002,TASK-003" {
+		t.Errorf("ready = %s", got)
+	}
+	g.Nodes[2].Done = true // TASK-003
+	if got := strings.Join(g.Ready(), ","); got != "TASK-002,TASK-005" {
+		t.Errorf("ready after TASK-003 = %s", got)
+	}
+	cycle := New([]Node{
+		{ID: "TASK-001", DependsOn: []string{"TASK-002"}},
+		{ID: "TASK-002", DependsOn: []string{"TASK-001"}},
+		{ID: "TASK-003", DependsOn: []string{"TASK-009"}},
+	})
+	if got := strings.Join(cycle.Ready(), ","); got != "TASK-003" {
+		t.Errorf("ready with a cycle = %s", got)
+	}
+}
+
+func TestProblems(t *testing.T) {
+	g := New([]Node{
+		{ID: "TASK-001", DependsOn: []string{"TASK-003"}},
+		{ID: "TASK-002", DependsOn: []string{"TASK-001", "TASK-009"}},
+		{ID: "TASK-003", DependsOn: []string{"TASK-002"}},
+		{ID: "TASK-004", DependsOn: []string{"TASK-004"}},
+	})
+	var got []string
+	for _, p := range g.Problems() {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"TASK-002: depends on unknown task TASK-009",
+		"TASK-001: dependency cycle TASK-001 -> TASK-003 -> TASK-002 -> TASK-001",
+		"TASK-004: dependency cycle TASK-004 -> TASK-004",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if _, err := g.Order(); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Order: %v, want ErrInvalid", err)
+	}
+	if err := g.Render(&strings.Builder{}, FormatMermaid); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Render: %v, want ErrInvalid", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	g := sample()
+	for _, tc := range []struct {
+		format string
+		want   []string
+	}{
+		{FormatMermaid, []string{
+			"flowchart LR\n",
+			"%% critical path: TASK-003 -> TASK-005 -> TASK-006",
+			`TASK_003["TASK-003: Payments #quot;v2#quot;"]`,
+			"TASK_001 --> TASK_002",
+			"class TASK_001 done",
+			"class TASK_003,TASK_005,TASK_006 critical",
+			"linkStyle 4,5 stroke:#d33",
+		}},
+		{FormatDOT, []string{
+			"digraph tasks {",
+			`"TASK-003" [label="TASK-003: Payments \"v2\"", color="#dd3333", penwidth=3];`,
+			`"TASK-001" [label="TASK-001: Scaffold", style=filled`,
+			`"TASK-003" -> "TASK-005" [color="#dd3333", penwidth=3];`,
+			`"TASK-002" -> "TASK-004";`,
+		}},
+		{FormatText, []string{
+			"Wave 1:\n  [x] TASK-001: Scaffold\n",
+			"  [ ] TASK-004: Checkout (after TASK-002, TASK-003)\n",
+			"Critical path (3 tasks): TASK-003 -> TASK-005 -> TASK-006",
+		}},
+	} {
+		var b strings.Builder
+		if err := g.Render(&b, tc.format); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(b.String(), want) {
+				t.Errorf("%s output missing %q:\n%s", tc.format, want, b.String())
+			}
+		}
+	}
+	if err := g.Render(&strings.Builder{}, "svg"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestFromPlan(t *testing.T) {
+	plan := &tasks.Plan{
+		List: tasks.ParseList("task_list.md", "- [x] TASK-001 — One\n- [ ] TASK-002 — Two\n"),
+		Tasks: map[string]*tasks.Task{
+			"TASK-002": tasks.ParseTask("tasks/TASK-002.md", "# TASK-002 — Two\n<depends_on>\nTASK-1, task-003\n</depends_on>\n"),
+			"TASK-003": tasks.ParseTask("tasks/TASK-003.md", "# TASK-003 — Three\n<depends_on>\nnone\n</depends_on>\n"),
+		},
+	}
+	g := FromPlan(plan)
+	if len(g.Nodes) != 3 || g.Nodes[2].ID != "TASK-003" || g.Nodes[2].Title != "Three" || !g.Nodes[0].Done {
+		t.Fatalf("nodes: %+v", g.Nodes)
+	}
+	if order, err := g.Order(); err != nil || strings.Join(order, ",") != "TASK-001,TASK-003,TASK-002" {
+		t.Fatalf("order = %v, %v", order, err)
+	}
+}
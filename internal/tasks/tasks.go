@@ -0,0 +1,396 @@
+// Package tasks reads the development plan written by `agentflow devplan`:
+// the task_list.md checklist and the per-task tasks/TASK-XXX.md files with
+// their <task>, <depends_on>, <context>, <implement>, <subtask> and <dod>
+// sections.
+package tasks
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// File names inside the output directory.
+const (
+	ListFile = "task_list.md"
+	Dir      = "tasks"
+)
+
+// Section tags, in the order devplan writes them.
+const (
+	SectionTask      = "task"
+	SectionDependsOn = "depends_on"
+	SectionContext   = "context"
+	SectionImplement = "implement"
+	SectionSubtask   = "subtask"
+	SectionDoD       = "dod"
+)
+
+// Required lists the sections every task file must have. <depends_on> is
+// optional: plans written before it existed have no dependencies.
+var Required = []string{SectionTask, SectionContext, SectionImplement, SectionSubtask, SectionDoD}
+
+var (
+	ErrUnknownTask = errors.New("unknown task")
+
+	idRe        = regexp.MustCompile(`(?i)\bTASK-(\d+)\b`)
+	itemRe      = regexp.MustCompile(`^(\s*[-*+]\s+\[)([ xX])(\]\s+)((?i:TASK-\d+))\b\s*(?:[—–:-]\s*)?(.*)$`)
+	checkboxRe  = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s+(.*)$`)
+	headingRe   = regexp.MustCompile(`^#\s+((?i:TASK-\d+))\b\s*(?:[—–:-]\s*)?(.*)$`)
+	openTagRe   = regexp.MustCompile(`<([a-z][a-z0-9_-]*)>`)
+	fileNameRe  = regexp.MustCompile(`^((?i:TASK-\d+))\.md$`)
+	bulletRe    = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	sectionName = map[string]string{
+		"subtasks":           SectionSubtask,
+		"definition_of_done": SectionDoD,
+		"dependencies":       SectionDependsOn,
+		"depends-on":         SectionDependsOn,
+	}
+)
+
+// NormalizeID accepts "TASK-003", "task-3" or "3" and returns "TASK-003".
+// Every ID read from the list, a task file or <depends_on> goes through it,
+// so "TASK-1" and "task-01" name the same task.
+func NormalizeID(s string) string {
+	s = strings.TrimSpace(s)
+	if m := idRe.FindStringSubmatch(s); m != nil && len(m[0]) == len(s) {
+		s = m[1]
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return strings.ToUpper(s)
+	}
+	return fmt.Sprintf("TASK-%03d", n)
+}
+
+// CompareIDs orders normalized task IDs by their number, so TASK-999 comes
+// before TASK-1000. IDs without a number sort after those with one, by name.
+func CompareIDs(a, b string) int {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "TASK-"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "TASK-"))
+	switch {
+	case errA == nil && errB == nil && na != nb:
+		return cmp.Compare(na, nb)
+	case (errA == nil) != (errB == nil):
+		if errA == nil {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Item is one line of the task list.
+type Item struct {
+	ID    string
+	Title string
+	Done  bool
+	Line  int // zero-based line in task_list.md
+}
+
+// List is task_list.md. It keeps the file's lines so that checkboxes can be
+// flipped without disturbing anything else.
+type List struct {
+	Path  string
+	Items []Item
+	lines []string
+}
+
+// LoadList reads and parses the task list at path.
+func LoadList(path string) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseList(path, string(data)), nil
+}
+
+// ParseList parses task list content. Lines that are not task checkboxes
+// are kept but ignored.
+func ParseList(path, content string) *List {
+	l := &List{Path: path, lines: strings.Split(content, "\n")}
+	for i, line := range l.lines {
+		m := itemRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		l.Items = append(l.Items, Item{
+			ID:    NormalizeID(m[4]),
+			Title: strings.TrimSpace(m[5]),
+			Done:  m[2] != " ",
+			Line:  i,
+		})
+	}
+	return l
+}
+
+// Find returns the item with id.
+func (l *List) Find(id string) (*Item, bool) {
+	for i := range l.Items {
+		if l.Items[i].ID == id {
+			return &l.Items[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetDone checks or unchecks the item with id and rewrites the file in
+// place. Nothing but the checkbox changes.
+func (l *List) SetDone(id string, done bool) error {
+	it, ok := l.Find(id)
+	if !ok {
+		return fmt.Errorf("%w: %s is not in %s", ErrUnknownTask, id, l.Path)
+	}
+	mark := " "
+	if done {
+		mark = "x"
+	}
+	line := l.lines[it.Line]
+	loc := itemRe.FindStringSubmatchIndex(line)
+	l.lines[it.Line] = line[:loc[4]] + mark + line[loc[5]:]
+	it.Done = done
+	return writeFile(l.Path, strings.Join(l.lines, "\n"))
+}
+
+// Subtask is one checkbox of a <subtask> section.
+type Subtask struct {
+	Text string
+	Done bool
+}
+
+// Task is one tasks/TASK-XXX.md file.
+type Task struct {
+	ID    string
+	Title string
+	Path  string
+	// Sections maps each top-level tag to its trimmed content, including
+	// extras such as <risk> or <notes>. Order lists the tags as they
+	// appear in the file.
+	Sections map[string]string
+	Order    []string
+	Subtasks []Subtask
+	DoD      []string
+	// DependsOn lists the tasks that must be done first, from <depends_on>.
+	DependsOn []string
+
+	headingID string
+}
+
+// Section returns the content of the named section.
+func (t *Task) Section(name string) string { return t.Sections[name] }
+
+// LoadTask reads and parses the task file at path.
+func LoadTask(path string) (*Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTask(path, string(data)), nil
+}
+
+// ParseTask parses task file content. The ID comes from the file name,
+// falling back to the "# TASK-XXX — Title" heading.
+func ParseTask(path, content string) *Task {
+	t := &Task{Path: path, Sections: map[string]string{}}
+	if m := fileNameRe.FindStringSubmatch(filepath.Base(path)); m != nil {
+		t.ID = NormalizeID(m[1])
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if m := headingRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			t.headingID, t.Title = NormalizeID(m[1]), strings.TrimSpace(m[2])
+			break
+		}
+	}
+	if t.ID == "" {
+		t.ID = t.headingID
+	}
+
+	rest := content
+	for {
+		loc := openTagRe.FindStringSubmatchIndex(rest)
+		if loc == nil {
+			break
+		}
+		tag := rest[loc[2]:loc[3]]
+		body := rest[loc[1]:]
+		end := strings.Index(body, "</"+tag+">")
+		if end < 0 {
+			// Unclosed: take the rest of the file rather than lose it.
+			end = len(body)
+			rest = ""
+		} else {
+			rest = body[end+len(tag)+3:]
+		}
+		name := tag
+		if alias, ok := sectionName[tag]; ok {
+			name = alias
+		}
+		if _, dup := t.Sections[name]; !dup {
+			t.Order = append(t.Order, name)
+		}
+		t.Sections[name] = strings.TrimSpace(body[:end])
+	}
+
+	for _, line := range strings.Split(t.Sections[SectionSubtask], "\n") {
+		if m := checkboxRe.FindStringSubmatch(line); m != nil {
+			t.Subtasks = append(t.Subtasks, Subtask{Text: strings.TrimSpace(m[2]), Done: m[1] != " "})
+		}
+	}
+	for _, line := range strings.Split(t.Sections[SectionDoD], "\n") {
+		if line = strings.TrimSpace(bulletRe.ReplaceAllString(line, "")); line != "" {
+			t.DoD = append(t.DoD, line)
+		}
+	}
+	seen := map[string]bool{}
+	for _, m := range idRe.FindAllStringSubmatch(t.Sections[SectionDependsOn], -1) {
+		if id := NormalizeID(m[1]); !seen[id] {
+			seen[id] = true
+			t.DependsOn = append(t.DependsOn, id)
+		}
+	}
+	if t.Title == "" {
+		t.Title = firstLine(t.Sections[SectionTask])
+	}
+	return t
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(s)
+}
+
+// Problem is an inconsistency between the task list and the task files.
+type Problem struct {
+	ID      string
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s (%s)", p.ID, p.Message, p.Path)
+}
+
+// Plan is the whole development plan of an output directory.
+type Plan struct {
+	Dir      string
+	List     *List
+	Tasks    map[string]*Task
+	Problems []Problem
+}
+
+// Load reads task_list.md and every tasks/TASK-XXX.md under dir and checks
+// that they agree. Inconsistencies are reported in Problems, not as errors;
+// only a missing or unreadable task list fails.
+func Load(dir string) (*Plan, error) {
+	list, err := LoadList(filepath.Join(dir, ListFile))
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{Dir: dir, List: list, Tasks: map[string]*Task{}}
+	entries, err := os.ReadDir(filepath.Join(dir, Dir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		path := filepath.Join(dir, Dir, e.Name())
+		if !fileNameRe.MatchString(e.Name()) {
+			p.problem("", path, "file name is not TASK-XXX.md")
+			continue
+		}
+		t, err := LoadTask(path)
+		if err != nil {
+			return nil, err
+		}
+		if prev, dup := p.Tasks[t.ID]; dup {
+			p.problem(t.ID, path, "same task as %s", prev.Path)
+			continue
+		}
+		p.Tasks[t.ID] = t
+	}
+	p.check()
+	return p, nil
+}
+
+func (p *Plan) problem(id, path, format string, args ...any) {
+	p.Problems = append(p.Problems, Problem{ID: id, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check records every way the list and the task files disagree.
+func (p *Plan) check() {
+	seen := map[string]bool{}
+	for _, it := range p.List.Items {
+		if seen[it.ID] {
+			p.problem(it.ID, p.List.Path, "listed more than once")
+			continue
+		}
+		seen[it.ID] = true
+		if _, ok := p.Tasks[it.ID]; !ok {
+			p.problem(it.ID, p.List.Path, "listed but %s/%s.md does not exist", Dir, it.ID)
+		}
+	}
+	for _, id := range p.IDs() {
+		t := p.Tasks[id]
+		if !seen[id] {
+			p.problem(id, t.Path, "task file is not in %s", ListFile)
+		}
+		if t.headingID != "" && t.headingID != id {
+			p.problem(id, t.Path, "heading says %s", t.headingID)
+		}
+		var missing []string
+		for _, s := range Required {
+			if _, ok := t.Sections[s]; !ok {
+				missing = append(missing, "<"+s+">")
+			}
+		}
+		if len(missing) > 0 {
+			p.problem(id, t.Path, "missing %s", strings.Join(missing, ", "))
+		}
+	}
+}
+
+// IDs returns the IDs of the task files in numeric order.
+func (p *Plan) IDs() []string {
+	ids := make([]string, 0, len(p.Tasks))
+	for id := range p.Tasks {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, CompareIDs)
+	return ids
+}
+
+// writeFile replaces path through a temporary file so a failed write
+// cannot truncate the task list.
+func writeFile(path, content string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
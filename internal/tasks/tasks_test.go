@@ -0,0 +1,235 @@
+package tasks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const listDoc = `# Task List
+
+Some intro text.
+
+- [ ] TASK-001 — Set up the repository
+- [x] TASK-002: Add the booking API
+* [ ] TASK-003 Write the payment adapter
+- [ ] not a task
+`
+
+const taskDoc = `# TASK-001 — Set up the repository
+
+<task>
+Create the skeleton.
+</task>
+
+<depends_on>
+TASK-7, task-002, TASK-007
+</depends_on>
+
+<context>
+See architecture.md.
+</context>
+
+<implement>
+Use Go modules.
+</implement>
+
+<subtask>
+- [x] go mod init
+- [ ] add CI
+</subtask>
+
+<dod>
+- Builds on CI
+1. Lints clean
+</dod>
+
+<notes>
+Keep it small.
+</notes>
+`
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNormalizeID(t *testing.T) {
+	for in, want := range map[string]string{
+		"TASK-003":  "TASK-003",
+		"task-3":    "TASK-003",
+		"3":         "TASK-003",
+		" 12 ":      "TASK-012",
+		"TASK-1234": "TASK-1234",
+		"nope":      "NOPE",
+	} {
+		if got := NormalizeID(in); got != want {
+			t.Errorf("NormalizeID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIDsSortNumerically(t *testing.T) {
+	plan := &Plan{Tasks: map[string]*Task{}}
+	for _, id := range []string{"TASK-1000", "NOTES", "TASK-999", "TASK-002"} {
+		plan.Tasks[id] = &Task{}
+	}
+	if got := strings.Join(plan.IDs(), ","); got != "TASK-002,TASK-999,TASK-1000,NOTES" {
+		t.Fatalf("ids = %s", got)
+	}
+}
+
+func TestParseList(t *testing.T) {
+	l := ParseList("task_list.md", listDoc)
+	if len(l.Items) != 3 {
+		t.Fatalf("items: %+v", l.Items)
+	}
+	want := []Item{
+		{ID: "TASK-001", Title: "Set up the repository", Line: 4},
+		{ID: "TASK-002", Title: "Add the booking API", Done: true, Line: 5},
+		{ID: "TASK-003", Title: "Write the payment adapter", Line: 6},
+	}
+	for i, w := range want {
+		if l.Items[i] != w {
+			t.Errorf("item %d = %+v, want %+v", i, l.Items[i], w)
+		}
+	}
+}
+
+func TestParseTask(t *testing.T) {
+	task := ParseTask("tasks/TASK-001.md", taskDoc)
+	if task.ID != "TASK-001" || task.Title != "Set up the repository" {
+		t.Fatalf("id/title: %q %q", task.ID, task.Title)
+	}
+	if got := strings.Join(task.Order, ","); got != "task,depends_on,context,implement,subtask,dod,notes" {
+		t.Errorf("order = %s", got)
+	}
+	if task.Section(SectionContext) != "See architecture.md." || task.Section("notes") != "Keep it small." {
+		t.Errorf("sections: %+v", task.Sections)
+	}
+	if len(task.Subtasks) != 2 || !task.Subtasks[0].Done || task.Subtasks[1] != (Subtask{Text: "add CI"}) {
+		t.Errorf("subtasks: %+v", task.Subtasks)
+	}
+	if strings.Join(task.DoD, "|") != "Builds on CI|Lints clean" {
+		t.Errorf("dod: %q", task.DoD)
+	}
+	if strings.Join(task.DependsOn, ",") != "TASK-007,TASK-002" {
+		t.Errorf("depends on: %q", task.DependsOn)
+	}
+
+	untitled := ParseTask("tasks/TASK-009.md", "<task>\nDo the thing.\nMore detail.\n")
+	if untitled.Title != "Do the thing." || untitled.Section(SectionTask) != "Do the thing.\nMore detail." {
+		t.Errorf("unclosed, untitled task: %+v", untitled)
+	}
+}
+
+func TestSetDoneKeepsRestOfFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ListFile)
+	writeTestFile(t, path, listDoc)
+	if err := os.Chmod(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDone("TASK-003", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetDone("TASK-002", false); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	want := strings.NewReplacer("* [ ] TASK-003", "* [x] TASK-003", "- [x] TASK-002", "- [ ] TASK-002").Replace(listDoc)
+	if string(got) != want {
+		t.Fatalf("file:\n%s\nwant:\n%s", got, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if err := l.SetDone("TASK-042", true); err == nil || !strings.Contains(err.Error(), "unknown task") {
+		t.Fatalf("expected unknown task error, got %v", err)
+	}
+}
+
+func TestLoadReportsProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ListFile), listDoc+"- [ ] TASK-001 — listed twice\n")
+	writeTestFile(t, filepath.Join(dir, Dir, "TASK-001.md"), taskDoc)
+	writeTestFile(t, filepath.Join(dir, Dir, "TASK-002.md"), strings.Replace(taskDoc, "# TASK-001", "# TASK-020", 1))
+	writeTestFile(t, filepath.Join(dir, Dir, "TASK-004.md"), "# TASK-004 — Stray\n\n<task>\nx\n</task>\n")
+	writeTestFile(t, filepath.Join(dir, Dir, "notes.md"), "scratch")
+
+	plan, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(plan.IDs(), ","); got != "TASK-001,TASK-002,TASK-004" {
+		t.Errorf("ids = %s", got)
+	}
+	var got []string
+	for _, p := range plan.Problems {
+		got = append(got, p.ID+": "+p.Message)
+	}
+	for _, want := range []string{
+		": file name is not TASK-XXX.md",
+		"TASK-001: listed more than once",
+		"TASK-003: listed but tasks/TASK-003.md does not exist",
+		"TASK-002: heading says TASK-020",
+		"TASK-004: task file is not in task_list.md",
+		"TASK-004: missing <context>, <implement>, <subtask>, <dod>",
+	} {
+		if !strings.Contains(strings.Join(got, "\n"), want) {
+			t.Errorf("missing problem %q in:\n%s", want, strings.Join(got, "\n"))
+		}
+	}
+	if len(got) != 6 {
+		t.Errorf("problems: %d, want 6:\n%s", len(got), strings.Join(got, "\n"))
+	}
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Error("expected an error without a task list")
+	}
+}
+
+func TestLoadNormalizesIDs(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ListFile), "- [ ] TASK-1 — First\n- [ ] task-01 — Again\n- [ ] task-02 — Second\n")
+	writeTestFile(t, filepath.Join(dir, Dir, "TASK-1.md"), strings.Replace(taskDoc, "# TASK-001", "# task-01", 1))
+	writeTestFile(t, filepath.Join(dir, Dir, "task-2.md"), "# TASK-2 — Second\n\n<depends_on>\nTASK-1\n</depends_on>\n")
+
+	plan, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(plan.IDs(), ","); got != "TASK-001,TASK-002" {
+		t.Fatalf("ids = %s", got)
+	}
+	if _, ok := plan.List.Find(NormalizeID("task-1")); !ok {
+		t.Error("list item TASK-1 not found as TASK-001")
+	}
+	if got := strings.Join(plan.Tasks["TASK-002"].DependsOn, ","); got != "TASK-001" {
+		t.Errorf("depends on %s", got)
+	}
+	for _, p := range plan.Problems {
+		if strings.Contains(p.Message, "heading says") || strings.Contains(p.Message, "does not exist") {
+			t.Errorf("unexpected problem: %s", p)
+		}
+	}
+	if len(plan.Problems) == 0 || !strings.Contains(plan.Problems[0].String(), "listed more than once") {
+		t.Errorf("TASK-1 and task-01 should be reported as one task listed twice: %v", plan.Problems)
+	}
+	if err := plan.List.SetDone("TASK-002", true); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, ListFile))
+	if !strings.Contains(string(data), "- [x] task-02 — Second") {
+		t.Errorf("done should keep the ID as written:\n%s", data)
+	}
+}
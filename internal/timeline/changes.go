@@ -0,0 +1,129 @@
+package timeline
+
+import (
+	"strings"
+)
+
+// subjectSeparators split "subject <sep> value" statements. The first match
+// wins, so ":" takes precedence over the verbal forms.
+var subjectSeparators = []string{":", " = ", " → ", " -> ", " will be ", " must be ", " should be ", " is ", " are ", " ใช้ ", " คือ "}
+
+// maxSubjectLen keeps long sentences that merely contain "is" from being
+// treated as subject/value pairs.
+const maxSubjectLen = 48
+
+// split returns the normalised subject and value of a statement such as
+// "Database: PostgreSQL". Statements without a recognisable subject return
+// empty strings.
+func split(text string) (subject, value string) {
+	lower := strings.ToLower(text)
+	for _, sep := range subjectSeparators {
+		i := strings.Index(lower, sep)
+		if i <= 0 || i > maxSubjectLen {
+			continue
+		}
+		subject = normalise(lower[:i])
+		value = normalise(lower[i+len(sep):])
+		if subject == "" || value == "" {
+			continue
+		}
+		return subject, value
+	}
+	return "", ""
+}
+
+var negations = []string{"do not", "does not", "don't", "doesn't", "will not", "won't", "not", "no longer", "never", "no", "ไม่ต้อง", "ไม่"}
+
+// core strips negations from a statement and reports whether it was
+// negated, so "Support SSO" and "Do not support SSO" compare equal with
+// opposite polarity.
+func core(text string) (string, bool) {
+	s := " " + normalise(strings.ToLower(text)) + " "
+	negated := false
+	for _, n := range negations {
+		pat := n
+		if isASCII(n) {
+			pat = " " + n + " "
+		}
+		for strings.Contains(s, pat) {
+			s = strings.Replace(s, pat, " ", 1)
+			negated = !negated
+		}
+	}
+	return normalise(s), negated
+}
+
+func normalise(s string) string {
+	s = strings.Trim(s, " \t.,;!*_`\"'")
+	return spaces.ReplaceAllString(s, " ")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+type located struct {
+	entry int
+	stmt  int
+}
+
+// detectChanges compares statements across dates. Within a single note
+// nothing is flagged; the note is taken as written.
+func (t *Timeline) detectChanges() {
+	bySubject := map[string]located{}
+	byCore := map[string]located{}
+	for ei := range t.Entries {
+		for si := range t.Entries[ei].Statements {
+			st := &t.Entries[ei].Statements[si]
+			here := located{ei, si}
+
+			if st.Subject != "" {
+				if prev, ok := bySubject[st.Subject]; ok && prev.entry != ei {
+					earlier := t.at(prev)
+					_, ev := split(earlier.Text)
+					_, lv := split(st.Text)
+					if ev != lv {
+						t.supersede(KindSuperseded, st.Subject, prev, here)
+					}
+				}
+				bySubject[st.Subject] = here
+			}
+
+			c, negated := core(st.Text)
+			if c == "" {
+				continue
+			}
+			if prev, ok := byCore[c]; ok && prev.entry != ei {
+				_, prevNegated := core(t.at(prev).Text)
+				if prevNegated != negated && t.at(prev).Status == StatusCurrent {
+					t.supersede(KindContradiction, st.Subject, prev, here)
+				}
+			}
+			byCore[c] = here
+		}
+	}
+}
+
+func (t *Timeline) at(l located) *Statement {
+	return &t.Entries[l.entry].Statements[l.stmt]
+}
+
+func (t *Timeline) supersede(kind, subject string, earlier, later located) {
+	e, l := t.at(earlier), t.at(later)
+	e.Status, e.SupersededBy = StatusSuperseded, l.ID
+	t.Changes = append(t.Changes, Change{
+		Kind:     kind,
+		Subject:  subject,
+		Earlier:  e.ID,
+		Later:    l.ID,
+		FromDate: t.Entries[earlier.entry].Date,
+		ToDate:   t.Entries[later.entry].Date,
+		From:     e.Text,
+		To:       l.Text,
+	})
+}
@@ -0,0 +1,207 @@
+// Package timeline orders dated intake notes (.agentflow/input/YYYY-MM-DD.md)
+// and finds statements that later notes supersede or contradict, so the
+// product owner agent can narrate how the idea evolved instead of guessing.
+package timeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout is the date format of dated input files.
+const DateLayout = "2006-01-02"
+
+// Change kinds.
+const (
+	// KindSuperseded: a later note gives a subject a different value, e.g.
+	// "Database: MySQL" followed by "Database: PostgreSQL".
+	KindSuperseded = "superseded"
+	// KindContradiction: a later note negates an earlier statement, e.g.
+	// "Support SSO" followed by "Do not support SSO".
+	KindContradiction = "contradiction"
+)
+
+// Statement status values.
+const (
+	StatusCurrent    = "current"
+	StatusSuperseded = "superseded"
+)
+
+// Statement is a single bullet or line from a dated note.
+type Statement struct {
+	ID           string `json:"id"`
+	Text         string `json:"text"`
+	Subject      string `json:"subject,omitempty"`
+	Status       string `json:"status"`
+	SupersededBy string `json:"supersededBy,omitempty"`
+}
+
+// Entry is one dated input file.
+type Entry struct {
+	Date       string      `json:"date"`
+	File       string      `json:"file"`
+	Statements []Statement `json:"statements"`
+}
+
+// Change records a later statement overriding an earlier one.
+type Change struct {
+	Kind     string `json:"kind"`
+	Subject  string `json:"subject,omitempty"`
+	Earlier  string `json:"earlier"` // statement ID
+	Later    string `json:"later"`   // statement ID
+	FromDate string `json:"fromDate"`
+	ToDate   string `json:"toDate"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// Timeline is the ordered view of an input directory. Undated lists the
+// Markdown files whose names carry no date; they are treated as background
+// that predates the timeline.
+type Timeline struct {
+	Entries []Entry  `json:"entries"`
+	Undated []string `json:"undated,omitempty"`
+	Changes []Change `json:"changes"`
+}
+
+var datedName = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})(?:[-_ .].*)?\.md$`)
+
+// Load reads every Markdown file in dir. Files named YYYY-MM-DD.md (an
+// optional suffix such as "2025-01-02-kickoff.md" is allowed) become
+// entries in date order; the rest are listed as undated. A missing
+// directory yields an empty timeline.
+func Load(dir string) (*Timeline, error) {
+	t := &Timeline{}
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, err
+	}
+	for _, e := range dirEntries {
+		name := e.Name()
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(name), ".md") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		m := datedName.FindStringSubmatch(name)
+		if m == nil {
+			t.Undated = append(t.Undated, path)
+			continue
+		}
+		if _, err := time.Parse(DateLayout, m[1]); err != nil {
+			t.Undated = append(t.Undated, path)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		t.Entries = append(t.Entries, Entry{Date: m[1], File: path, Statements: statements(string(data))})
+	}
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		if t.Entries[i].Date != t.Entries[j].Date {
+			return t.Entries[i].Date < t.Entries[j].Date
+		}
+		return t.Entries[i].File < t.Entries[j].File
+	})
+	sort.Strings(t.Undated)
+	n := 0
+	for i := range t.Entries {
+		for j := range t.Entries[i].Statements {
+			n++
+			t.Entries[i].Statements[j].ID = fmt.Sprintf("S-%d", n)
+		}
+	}
+	t.detectChanges()
+	return t, nil
+}
+
+// Files returns the dated files in timeline order, after the undated ones.
+func (t *Timeline) Files() []string {
+	files := append([]string(nil), t.Undated...)
+	for _, e := range t.Entries {
+		files = append(files, e.File)
+	}
+	return files
+}
+
+// WriteJSON writes the timeline to path.
+func (t *Timeline) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Narrative renders the timeline for the product owner prompt: the reading
+// order and every superseded or contradicted statement, oldest first.
+func (t *Timeline) Narrative() string {
+	var b strings.Builder
+	if len(t.Entries) == 0 {
+		b.WriteString("No dated input files (YYYY-MM-DD.md) were found; treat the inputs as a single undated set.\n")
+	}
+	if len(t.Undated) > 0 {
+		b.WriteString("Undated background notes (read first):\n")
+		for _, f := range t.Undated {
+			fmt.Fprintf(&b, "- %s\n", f)
+		}
+	}
+	if len(t.Entries) > 0 {
+		b.WriteString("Dated notes, oldest first. When notes disagree the later date wins:\n")
+		for _, e := range t.Entries {
+			fmt.Fprintf(&b, "- %s: %s (%d statements)\n", e.Date, e.File, len(e.Statements))
+		}
+	}
+	if len(t.Changes) > 0 {
+		b.WriteString("Statements changed between dates:\n")
+		for _, c := range t.Changes {
+			verb := "superseded"
+			if c.Kind == KindContradiction {
+				verb = "contradicted"
+			}
+			fmt.Fprintf(&b, "- %s %s on %s: %q → %q\n", c.FromDate, verb, c.ToDate, c.From, c.To)
+		}
+	}
+	return b.String()
+}
+
+var (
+	listMarker = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+	spaces     = regexp.MustCompile(`\s+`)
+)
+
+// statements splits a note into its non-empty lines, ignoring headings,
+// code blocks and list markers.
+func statements(doc string) []Statement {
+	var out []Statement
+	inFence := false
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<!--") {
+			continue
+		}
+		line = strings.TrimSpace(strings.ReplaceAll(listMarker.ReplaceAllString(line, ""), "**", ""))
+		if line == "" {
+			continue
+		}
+		subject, _ := split(line)
+		out = append(out, Statement{Text: line, Subject: subject, Status: StatusCurrent})
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package timeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOrdersDatedFilesAndDetectsChanges(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"2025-02-10-review.md": "# Review\n\n- Database: PostgreSQL\n- Do not support SSO\n- Launch is in June\n",
+		"2025-01-15.md":        "# Kickoff\n\n- Database: MySQL\n- Support SSO\n- Launch is in June\n\n```\nDatabase: Oracle\n```\n",
+		"background.md":        "Consent platform for Thailand.\n",
+		"2025-13-01.md":        "not a real date\n",
+		"notes.txt":            "ignored\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tl, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tl.Entries) != 2 || tl.Entries[0].Date != "2025-01-15" || tl.Entries[1].Date != "2025-02-10" {
+		t.Fatalf("entries out of order: %+v", tl.Entries)
+	}
+	if len(tl.Undated) != 2 || !strings.HasSuffix(tl.Undated[0], "2025-13-01.md") {
+		t.Fatalf("undated files: %v", tl.Undated)
+	}
+	if n := len(tl.Entries[0].Statements); n != 3 {
+		t.Fatalf("code blocks and headings should be skipped, got %d statements", n)
+	}
+	if len(tl.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", tl.Changes)
+	}
+	db, sso := tl.Changes[0], tl.Changes[1]
+	if db.Kind != KindSuperseded || db.Subject != "database" || db.From != "Database: MySQL" || db.To != "Database: PostgreSQL" {
+		t.Errorf("database change: %+v", db)
+	}
+	if sso.Kind != KindContradiction || sso.Earlier != "S-2" || sso.ToDate != "2025-02-10" {
+		t.Errorf("SSO change: %+v", sso)
+	}
+	if s := tl.Entries[0].Statements[0]; s.Status != StatusSuperseded || s.SupersededBy != "S-4" {
+		t.Errorf("earlier statement not marked superseded: %+v", s)
+	}
+	if s := tl.Entries[0].Statements[2]; s.Status != StatusCurrent {
+		t.Errorf("repeated statement should stay current: %+v", s)
+	}
+
+	files := tl.Files()
+	if len(files) != 4 || !strings.HasSuffix(files[3], "2025-02-10-review.md") {
+		t.Errorf("Files: %v", files)
+	}
+	n := tl.Narrative()
+	if !strings.Contains(n, "later date wins") || !strings.Contains(n, "2025-01-15 contradicted on 2025-02-10") {
+		t.Errorf("Narrative:\n%s", n)
+	}
+}
+
+func TestLoadMissingDir(t *testing.T) {
+	tl, err := Load(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(tl.Entries) != 0 {
+		t.Fatalf("Load(missing) = %+v, %v", tl, err)
+	}
+	if !strings.Contains(tl.Narrative(), "No dated input files") {
+		t.Errorf("Narrative: %q", tl.Narrative())
+	}
+}
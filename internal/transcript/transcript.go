@@ -0,0 +1,293 @@
+// Package transcript records what happened during one agent run: the
+// rendered prompt, every model response, every tool call and the outcome.
+// Transcripts are stored as .agentflow/runs/<run-id>/transcript.json.
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is the name of the transcript inside a run directory. The prompt is
+// also written to PromptFile as plain text for easy reading.
+const (
+	File       = "transcript.json"
+	PromptFile = "prompt.md"
+)
+
+// Run status values.
+const (
+	StatusRunning = "running"
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+)
+
+var ErrUnknownRun = errors.New("unknown run")
+
+// Message is one prompt item handed to the model.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Usage is the token usage reported for a model response.
+type Usage struct {
+	InputTokens  int64 `json:"inputTokens"`
+	OutputTokens int64 `json:"outputTokens"`
+	TotalTokens  int64 `json:"totalTokens"`
+}
+
+// Response is one model turn. Output holds the raw output items as the
+// backend returned them; Text is the assistant text they contain.
+type Response struct {
+	ID     string            `json:"id,omitempty"`
+	At     time.Time         `json:"at"`
+	Text   string            `json:"text,omitempty"`
+	Output []json.RawMessage `json:"output,omitempty"`
+	Usage  *Usage            `json:"usage,omitempty"`
+}
+
+// ToolCall is one tool invocation made by the model.
+type ToolCall struct {
+	Tool       string          `json:"tool"`
+	Args       json.RawMessage `json:"args,omitempty"`
+	Result     string          `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	DurationMS int64           `json:"durationMs"`
+}
+
+// Transcript is the record of a single agent run. It is safe for concurrent
+// use; tools may run in parallel within a turn.
+type Transcript struct {
+	ID         string     `json:"id"`
+	Stage      string     `json:"stage"`
+	Provider   string     `json:"provider,omitempty"`
+	Model      string     `json:"model,omitempty"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt time.Time  `json:"finishedAt,omitzero"`
+	DurationMS int64      `json:"durationMs"`
+	Prompt     []Message  `json:"prompt"`
+	Responses  []Response `json:"responses"`
+	ToolCalls  []ToolCall `json:"toolCalls"`
+	Output     string     `json:"output,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	// Usage and CostUSD total the run; CostUSD is nil for unpriced models.
+	Usage   *Usage   `json:"usage,omitempty"`
+	CostUSD *float64 `json:"costUSD,omitempty"`
+
+	mu    sync.Mutex
+	saved bool
+}
+
+// New starts a transcript for stage. The run ID sorts by start time.
+func New(stage, provider, model string, prompt []Message) *Transcript {
+	now := time.Now()
+	return &Transcript{
+		ID:        fmt.Sprintf("%s-%s", now.UTC().Format("20060102-150405.000"), stage),
+		Stage:     stage,
+		Provider:  provider,
+		Model:     model,
+		Status:    StatusRunning,
+		StartedAt: now,
+		Prompt:    prompt,
+	}
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context that carries t, so the tools and the runner
+// of the run can add to it.
+func NewContext(ctx context.Context, t *Transcript) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromContext returns the transcript carried by ctx, or nil. All methods
+// accept a nil receiver, so callers need not check.
+func FromContext(ctx context.Context) *Transcript {
+	t, _ := ctx.Value(ctxKey{}).(*Transcript)
+	return t
+}
+
+// AddPrompt records a message handed to the model after the run started,
+// such as the request of a follow-up pass.
+func (t *Transcript) AddPrompt(m Message) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Prompt = append(t.Prompt, m)
+}
+
+// AddResponse records a model turn.
+func (t *Transcript) AddResponse(r Response) {
+	if t == nil {
+		return
+	}
+	if r.At.IsZero() {
+		r.At = time.Now()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Responses = append(t.Responses, r)
+}
+
+// AddToolCall records a tool invocation that started at start.
+func (t *Transcript) AddToolCall(tool string, args json.RawMessage, result string, err error, start time.Time) {
+	if t == nil {
+		return
+	}
+	c := ToolCall{
+		Tool:       tool,
+		Args:       args,
+		Result:     result,
+		StartedAt:  start,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ToolCalls = append(t.ToolCalls, c)
+}
+
+// Finish records the outcome of the run.
+func (t *Transcript) Finish(output string, err error) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.FinishedAt = time.Now()
+	t.DurationMS = t.FinishedAt.Sub(t.StartedAt).Milliseconds()
+	t.Output = output
+	t.Status = StatusOK
+	if err != nil {
+		t.Status, t.Error = StatusFailed, err.Error()
+	}
+}
+
+// SetUsage records the run's token totals and, when priced, its cost.
+func (t *Transcript) SetUsage(input, output int64, cost float64, priced bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Usage = &Usage{InputTokens: input, OutputTokens: output, TotalTokens: input + output}
+	t.CostUSD = nil
+	if priced {
+		t.CostUSD = &cost
+	}
+}
+
+// Save writes the transcript to <dir>/<id>/. The first save claims the
+// directory; should another run of the same stage have started in the same
+// millisecond, a numeric suffix is added to the ID.
+func (t *Transcript) Save(dir string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.saved {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		base := t.ID
+		for n := 2; ; n++ {
+			err := os.Mkdir(filepath.Join(dir, t.ID), 0o755)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, os.ErrExist) {
+				return err
+			}
+			t.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		t.saved = true
+	}
+	runDir := filepath.Join(dir, t.ID)
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(runDir, File), data, 0o644); err != nil {
+		return err
+	}
+	var prompt strings.Builder
+	for _, m := range t.Prompt {
+		fmt.Fprintf(&prompt, "<!-- %s -->\n%s\n\n", m.Role, m.Content)
+	}
+	return os.WriteFile(filepath.Join(runDir, PromptFile), []byte(prompt.String()), 0o644)
+}
+
+// List returns the transcripts stored under dir, oldest first. Directories
+// without a readable transcript are skipped. A missing dir yields none.
+func List(dir string) ([]*Transcript, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var out []*Transcript
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		t, err := read(filepath.Join(dir, e.Name(), File))
+		if err != nil {
+			continue
+		}
+		out = append(out, t)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out, nil
+}
+
+// Load returns the transcript whose ID is id or, failing that, the only one
+// whose ID starts with id.
+func Load(dir, id string) (*Transcript, error) {
+	if t, err := read(filepath.Join(dir, id, File)); err == nil {
+		return t, nil
+	}
+	all, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var match *Transcript
+	for _, t := range all {
+		if strings.HasPrefix(t.ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("%w: %q matches more than one run", ErrUnknownRun, id)
+			}
+			match = t
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRun, id)
+	}
+	return match, nil
+}
+
+func read(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &t, nil
+}
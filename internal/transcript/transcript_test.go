@@ -0,0 +1,66 @@
+package transcript
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveListAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	tr := New("plan", "mock", "gpt-5", []Message{{Role: "system", Content: "Write srs.md"}})
+	ctx := NewContext(context.Background(), tr)
+	FromContext(ctx).AddToolCall("file_reader", json.RawMessage(`{"Path":"requirements.md"}`), "# Requirements", nil, time.Now())
+	FromContext(ctx).AddToolCall("file_creator", json.RawMessage(`{"Path":"/etc/passwd"}`), "", errors.New("refused"), time.Now())
+	FromContext(ctx).AddResponse(Response{Text: "done", Usage: &Usage{InputTokens: 10, OutputTokens: 2, TotalTokens: 12}})
+	tr.Finish("done", nil)
+	if err := tr.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	prompt, err := os.ReadFile(filepath.Join(dir, tr.ID, PromptFile))
+	if err != nil || !strings.Contains(string(prompt), "Write srs.md") {
+		t.Fatalf("prompt.md: %q (%v)", prompt, err)
+	}
+
+	other := New("qa", "mock", "gpt-5", nil)
+	other.StartedAt = tr.StartedAt.Add(time.Second)
+	other.Finish("", errors.New("model unavailable"))
+	if err := other.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != tr.ID || runs[1].Status != StatusFailed || runs[1].Error != "model unavailable" {
+		t.Fatalf("List: %+v", runs)
+	}
+
+	got, err := Load(dir, tr.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusOK || len(got.ToolCalls) != 2 || got.ToolCalls[1].Error != "refused" || got.Responses[0].Usage.TotalTokens != 12 {
+		t.Fatalf("Load: %+v", got)
+	}
+	if _, err := Load(dir, strings.TrimSuffix(tr.ID, "plan")); err != nil {
+		t.Fatalf("unique prefix should resolve: %v", err)
+	}
+	if _, err := Load(dir, "1999"); !errors.Is(err, ErrUnknownRun) {
+		t.Fatalf("expected ErrUnknownRun, got %v", err)
+	}
+}
+
+func TestNilTranscriptIsNoop(t *testing.T) {
+	tr := FromContext(context.Background())
+	tr.AddResponse(Response{Text: "x"})
+	tr.AddToolCall("file_reader", nil, "", nil, time.Now())
+	tr.Finish("", nil)
+}
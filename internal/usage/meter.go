@@ -0,0 +1,154 @@
+// Package usage meters the tokens an agent run consumes, prices them with
+// the config's price table and enforces the budget limits. It also
+// aggregates the usage recorded in past run transcripts.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"agentflow/internal/config"
+)
+
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetError reports which limit a run crossed.
+type BudgetError struct {
+	Limit string // e.g. "budget.maxTokensPerRun"
+	Used  string
+	Max   string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("%s: used %s of %s", e.Limit, e.Used, e.Max)
+}
+
+func (e *BudgetError) Unwrap() error { return ErrBudgetExceeded }
+
+// Meter tracks the token usage of one agent run. When a limit is crossed it
+// cancels the run's context with a *BudgetError, so the run stops at the
+// next model or tool boundary. All methods accept a nil receiver.
+type Meter struct {
+	model     string
+	price     config.ModelPrice
+	priced    bool
+	maxTokens int64
+	maxCost   float64
+	cancel    context.CancelCauseFunc
+
+	mu     sync.Mutex
+	input  int64
+	output int64
+	err    error
+}
+
+// NewMeter returns a meter for a run of model under cfg's budget. spent is
+// what earlier runs of the project have cost; it only matters with
+// budget.maxCostPerProject set. cancel stops the run.
+func NewMeter(cfg *config.Config, model string, spent float64, cancel context.CancelCauseFunc) *Meter {
+	m := &Meter{
+		model:     model,
+		maxTokens: cfg.Budget.MaxTokensPerRun,
+		maxCost:   cfg.Budget.MaxCostPerRun,
+		cancel:    cancel,
+	}
+	m.price, m.priced = cfg.Price(model)
+	if limit := cfg.Budget.MaxCostPerProject; limit > 0 {
+		if left := limit - spent; m.maxCost == 0 || left < m.maxCost {
+			m.maxCost = left
+		}
+	}
+	return m
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context that carries m.
+func NewContext(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, ctxKey{}, m)
+}
+
+// FromContext returns the meter carried by ctx, or nil.
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(ctxKey{}).(*Meter)
+	return m
+}
+
+// Add records the usage of one model response.
+func (m *Meter) Add(input, output int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.input += input
+	m.output += output
+	m.check()
+}
+
+func (m *Meter) check() {
+	if m.err != nil {
+		return
+	}
+	if total := m.input + m.output; m.maxTokens > 0 && total > m.maxTokens {
+		m.err = &BudgetError{Limit: "budget.maxTokensPerRun", Used: fmt.Sprintf("%d tokens", total), Max: fmt.Sprintf("%d", m.maxTokens)}
+	} else if cost := m.cost(); m.priced && m.maxCost > 0 && cost > m.maxCost {
+		m.err = &BudgetError{Limit: "budget.maxCostPerRun", Used: FormatCost(cost), Max: FormatCost(m.maxCost)}
+	}
+	if m.err != nil && m.cancel != nil {
+		m.cancel(m.err)
+	}
+}
+
+func (m *Meter) cost() float64 {
+	return m.price.Cost(m.input, m.output)
+}
+
+// Tokens returns the input and output tokens recorded so far.
+func (m *Meter) Tokens() (input, output int64) {
+	if m == nil {
+		return 0, 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.input, m.output
+}
+
+// Cost returns the cost so far. ok is false when the model has no price.
+func (m *Meter) Cost() (cost float64, ok bool) {
+	if m == nil {
+		return 0, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cost(), m.priced
+}
+
+// Err returns the *BudgetError that stopped the run, if any.
+func (m *Meter) Err() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Summary is a one-line usage note for the end of a command.
+func (m *Meter) Summary() string {
+	in, out := m.Tokens()
+	s := fmt.Sprintf("%d input + %d output tokens", in, out)
+	if cost, ok := m.Cost(); ok {
+		s += ", " + FormatCost(cost)
+	} else {
+		s += fmt.Sprintf(", no price for %s", m.model)
+	}
+	return s
+}
+
+// FormatCost renders a USD amount.
+func FormatCost(c float64) string {
+	return fmt.Sprintf("$%.4f", c)
+}
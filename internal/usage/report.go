@@ -0,0 +1,105 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"agentflow/internal/config"
+	"agentflow/internal/transcript"
+)
+
+// Report groupings.
+const (
+	ByStage = "stage"
+	ByModel = "model"
+	ByDay   = "day"
+)
+
+// Row aggregates the runs that share a key. Unpriced counts the runs whose
+// model had no price, so Cost understates their share.
+type Row struct {
+	Key      string
+	Runs     int
+	Input    int64
+	Output   int64
+	Cost     float64
+	Unpriced int
+}
+
+// Report totals past runs, one row per key in key order.
+type Report struct {
+	Rows  []Row
+	Total Row
+}
+
+// Summarize aggregates runs by stage, model or day. Runs started before
+// since are left out when since is non-zero.
+func Summarize(runs []*transcript.Transcript, by string, since time.Time) (*Report, error) {
+	keyOf, err := keyFunc(by)
+	if err != nil {
+		return nil, err
+	}
+	rows := map[string]*Row{}
+	r := &Report{Total: Row{Key: "TOTAL"}}
+	for _, t := range runs {
+		if !since.IsZero() && t.StartedAt.Before(since) {
+			continue
+		}
+		key := keyOf(t)
+		row := rows[key]
+		if row == nil {
+			row = &Row{Key: key}
+			rows[key] = row
+		}
+		for _, x := range []*Row{row, &r.Total} {
+			x.Runs++
+			if t.Usage != nil {
+				x.Input += t.Usage.InputTokens
+				x.Output += t.Usage.OutputTokens
+			}
+			if t.CostUSD != nil {
+				x.Cost += *t.CostUSD
+			} else {
+				x.Unpriced++
+			}
+		}
+	}
+	for _, row := range rows {
+		r.Rows = append(r.Rows, *row)
+	}
+	sort.Slice(r.Rows, func(i, j int) bool { return r.Rows[i].Key < r.Rows[j].Key })
+	return r, nil
+}
+
+func keyFunc(by string) (func(*transcript.Transcript) string, error) {
+	switch by {
+	case "", ByStage:
+		return func(t *transcript.Transcript) string { return t.Stage }, nil
+	case ByModel:
+		return func(t *transcript.Transcript) string { return t.Model }, nil
+	case ByDay:
+		return func(t *transcript.Transcript) string { return t.StartedAt.Local().Format(time.DateOnly) }, nil
+	}
+	return nil, fmt.Errorf("unknown grouping %q (want %s, %s or %s)", by, ByStage, ByModel, ByDay)
+}
+
+// Spent returns the total recorded cost of runs.
+func Spent(runs []*transcript.Transcript) float64 {
+	total := 0.0
+	for _, t := range runs {
+		if t.CostUSD != nil {
+			total += *t.CostUSD
+		}
+	}
+	return total
+}
+
+// CheckProject refuses a new run once the project has spent
+// budget.maxCostPerProject.
+func CheckProject(cfg *config.Config, spent float64) error {
+	if limit := cfg.Budget.MaxCostPerProject; limit > 0 && spent >= limit {
+		return &BudgetError{Limit: "budget.maxCostPerProject", Used: FormatCost(spent), Max: FormatCost(limit)}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"agentflow/internal/config"
+	"agentflow/internal/transcript"
+)
+
+func TestMeterStopsRunOverBudget(t *testing.T) {
+	cfg := config.DefaultConfig("Demo", "gpt-5")
+	cfg.Pricing = map[string]config.ModelPrice{"gpt-5": {InputPerMTok: 1, OutputPerMTok: 10}}
+	cfg.Budget.MaxTokensPerRun = 1000
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	m := NewMeter(cfg, "gpt-5", 0, cancel)
+	m.Add(400, 100)
+	if in, out := m.Tokens(); in != 400 || out != 100 || ctx.Err() != nil {
+		t.Fatalf("tokens = %d/%d, ctx err %v", in, out, ctx.Err())
+	}
+	if cost, ok := m.Cost(); !ok || cost != 0.0014 {
+		t.Fatalf("cost = %v %v", cost, ok)
+	}
+	m.Add(400, 200)
+	var be *BudgetError
+	if !errors.As(m.Err(), &be) || be.Limit != "budget.maxTokensPerRun" || !errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+		t.Fatalf("expected token budget to cancel the run, got %v / %v", m.Err(), context.Cause(ctx))
+	}
+}
+
+func TestMeterCostLimitIncludesProjectBudget(t *testing.T) {
+	cfg := config.DefaultConfig("Demo", "gpt-5")
+	cfg.Pricing = map[string]config.ModelPrice{"gpt-5": {InputPerMTok: 1, OutputPerMTok: 10}}
+	cfg.Budget.MaxCostPerRun = 1
+	cfg.Budget.MaxCostPerProject = 5
+
+	m := NewMeter(cfg, "gpt-5", 4.5, nil)
+	m.Add(0, 60_000) // $0.60: within the run limit, over what is left of the project
+	if !errors.Is(m.Err(), ErrBudgetExceeded) {
+		t.Fatalf("expected the remaining project budget to apply, got %v", m.Err())
+	}
+	if err := CheckProject(cfg, 5); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected spent project budget to refuse a run, got %v", err)
+	}
+
+	unpriced := NewMeter(cfg, "llama3", 0, nil)
+	unpriced.Add(1_000_000, 1_000_000)
+	if unpriced.Err() != nil {
+		t.Fatalf("cost limits cannot apply to unpriced models, got %v", unpriced.Err())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	cost := func(c float64) *float64 { return &c }
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.Local)
+	runs := []*transcript.Transcript{
+		{Stage: "plan", Model: "gpt-5", StartedAt: day, Usage: &transcript.Usage{InputTokens: 100, OutputTokens: 10}, CostUSD: cost(0.5)},
+		{Stage: "plan", Model: "gpt-5", StartedAt: day.Add(time.Hour), Usage: &transcript.Usage{InputTokens: 200, OutputTokens: 20}, CostUSD: cost(0.25)},
+		{Stage: "qa", Model: "llama3", StartedAt: day.AddDate(0, 0, 1), Usage: &transcript.Usage{InputTokens: 50, OutputTokens: 5}},
+	}
+	r, err := Summarize(runs, ByStage, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Rows) != 2 || r.Rows[0].Key != "plan" || r.Rows[0].Runs != 2 || r.Rows[0].Input != 300 || r.Rows[0].Cost != 0.75 {
+		t.Fatalf("rows: %+v", r.Rows)
+	}
+	if r.Total.Runs != 3 || r.Total.Output != 35 || r.Total.Unpriced != 1 {
+		t.Fatalf("total: %+v", r.Total)
+	}
+	r, _ = Summarize(runs, ByDay, day.AddDate(0, 0, 1).Truncate(24*time.Hour))
+	if len(r.Rows) != 1 || r.Rows[0].Key != "2025-03-02" {
+		t.Fatalf("since filter: %+v", r.Rows)
+	}
+	if _, err := Summarize(runs, "week", time.Time{}); err == nil {
+		t.Fatal("expected unknown grouping error")
+	}
+	if got := Spent(runs); got != 0.75 {
+		t.Fatalf("Spent = %v", got)
+	}
+}